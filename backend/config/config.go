@@ -20,11 +20,28 @@ type Config struct {
 	JWTSecret          string
 	JWTExpiresIn       time.Duration
 	Port               string
+	APIBaseURL         string
 	GinMode            string
 	CloudinaryURL      string
 	CloudinaryCloud    string
 	CloudinaryKey      string
 	CloudinarySecret   string
+	StorageBackend     string
+	LocalStorageDir    string
+	LocalStorageURL    string
+	S3Endpoint         string
+	S3Region           string
+	S3Bucket           string
+	S3AccessKey        string
+	S3SecretKey        string
+	S3PublicURL        string
+	GeocoderBackend    string
+	GeocoderURL        string
+	GeocoderAPIKey     string
+	MaxImageSizeMB     int
+	MaxImageDimension  int
+	MaxHouseImages     int
+	ReportThreshold    int
 	MTNMoMoAPIURL      string
 	MTNMoMoAPIKey      string
 	MTNMoMoSubKey      string
@@ -33,6 +50,10 @@ type Config struct {
 	AirtelClientSecret string
 	CommissionRate     float64
 	FeaturedPrice      float64
+	SchedulerInterval  time.Duration
+	ViewingReminder    time.Duration
+	PreLeaseWindowDays int
+	EscalationLeadDays int
 }
 
 // Load loads configuration from environment variables
@@ -60,6 +81,62 @@ func Load() *Config {
 		log.Fatal("Invalid FEATURED_LISTING_PRICE format:", err)
 	}
 
+	// Parse background job interval
+	schedulerInterval, err := time.ParseDuration(getEnv("SCHEDULER_INTERVAL", "15m"))
+	if err != nil {
+		log.Fatal("Invalid SCHEDULER_INTERVAL format:", err)
+	}
+	if schedulerInterval <= 0 {
+		log.Fatal("SCHEDULER_INTERVAL must be positive, got ", schedulerInterval)
+	}
+
+	// Parse how long before a viewing the reminder is sent
+	viewingReminder, err := time.ParseDuration(getEnv("VIEWING_REMINDER_BEFORE", "24h"))
+	if err != nil {
+		log.Fatal("Invalid VIEWING_REMINDER_BEFORE format:", err)
+	}
+
+	// Parse how far ahead of its start date an agreement can be signed
+	preLeaseWindowDays, err := strconv.Atoi(getEnv("PRE_LEASE_WINDOW_DAYS", "90"))
+	if err != nil {
+		log.Fatal("Invalid PRE_LEASE_WINDOW_DAYS format:", err)
+	}
+
+	// Parse how many days before a rent increase tenants are notified
+	escalationLeadDays, err := strconv.Atoi(getEnv("ESCALATION_NOTICE_DAYS", "30"))
+	if err != nil {
+		log.Fatal("Invalid ESCALATION_NOTICE_DAYS format:", err)
+	}
+
+	// Parse image upload limits
+	maxImageSizeMB, err := strconv.Atoi(getEnv("MAX_IMAGE_SIZE_MB", "10"))
+	if err != nil {
+		log.Fatal("Invalid MAX_IMAGE_SIZE_MB format:", err)
+	}
+	maxImageDimension, err := strconv.Atoi(getEnv("MAX_IMAGE_DIMENSION", "8000"))
+	if err != nil {
+		log.Fatal("Invalid MAX_IMAGE_DIMENSION format:", err)
+	}
+	maxHouseImages, err := strconv.Atoi(getEnv("MAX_HOUSE_IMAGES", "30"))
+	if err != nil {
+		log.Fatal("Invalid MAX_HOUSE_IMAGES format:", err)
+	}
+	if maxImageSizeMB <= 0 || maxImageDimension <= 0 || maxHouseImages <= 0 {
+		log.Fatal("MAX_IMAGE_SIZE_MB, MAX_IMAGE_DIMENSION and MAX_HOUSE_IMAGES must be positive")
+	}
+
+	// Parse how many open reports hide a listing until a moderator reviews it
+	reportThreshold, err := strconv.Atoi(getEnv("REPORT_HIDE_THRESHOLD", "3"))
+	if err != nil {
+		log.Fatal("Invalid REPORT_HIDE_THRESHOLD format:", err)
+	}
+
+	// Image storage defaults to Cloudinary when it is configured, otherwise to the local disk
+	storageBackend := getEnv("STORAGE_BACKEND", "local")
+	if os.Getenv("STORAGE_BACKEND") == "" && (os.Getenv("CLOUDINARY_URL") != "" || os.Getenv("CLOUDINARY_CLOUD_NAME") != "") {
+		storageBackend = "cloudinary"
+	}
+
 	return &Config{
 		DBHost:             getEnv("DB_HOST", "localhost"),
 		DBPort:             getEnv("DB_PORT", "5432"),
@@ -70,11 +147,28 @@ func Load() *Config {
 		JWTSecret:          getEnv("JWT_SECRET", "your-super-secret-jwt-key-here"),
 		JWTExpiresIn:       jwtExpiresIn,
 		Port:               getEnv("PORT", "8080"),
+		APIBaseURL:         getEnv("API_BASE_URL", "http://localhost:"+getEnv("PORT", "8080")+"/api/v1"),
 		GinMode:            getEnv("GIN_MODE", "debug"),
 		CloudinaryURL:      getEnv("CLOUDINARY_URL", ""),
-		CloudinaryCloud:    getEnv("CLOUDINARY_CLOUD_NAME", "dxdjpss9e"),
-		CloudinaryKey:      getEnv("CLOUDINARY_API_KEY", "433566288488979"),
-		CloudinarySecret:   getEnv("CLOUDINARY_API_SECRET", "LJftQgKyBha2XF6yNIfgTSTwZYE"),
+		CloudinaryCloud:    getEnv("CLOUDINARY_CLOUD_NAME", ""),
+		CloudinaryKey:      getEnv("CLOUDINARY_API_KEY", ""),
+		CloudinarySecret:   getEnv("CLOUDINARY_API_SECRET", ""),
+		StorageBackend:     storageBackend,
+		LocalStorageDir:    getEnv("LOCAL_STORAGE_DIR", "uploads"),
+		LocalStorageURL:    getEnv("LOCAL_STORAGE_URL", "http://localhost:"+getEnv("PORT", "8080")+"/uploads"),
+		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
+		S3Region:           getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:           getEnv("S3_BUCKET", ""),
+		S3AccessKey:        getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretKey:        getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PublicURL:        getEnv("S3_PUBLIC_URL", ""),
+		GeocoderBackend:    getEnv("GEOCODER_BACKEND", "offline"),
+		GeocoderURL:        getEnv("GEOCODER_URL", "https://nominatim.openstreetmap.org"),
+		GeocoderAPIKey:     getEnv("GEOCODER_API_KEY", ""),
+		MaxImageSizeMB:     maxImageSizeMB,
+		MaxImageDimension:  maxImageDimension,
+		MaxHouseImages:     maxHouseImages,
+		ReportThreshold:    reportThreshold,
 		MTNMoMoAPIURL:      getEnv("MTN_MOMO_API_URL", ""),
 		MTNMoMoAPIKey:      getEnv("MTN_MOMO_API_KEY", ""),
 		MTNMoMoSubKey:      getEnv("MTN_MOMO_SUBSCRIPTION_KEY", ""),
@@ -83,6 +177,10 @@ func Load() *Config {
 		AirtelClientSecret: getEnv("AIRTEL_MONEY_CLIENT_SECRET", ""),
 		CommissionRate:     commissionRate,
 		FeaturedPrice:      featuredPrice,
+		SchedulerInterval:  schedulerInterval,
+		ViewingReminder:    viewingReminder,
+		PreLeaseWindowDays: preLeaseWindowDays,
+		EscalationLeadDays: escalationLeadDays,
 	}
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"regexp"
 
 	"bondihub/models"
 
@@ -42,11 +43,43 @@ func InitDB() {
 
 // AutoMigrate runs database migrations
 func AutoMigrate() {
+	if err := DB.SetupJoinTable(&models.House{}, "Amenities", &models.HouseAmenity{}); err != nil {
+		log.Fatal("Failed to set up house amenities join table:", err)
+	}
+	if err := DB.SetupJoinTable(&models.Property{}, "Amenities", &models.PropertyAmenity{}); err != nil {
+		log.Fatal("Failed to set up property amenities join table:", err)
+	}
+
 	err := DB.AutoMigrate(
 		&models.User{},
+		&models.Amenity{},
+		&models.Location{},
+		&models.Property{},
+		&models.PropertyImage{},
 		&models.House{},
 		&models.HouseImage{},
+		&models.ImageDeletion{},
+		&models.UploadClaim{},
+		&models.ListingModeration{},
+		&models.ListingReport{},
+		&models.ListingRisk{},
+		&models.ListingEvent{},
+		&models.ListingDailyStat{},
+		&models.SavedSearch{},
+		&models.SavedSearchMatch{},
 		&models.RentalAgreement{},
+		&models.AgreementTenant{},
+		&models.MoveOutNotice{},
+		&models.AgreementAmendment{},
+		&models.RentEscalation{},
+		&models.InspectionReport{},
+		&models.InspectionItem{},
+		&models.InspectionPhoto{},
+		&models.ViewingSlot{},
+		&models.Viewing{},
+		&models.ChargeType{},
+		&models.MeterReading{},
+		&models.Charge{},
 		&models.Payment{},
 		&models.Review{},
 		&models.MaintenanceRequest{},
@@ -58,9 +91,188 @@ func AutoMigrate() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	setupHouseSearch()
+	setupChargeIndexes()
+	setupReportIndexes()
+	setupImageHashIndexes()
+	seedLocations()
+	backfillHouseGeohashes()
+	backfillListingLocations()
+	backfillImageRecords()
+
 	log.Println("Database migration completed successfully")
 }
 
+// setupHouseSearch creates the trigger that maintains the weighted search vector of houses
+// (title, then address, then description) and the trigram indexes used to match misspelt place names
+func setupHouseSearch() {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE OR REPLACE FUNCTION houses_search_vector_update() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector :=
+				setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+				setweight(to_tsvector('english', COALESCE(NEW.address, '')), 'B') ||
+				setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'C');
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS houses_search_vector_trigger ON houses`,
+		`CREATE TRIGGER houses_search_vector_trigger BEFORE INSERT OR UPDATE OF title, address, description
+			ON houses FOR EACH ROW EXECUTE FUNCTION houses_search_vector_update()`,
+		`CREATE INDEX IF NOT EXISTS idx_houses_title_trgm ON houses USING gin (title gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_houses_address_trgm ON houses USING gin (address gin_trgm_ops)`,
+		// Used to find near-duplicate listings
+		`CREATE INDEX IF NOT EXISTS idx_houses_description_trgm ON houses USING gin (description gin_trgm_ops)`,
+		// Fill the vector of houses saved before the trigger existed
+		`UPDATE houses SET title = title WHERE search_vector IS NULL`,
+	}
+
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatal("Failed to set up house search:", err)
+		}
+	}
+}
+
+// setupChargeIndexes makes each agreement's rent and recurring charges unique per billing month, so
+// overlapping billing runs cannot charge a month twice. Rent has no charge type, so a missing one is
+// indexed as the nil UUID.
+func setupChargeIndexes() {
+	statement := `CREATE UNIQUE INDEX IF NOT EXISTS idx_charges_billing_month ON charges
+		(agreement_id, kind, COALESCE(charge_type_id, '00000000-0000-0000-0000-000000000000'), period_start)
+		WHERE kind IN ('rent', 'recurring')`
+	if err := DB.Exec(statement).Error; err != nil {
+		log.Fatal("Failed to set up charge indexes:", err)
+	}
+}
+
+// setupReportIndexes allows one open report of a listing per user. Duplicates filed before the index
+// existed are removed first, keeping the earliest.
+func setupReportIndexes() {
+	statements := []string{
+		`DELETE FROM listing_reports duplicate USING listing_reports earlier
+			WHERE duplicate.status = 'open' AND earlier.status = 'open'
+				AND duplicate.house_id = earlier.house_id AND duplicate.reporter_id = earlier.reporter_id
+				AND (duplicate.created_at, duplicate.id) > (earlier.created_at, earlier.id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_listing_reports_open ON listing_reports (house_id, reporter_id)
+			WHERE status = 'open'`,
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatal("Failed to set up report indexes:", err)
+		}
+	}
+}
+
+// setupImageHashIndexes indexes each band of the perceptual hashes of house images, used to find
+// photos reused across listings
+func setupImageHashIndexes() {
+	for i, band := range models.ImageHashBands {
+		statement := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_house_images_hash_band_%d ON house_images (%s)
+			WHERE image_hash IS NOT NULL`, i, band.SQL())
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatal("Failed to set up image hash indexes:", err)
+		}
+	}
+}
+
+// backfillHouseGeohashes sets the geohash of houses saved before location search existed
+func backfillHouseGeohashes() {
+	var houses []models.House
+	if err := DB.Select("id", "latitude", "longitude").
+		Where("(geohash IS NULL OR geohash = '') AND (latitude <> 0 OR longitude <> 0)").
+		Find(&houses).Error; err != nil {
+		log.Println("Failed to load houses for geohash backfill:", err)
+		return
+	}
+
+	for _, house := range houses {
+		geohash := models.EncodeGeohash(house.Latitude, house.Longitude, models.GeohashPrecision)
+		if err := DB.Model(&models.House{}).Where("id = ?", house.ID).UpdateColumn("geohash", geohash).Error; err != nil {
+			log.Println("Failed to backfill house geohash:", err)
+		}
+	}
+}
+
+// listingLocationSQL files the listings of a table that have coordinates but no location under the
+// nearest area within 3 km, or failing that the nearest town within 25 km, as the gazetteer does
+// when a pin is placed
+const listingLocationSQL = `UPDATE %[1]s SET location_id = COALESCE(
+		(SELECT id FROM locations WHERE level = 'area' AND %[2]s <= 3 ORDER BY %[2]s LIMIT 1),
+		(SELECT id FROM locations WHERE level = 'town' AND %[2]s <= 25 ORDER BY %[2]s LIMIT 1))
+	WHERE location_id IS NULL AND (latitude <> 0 OR longitude <> 0)`
+
+// locationDistanceSQL is the haversine distance in km from a listing of a table to a location
+const locationDistanceSQL = `(6371 * 2 * ASIN(LEAST(1, SQRT(
+		POWER(SIN(RADIANS(%[1]s.latitude - locations.latitude) / 2), 2) +
+		COS(RADIANS(locations.latitude)) * COS(RADIANS(%[1]s.latitude)) * POWER(SIN(RADIANS(%[1]s.longitude - locations.longitude) / 2), 2)))))`
+
+// backfillListingLocations files houses and properties saved before the location hierarchy existed
+// under the location nearest their coordinates
+func backfillListingLocations() {
+	for _, table := range []string{"properties", "houses"} {
+		distance := fmt.Sprintf(locationDistanceSQL, table)
+		if err := DB.Exec(fmt.Sprintf(listingLocationSQL, table, distance)).Error; err != nil {
+			log.Printf("Failed to backfill %s locations: %v", table, err)
+		}
+	}
+}
+
+// cloudinaryPublicIDPattern extracts the public ID from a Cloudinary delivery URL, skipping any
+// transformation and version segments and the file extension
+var cloudinaryPublicIDPattern = regexp.MustCompile(`/image/upload/(?:[a-z]{1,2}_[^/]*/)*(?:v\d+/)?(.+?)(?:\.[A-Za-z0-9]+)?$`)
+
+// backfillImageRecords fills in the public IDs of Cloudinary images saved before they were recorded,
+// so deleting them removes the stored image, and numbers the gallery order of existing house and
+// property images
+func backfillImageRecords() {
+	for _, table := range []string{"house_images", "property_images"} {
+		var images []struct {
+			ID       string
+			ImageURL string
+		}
+		if err := DB.Table(table).Select("id", "image_url").
+			Where("(public_id IS NULL OR public_id = '') AND image_url LIKE ?", "%res.cloudinary.com%").
+			Find(&images).Error; err != nil {
+			log.Printf("Failed to load %s for public ID backfill: %v", table, err)
+			continue
+		}
+
+		for _, image := range images {
+			match := cloudinaryPublicIDPattern.FindStringSubmatch(image.ImageURL)
+			if match == nil {
+				continue
+			}
+			if err := DB.Table(table).Where("id = ?", image.ID).UpdateColumn("public_id", match[1]).Error; err != nil {
+				log.Printf("Failed to backfill %s public ID: %v", table, err)
+			}
+		}
+	}
+
+	// Number the images of houses and properties saved before gallery order existed, oldest first,
+	// and make the first image primary where there is none
+	statements := []string{
+		`UPDATE house_images SET sort_order = numbered.position
+			FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY house_id ORDER BY created_at) - 1 AS position FROM house_images) numbered
+			WHERE house_images.id = numbered.id AND house_images.house_id IN (
+				SELECT house_id FROM house_images GROUP BY house_id HAVING COUNT(*) > 1 AND MAX(sort_order) = 0)`,
+		`UPDATE house_images SET is_primary = true WHERE sort_order = 0 AND house_id NOT IN (
+			SELECT house_id FROM house_images WHERE is_primary)`,
+		`UPDATE property_images SET sort_order = numbered.position
+			FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY property_id ORDER BY created_at) - 1 AS position FROM property_images) numbered
+			WHERE property_images.id = numbered.id AND property_images.property_id IN (
+				SELECT property_id FROM property_images GROUP BY property_id HAVING COUNT(*) > 1 AND MAX(sort_order) = 0)`,
+		`UPDATE property_images SET is_primary = true WHERE sort_order = 0 AND property_id NOT IN (
+			SELECT property_id FROM property_images WHERE is_primary)`,
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Println("Failed to backfill image order:", err)
+		}
+	}
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"log"
+
+	"bondihub/models"
+
+	"github.com/google/uuid"
+)
+
+// seedLocation is a place in the seeded location hierarchy. Coordinates are approximate: the centre of
+// a town or area, and the capital of a province or district.
+type seedLocation struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Children  []seedLocation
+}
+
+// place is an area, or a town without seeded areas
+func place(name string, lat, lng float64, children ...seedLocation) seedLocation {
+	return seedLocation{Name: name, Latitude: lat, Longitude: lng, Children: children}
+}
+
+// district is a district whose only seeded town shares its name, as most Zambian district capitals do
+func district(name string, lat, lng float64, areas ...seedLocation) seedLocation {
+	return place(name, lat, lng, place(name, lat, lng, areas...))
+}
+
+// province is a province located at its capital, the first of its districts
+func province(name string, districts ...seedLocation) seedLocation {
+	return place(name, districts[0].Latitude, districts[0].Longitude, districts...)
+}
+
+// zambianLocations are the provinces, districts, main towns and the areas and compounds of the
+// largest towns that listings are filed under. Admins can add more nodes through the API.
+var zambianLocations = []seedLocation{
+	province("Lusaka",
+		district("Lusaka", -15.4167, 28.2833,
+			place("Avondale", -15.3850, 28.3600),
+			place("Chainda", -15.3930, 28.3830),
+			place("Chalala", -15.4600, 28.3500),
+			place("Chawama", -15.4500, 28.2750),
+			place("Chelston", -15.3700, 28.3700),
+			place("Chilenje", -15.4470, 28.3150),
+			place("Emmasdale", -15.3800, 28.2750),
+			place("Foxdale", -15.3680, 28.3280),
+			place("Garden", -15.3950, 28.2650),
+			place("Ibex Hill", -15.4330, 28.3600),
+			place("Kabulonga", -15.4167, 28.3333),
+			place("Kabwata", -15.4300, 28.2900),
+			place("Kalingalinga", -15.4080, 28.3300),
+			place("Kalundu", -15.3820, 28.3270),
+			place("Kamwala", -15.4280, 28.2870),
+			place("Kanyama", -15.4300, 28.2400),
+			place("Libala", -15.4450, 28.3000),
+			place("Longacres", -15.4160, 28.2990),
+			place("Makeni", -15.4750, 28.2600),
+			place("Matero", -15.3750, 28.2450),
+			place("Meanwood", -15.3700, 28.3900),
+			place("Mtendere", -15.4120, 28.3600),
+			place("Northmead", -15.4050, 28.2960),
+			place("Olympia", -15.3960, 28.3200),
+			place("Rhodes Park", -15.4097, 28.3036),
+			place("Roma", -15.3806, 28.3111),
+			place("Silverest", -15.3850, 28.4500),
+			place("State Lodge", -15.4550, 28.4000),
+			place("Woodlands", -15.4333, 28.3167),
+		),
+		district("Chilanga", -15.5580, 28.2760),
+		district("Chongwe", -15.3290, 28.6820),
+		district("Kafue", -15.7690, 28.1810),
+		district("Luangwa", -15.6167, 30.4167),
+		district("Rufunsa", -15.0833, 29.6333),
+	),
+	province("Copperbelt",
+		district("Ndola", -12.9587, 28.6366,
+			place("Chifubu", -12.9300, 28.6700),
+			place("Hillcrest", -12.9700, 28.6550),
+			place("Itawa", -12.9600, 28.6200),
+			place("Kabushi", -12.9750, 28.6050),
+			place("Kansenshi", -12.9500, 28.6500),
+			place("Lubuto", -12.9550, 28.6150),
+			place("Masala", -12.9900, 28.6500),
+			place("Ndeke", -12.9450, 28.5950),
+			place("Northrise", -12.9400, 28.6450),
+			place("Twapia", -12.9400, 28.6000),
+		),
+		district("Kitwe", -12.8024, 28.2132,
+			place("Buchi", -12.8100, 28.1800),
+			place("Chamboli", -12.8300, 28.1700),
+			place("Chimwemwe", -12.7700, 28.2400),
+			place("Kwacha", -12.7800, 28.2100),
+			place("Ndeke", -12.7950, 28.2500),
+			place("Nkana East", -12.8100, 28.2400),
+			place("Nkana West", -12.8250, 28.1950),
+			place("Parklands", -12.8100, 28.2200),
+			place("Riverside", -12.8200, 28.2300),
+			place("Wusakile", -12.8400, 28.1900),
+		),
+		district("Chingola", -12.5290, 27.8830),
+		district("Chililabombwe", -12.3667, 27.8333),
+		district("Kalulushi", -12.8386, 28.0947),
+		district("Luanshya", -13.1367, 28.4166),
+		district("Lufwanyama", -12.9500, 27.6300),
+		district("Masaiti", -13.2833, 28.4167),
+		district("Mpongwe", -13.5167, 28.1500),
+		district("Mufulira", -12.5490, 28.2410),
+	),
+	province("Southern",
+		district("Choma", -16.8065, 26.9531),
+		district("Livingstone", -17.8419, 25.8543,
+			place("Dambwa", -17.8300, 25.8300),
+			place("Highlands", -17.8300, 25.8700),
+			place("Libuyu", -17.8550, 25.8500),
+			place("Linda", -17.8250, 25.8200),
+			place("Maramba", -17.8500, 25.8700),
+		),
+		district("Kalomo", -17.0333, 26.4833),
+		district("Mazabuka", -15.8560, 27.7480),
+		district("Monze", -16.2833, 27.4833),
+		district("Namwala", -15.7500, 26.4333),
+		district("Siavonga", -16.5380, 28.7080),
+		district("Sinazongwe", -17.2614, 27.4617),
+	),
+	province("Central",
+		district("Kabwe", -14.4469, 28.4464,
+			place("Bwacha", -14.4300, 28.4600),
+			place("Highridge", -14.4350, 28.4700),
+			place("Kasanda", -14.4600, 28.4500),
+			place("Katondo", -14.4450, 28.4400),
+			place("Makululu", -14.4700, 28.4300),
+		),
+		district("Chibombo", -14.6567, 28.0714),
+		district("Kapiri Mposhi", -13.9667, 28.6833),
+		district("Mkushi", -13.6200, 29.3900),
+		district("Mumbwa", -14.9833, 27.0667),
+		district("Serenje", -13.2333, 30.2333),
+	),
+	province("Eastern",
+		district("Chipata", -13.6333, 32.6500),
+		district("Chadiza", -14.0667, 32.4333),
+		district("Katete", -14.0667, 31.9833),
+		district("Lundazi", -12.2833, 33.1833),
+		district("Nyimba", -14.5500, 30.8167),
+		district("Petauke", -14.2426, 31.3253),
+	),
+	province("Northern",
+		district("Kasama", -10.2129, 31.1808),
+		district("Luwingu", -10.2500, 29.9167),
+		district("Mbala", -8.8400, 31.3700),
+		district("Mporokoso", -9.3667, 30.1167),
+		district("Mpulungu", -8.7600, 31.1100),
+	),
+	province("Muchinga",
+		district("Chinsali", -10.5414, 32.0816),
+		district("Isoka", -10.1500, 32.6333),
+		district("Mpika", -11.8343, 31.4529),
+		district("Nakonde", -9.3274, 32.7561),
+	),
+	province("Luapula",
+		district("Mansa", -11.1998, 28.8943),
+		district("Kawambwa", -9.7915, 29.0791),
+		district("Mwense", -10.3833, 28.7000),
+		district("Nchelenge", -9.3500, 28.7333),
+		district("Samfya", -11.3650, 29.5580),
+	),
+	province("North-Western",
+		district("Solwezi", -12.1688, 26.3894),
+		district("Kabompo", -13.5928, 24.2010),
+		district("Kalumbila", -12.2500, 25.3333),
+		district("Kasempa", -13.4584, 25.8338),
+		district("Mwinilunga", -11.7358, 24.4286),
+		district("Zambezi", -13.5432, 23.1047),
+	),
+	province("Western",
+		district("Mongu", -15.2484, 23.1274),
+		district("Kalabo", -14.9700, 22.6812),
+		district("Kaoma", -14.7833, 24.8000),
+		district("Senanga", -16.1167, 23.2667),
+		district("Sesheke", -17.4759, 24.2957),
+	),
+}
+
+// seedLocations adds the seeded locations that are missing. Existing nodes, including any an admin
+// renamed or moved, are left alone.
+func seedLocations() {
+	var existing []models.Location
+	if err := DB.Select("id", "path").Find(&existing).Error; err != nil {
+		log.Println("Failed to load locations for seeding:", err)
+		return
+	}
+	ids := make(map[string]uuid.UUID, len(existing))
+	for _, location := range existing {
+		ids[location.Path] = location.ID
+	}
+
+	var missing []models.Location
+	var walk func(nodes []seedLocation, parent *models.Location, level models.LocationLevel)
+	walk = func(nodes []seedLocation, parent *models.Location, level models.LocationLevel) {
+		for _, node := range nodes {
+			location := models.Location{
+				Level:     level,
+				Name:      node.Name,
+				Slug:      models.LocationSlug(node.Name),
+				Latitude:  node.Latitude,
+				Longitude: node.Longitude,
+			}
+			location.Path = location.Slug
+			if parent != nil {
+				location.ParentID = &parent.ID
+				location.Path = parent.Path + "/" + location.Slug
+			}
+			if id, ok := ids[location.Path]; ok {
+				location.ID = id
+			} else {
+				location.ID = uuid.New()
+				missing = append(missing, location)
+			}
+			walk(node.Children, &location, level.ChildLevel())
+		}
+	}
+	walk(zambianLocations, nil, models.LevelProvince)
+
+	if len(missing) == 0 {
+		return
+	}
+	// Parents come before their children, so each batch only refers to nodes already saved
+	if err := DB.CreateInBatches(missing, 200).Error; err != nil {
+		log.Println("Failed to seed locations:", err)
+		return
+	}
+	log.Printf("Seeded %d locations", len(missing))
+}
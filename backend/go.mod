@@ -13,6 +13,7 @@ require (
 	github.com/swaggo/gin-swagger v1.6.1
 	github.com/swaggo/swag v1.16.6
 	golang.org/x/crypto v0.36.0
+	golang.org/x/image v0.25.0
 	gorm.io/driver/postgres v1.5.4
 	gorm.io/gorm v1.25.5
 )
@@ -3,13 +3,16 @@ package handlers
 import (
 	"bondihub/config"
 	"bondihub/models"
+	"bondihub/services"
 	"bondihub/utils"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // AdminHandler handles admin-related requests
@@ -61,6 +64,16 @@ func (ah *AdminHandler) GetDashboardStats(c *gin.Context) {
 		Group("status").
 		Find(&housesByStatus)
 
+	// Get houses by listing status, for the moderation queue
+	var housesByListingStatus []struct {
+		ListingStatus string `json:"listing_status"`
+		Count         int64  `json:"count"`
+	}
+	config.DB.Model(&models.House{}).
+		Select("listing_status, COUNT(*) as count").
+		Group("listing_status").
+		Find(&housesByListingStatus)
+
 	// Get total rental agreements
 	var totalAgreements int64
 	config.DB.Model(&models.RentalAgreement{}).Count(&totalAgreements)
@@ -120,6 +133,10 @@ func (ah *AdminHandler) GetDashboardStats(c *gin.Context) {
 	var recentPayments int64
 	config.DB.Model(&models.Payment{}).Where("created_at >= ?", thirtyDaysAgo).Count(&recentPayments)
 
+	// Stored images that could not be deleted need an admin to look at them
+	var failedImageDeletions int64
+	services.FailedImageDeletionsQuery().Count(&failedImageDeletions)
+
 	utils.SuccessResponse(c, http.StatusOK, "Dashboard statistics retrieved successfully", gin.H{
 		"users": gin.H{
 			"total":   totalUsers,
@@ -127,9 +144,10 @@ func (ah *AdminHandler) GetDashboardStats(c *gin.Context) {
 			"recent":  recentUsers,
 		},
 		"houses": gin.H{
-			"total":     totalHouses,
-			"by_status": housesByStatus,
-			"recent":    recentHouses,
+			"total":             totalHouses,
+			"by_status":         housesByStatus,
+			"by_listing_status": housesByListingStatus,
+			"recent":            recentHouses,
 		},
 		"agreements": gin.H{
 			"total":  totalAgreements,
@@ -150,6 +168,7 @@ func (ah *AdminHandler) GetDashboardStats(c *gin.Context) {
 			"total":          totalReviews,
 			"average_rating": avgRating,
 		},
+		"failed_image_deletions": failedImageDeletions,
 	})
 }
 
@@ -235,10 +254,7 @@ func (ah *AdminHandler) UpdateUserStatus(c *gin.Context) {
 	}
 
 	// Update user status
-	user.IsActive = req.IsActive
-	user.UpdatedAt = time.Now()
-
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := setUserActive(config.DB, &user, req.IsActive); err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to update user status", err)
 		return
 	}
@@ -249,6 +265,82 @@ func (ah *AdminHandler) UpdateUserStatus(c *gin.Context) {
 	})
 }
 
+// GetFailedImageDeletions handles listing stored images whose deletion was given up on
+// @Summary Get failed image deletions
+// @Description Get the stored images that could not be deleted from image storage after repeated attempts, most recent first, with the last error (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{} "Failed image deletions retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/image-deletions [get]
+func (ah *AdminHandler) GetFailedImageDeletions(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	deletions, total, err := services.FailedImageDeletions(offset, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch image deletions", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	utils.SuccessResponse(c, http.StatusOK, "Failed image deletions retrieved successfully", gin.H{
+		"deletions": deletions,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// RetryImageDeletion handles putting a failed image deletion back in the queue
+// @Summary Retry failed image deletion
+// @Description Queue a stored image deletion that was given up on to be tried again on the next run of the deletion job (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Image deletion ID"
+// @Success 200 {object} map[string]interface{} "Image deletion queued"
+// @Failure 400 {object} map[string]interface{} "Invalid image deletion ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Failed image deletion not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/image-deletions/{id}/retry [put]
+func (ah *AdminHandler) RetryImageDeletion(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid image deletion ID", err)
+		return
+	}
+
+	deletion, err := services.RetryImageDeletion(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.NotFoundResponse(c, "Failed image deletion not found")
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to queue image deletion", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Image deletion queued", gin.H{
+		"deletion": deletion,
+	})
+}
+
 // GetReports handles getting various reports
 func (ah *AdminHandler) GetReports(c *gin.Context) {
 	reportType := c.Query("type")
@@ -414,3 +506,10 @@ func (ah *AdminHandler) getUserReport(c *gin.Context, start, end time.Time) {
 		"users":         users,
 	})
 }
+
+// setUserActive activates or deactivates a user account. Deactivated users cannot sign in.
+func setUserActive(tx *gorm.DB, user *models.User, isActive bool) error {
+	user.IsActive = isActive
+	user.UpdatedAt = time.Now()
+	return tx.Save(user).Error
+}
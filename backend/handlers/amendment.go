@@ -0,0 +1,560 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProposeAmendmentRequest represents the request structure for proposing changes to an agreement.
+// Only the fields being changed need to be sent.
+type ProposeAmendmentRequest struct {
+	RentAmount *float64 `json:"rent_amount" binding:"omitempty,min=0"`
+	Deposit    *float64 `json:"deposit" binding:"omitempty,min=0"`
+	StartDate  *string  `json:"start_date"`
+	EndDate    *string  `json:"end_date"`
+	// Tenants replaces the full list of tenants and rent shares, including the primary tenant
+	Tenants []CoTenantRequest `json:"tenants" binding:"omitempty,dive"`
+	// Escalation replaces the rent escalation clause; type "none" removes it
+	Escalation    *EscalationRequest `json:"escalation"`
+	EffectiveDate string             `json:"effective_date"`
+	Reason        string             `json:"reason" binding:"required,max=1000"`
+}
+
+// RejectAmendmentRequest represents the request structure for rejecting an amendment
+type RejectAmendmentRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// ProposeAmendment handles proposing a change to the terms of an agreement
+// @Summary Propose agreement amendment
+// @Description Propose changes to rent, deposit, dates or occupants. The other party must accept before the changes take effect.
+// @Tags Rentals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param request body ProposeAmendmentRequest true "Proposed changes"
+// @Success 201 {object} map[string]interface{} "Amendment proposed successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Failure 409 {object} map[string]interface{} "An amendment is already awaiting a response"
+// @Router /rentals/{id}/amendments [post]
+func (rh *RentalHandler) ProposeAmendment(c *gin.Context) {
+	agreement, party, ok := loadAgreementParty(c)
+	if !ok {
+		return
+	}
+
+	userModel := c.MustGet("user").(models.User)
+
+	var req ProposeAmendmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if agreement.Status != models.AgreementStatusActive && agreement.Status != models.AgreementStatusUpcoming {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only active or upcoming agreements can be amended", nil)
+		return
+	}
+
+	effectiveDate := time.Now().Truncate(24 * time.Hour)
+	if req.EffectiveDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.EffectiveDate)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid effective date format", err)
+			return
+		}
+		effectiveDate = parsed
+	}
+
+	// Only one amendment can be awaiting a response at a time
+	var pending int64
+	config.DB.Model(&models.AgreementAmendment{}).
+		Where("agreement_id = ? AND status = ?", agreement.ID, models.AmendmentStatusProposed).
+		Count(&pending)
+	if pending > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "Another amendment is already awaiting a response", nil)
+		return
+	}
+
+	changes, err := rh.diffAmendment(agreement, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if len(changes) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The amendment does not change anything", nil)
+		return
+	}
+
+	amendment := models.AgreementAmendment{
+		AgreementID:   agreement.ID,
+		Status:        models.AmendmentStatusProposed,
+		Changes:       changes,
+		Reason:        req.Reason,
+		EffectiveDate: effectiveDate,
+		ProposedByID:  &userModel.ID,
+	}
+
+	if err := services.CreateAgreementVersion(config.DB, &amendment); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to propose amendment", err)
+		return
+	}
+
+	// Notify the other side of the agreement
+	message := fmt.Sprintf("%s proposed changes to the rental agreement for %s: %s",
+		userModel.FullName, agreement.House.Title, req.Reason)
+	if party == partyTenant {
+		services.Notify(agreement.House.LandlordID, "Agreement Amendment Proposed", message, "agreement")
+	} else {
+		services.NotifyAll(services.AgreementTenantIDs(agreement), "Agreement Amendment Proposed", message, "agreement")
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Amendment proposed successfully", gin.H{
+		"amendment": amendment,
+	})
+}
+
+// AcceptAmendment handles the other party accepting a proposed amendment
+// @Summary Accept agreement amendment
+// @Description Accept a proposed amendment. It is applied immediately if its effective date has arrived, otherwise on the effective date.
+// @Tags Rentals
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param amendmentId path string true "Amendment ID"
+// @Success 200 {object} map[string]interface{} "Amendment accepted successfully"
+// @Failure 400 {object} map[string]interface{} "Amendment is not awaiting a response"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Amendment not found"
+// @Failure 409 {object} map[string]interface{} "New dates overlap another agreement"
+// @Router /rentals/{id}/amendments/{amendmentId}/accept [put]
+func (rh *RentalHandler) AcceptAmendment(c *gin.Context) {
+	agreement, amendment, ok := rh.loadPendingAmendment(c)
+	if !ok {
+		return
+	}
+
+	userModel := c.MustGet("user").(models.User)
+	now := time.Now()
+	amendment.Status = models.AmendmentStatusAccepted
+	amendment.AcceptedByID = &userModel.ID
+	amendment.AcceptedAt = &now
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if !amendment.EffectiveDate.After(now) {
+			return rh.rentalService.ApplyAmendment(tx, amendment)
+		}
+		return tx.Omit(clause.Associations).Save(amendment).Error
+	})
+	if errors.Is(err, services.ErrAmendmentOverlap) {
+		utils.ErrorResponse(c, http.StatusConflict, "The amendment can no longer be applied because the new dates overlap another agreement for this house", nil)
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to accept amendment", err)
+		return
+	}
+
+	message := fmt.Sprintf("Version %d of the rental agreement for %s was accepted and takes effect on %s",
+		amendment.Version, agreement.House.Title, amendment.EffectiveDate.Format("2006-01-02"))
+	services.NotifyAll(append(services.AgreementTenantIDs(agreement), agreement.House.LandlordID),
+		"Agreement Amendment Accepted", message, "agreement")
+
+	utils.SuccessResponse(c, http.StatusOK, "Amendment accepted successfully", gin.H{
+		"amendment": amendment,
+	})
+}
+
+// RejectAmendment handles the other party rejecting a proposed amendment
+func (rh *RentalHandler) RejectAmendment(c *gin.Context) {
+	agreement, amendment, ok := rh.loadPendingAmendment(c)
+	if !ok {
+		return
+	}
+
+	userModel := c.MustGet("user").(models.User)
+
+	var req RejectAmendmentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	now := time.Now()
+	amendment.Status = models.AmendmentStatusRejected
+	amendment.RejectedByID = &userModel.ID
+	amendment.RejectedAt = &now
+
+	if err := config.DB.Omit(clause.Associations).Save(amendment).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to reject amendment", err)
+		return
+	}
+
+	if amendment.ProposedByID != nil {
+		message := fmt.Sprintf("Your proposed changes to the rental agreement for %s were rejected", agreement.House.Title)
+		if req.Reason != "" {
+			message += ": " + req.Reason
+		}
+		services.Notify(*amendment.ProposedByID, "Agreement Amendment Rejected", message, "agreement")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Amendment rejected successfully", gin.H{
+		"amendment": amendment,
+	})
+}
+
+// GetAmendments handles getting every version of an agreement
+func (rh *RentalHandler) GetAmendments(c *gin.Context) {
+	agreement, _, ok := loadAgreementParty(c)
+	if !ok {
+		return
+	}
+
+	var amendments []models.AgreementAmendment
+	if err := config.DB.Preload("ProposedBy").Preload("AcceptedBy").
+		Where("agreement_id = ?", agreement.ID).
+		Order("version ASC").
+		Find(&amendments).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch amendments", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Amendments retrieved successfully", gin.H{
+		"amendments": amendments,
+	})
+}
+
+// GetAgreementTimeline handles getting the full history of an agreement
+// @Summary Get agreement timeline
+// @Description Get every version of an agreement and related events, such as move-out notices, in date order
+// @Tags Rentals
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Success 200 {object} map[string]interface{} "Agreement timeline retrieved successfully"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Router /rentals/{id}/timeline [get]
+func (rh *RentalHandler) GetAgreementTimeline(c *gin.Context) {
+	agreement, _, ok := loadAgreementParty(c)
+	if !ok {
+		return
+	}
+
+	var amendments []models.AgreementAmendment
+	config.DB.Preload("ProposedBy").Preload("AcceptedBy").
+		Where("agreement_id = ?", agreement.ID).
+		Order("version ASC").
+		Find(&amendments)
+
+	var notices []models.MoveOutNotice
+	config.DB.Preload("Tenant").Where("agreement_id = ?", agreement.ID).Find(&notices)
+
+	type timelineEntry struct {
+		At      time.Time   `json:"at"`
+		Event   string      `json:"event"`
+		Version int         `json:"version,omitempty"`
+		Details interface{} `json:"details"`
+	}
+
+	timeline := []timelineEntry{}
+	for i := range amendments {
+		amendment := &amendments[i]
+		timeline = append(timeline, timelineEntry{
+			At:      amendment.CreatedAt,
+			Event:   "version_" + string(models.AmendmentStatusProposed),
+			Version: amendment.Version,
+			Details: amendment,
+		})
+		if amendment.AcceptedAt != nil && amendment.ProposedByID != nil && amendment.AcceptedByID != nil &&
+			*amendment.ProposedByID != *amendment.AcceptedByID {
+			timeline = append(timeline, timelineEntry{
+				At:      *amendment.AcceptedAt,
+				Event:   "version_" + string(models.AmendmentStatusAccepted),
+				Version: amendment.Version,
+				Details: gin.H{"accepted_by": amendment.AcceptedBy, "effective_date": amendment.EffectiveDate},
+			})
+		}
+		if amendment.RejectedAt != nil {
+			timeline = append(timeline, timelineEntry{
+				At:      *amendment.RejectedAt,
+				Event:   "version_" + string(models.AmendmentStatusRejected),
+				Version: amendment.Version,
+				Details: gin.H{"rejected_by_id": amendment.RejectedByID},
+			})
+		}
+		if amendment.AppliedAt != nil {
+			timeline = append(timeline, timelineEntry{
+				At:      *amendment.AppliedAt,
+				Event:   "version_" + string(models.AmendmentStatusApplied),
+				Version: amendment.Version,
+				Details: gin.H{"changes": amendment.Changes},
+			})
+		}
+	}
+	for i := range notices {
+		notice := &notices[i]
+		timeline = append(timeline, timelineEntry{
+			At:      notice.NoticeDate,
+			Event:   "move_out_notice_given",
+			Details: notice,
+		})
+		if notice.AcknowledgedAt != nil {
+			timeline = append(timeline, timelineEntry{
+				At:      *notice.AcknowledgedAt,
+				Event:   "move_out_notice_acknowledged",
+				Details: gin.H{"notice_id": notice.ID, "move_out_date": notice.MoveOutDate},
+			})
+		}
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].At.Before(timeline[j].At)
+	})
+
+	var currentVersion int
+	for _, amendment := range amendments {
+		if amendment.Status == models.AmendmentStatusApplied && amendment.Version > currentVersion {
+			currentVersion = amendment.Version
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Agreement timeline retrieved successfully", gin.H{
+		"agreement_id":    agreement.ID,
+		"current_version": currentVersion,
+		"timeline":        timeline,
+	})
+}
+
+// diffAmendment compares the requested terms with the agreement and returns the field changes
+func (rh *RentalHandler) diffAmendment(agreement *models.RentalAgreement, req *ProposeAmendmentRequest) (models.FieldChanges, error) {
+	changes := models.FieldChanges{}
+
+	newRent := agreement.RentAmount
+	if req.RentAmount != nil && *req.RentAmount != agreement.RentAmount {
+		newRent = *req.RentAmount
+		changes = append(changes, models.FieldChange{Field: models.FieldRentAmount, From: agreement.RentAmount, To: newRent})
+	}
+	if req.Deposit != nil && *req.Deposit != agreement.Deposit {
+		changes = append(changes, models.FieldChange{Field: models.FieldDeposit, From: agreement.Deposit, To: *req.Deposit})
+	}
+
+	startDate, endDate := agreement.StartDate, agreement.EndDate
+	if req.StartDate != nil {
+		parsed, err := time.Parse("2006-01-02", *req.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date format")
+		}
+		if !parsed.Equal(startDate) {
+			changes = append(changes, models.FieldChange{
+				Field: models.FieldStartDate,
+				From:  startDate.Format("2006-01-02"),
+				To:    *req.StartDate,
+			})
+		}
+		startDate = parsed
+	}
+	if req.EndDate != nil {
+		parsed, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date format")
+		}
+		if !parsed.Equal(endDate) {
+			changes = append(changes, models.FieldChange{
+				Field: models.FieldEndDate,
+				From:  endDate.Format("2006-01-02"),
+				To:    *req.EndDate,
+			})
+		}
+		endDate = parsed
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end date must be after start date")
+	}
+	if !startDate.Equal(agreement.StartDate) || !endDate.Equal(agreement.EndDate) {
+		occupiedUntil := endDate
+		if agreement.TerminationDate != nil && agreement.TerminationDate.Before(endDate) {
+			occupiedUntil = *agreement.TerminationDate
+		}
+		overlap, err := services.FindOverlappingAgreement(config.DB, agreement.HouseID, startDate, occupiedUntil, &agreement.ID)
+		if err != nil {
+			return nil, err
+		}
+		if overlap != nil {
+			return nil, fmt.Errorf("the house is already let from %s to %s",
+				overlap.StartDate.Format("2006-01-02"), overlap.OccupiedUntil().Format("2006-01-02"))
+		}
+	}
+
+	if req.Escalation != nil {
+		terms, err := req.Escalation.Terms()
+		if err != nil {
+			return nil, err
+		}
+		if terms != agreement.Escalation {
+			var to interface{}
+			if terms.Type != "" {
+				to = terms
+			}
+			var from interface{}
+			if agreement.Escalation.Type != "" {
+				from = agreement.Escalation
+			}
+			changes = append(changes, models.FieldChange{Field: models.FieldEscalations, From: from, To: to})
+		}
+	}
+
+	currentShares := services.AgreementTenantShares(config.DB, agreement)
+	var newShares []models.TenantShare
+
+	if len(req.Tenants) > 0 {
+		hasPrimary := false
+		seen := map[uuid.UUID]bool{}
+		for _, tenant := range req.Tenants {
+			if seen[tenant.TenantID] {
+				return nil, fmt.Errorf("each tenant can only appear once on an agreement")
+			}
+			seen[tenant.TenantID] = true
+			if tenant.TenantID == agreement.TenantID {
+				hasPrimary = true
+			}
+
+			var count int64
+			config.DB.Model(&models.User{}).Where("id = ? AND role = ?", tenant.TenantID, models.RoleTenant).Count(&count)
+			if count == 0 {
+				return nil, fmt.Errorf("tenant %s not found", tenant.TenantID)
+			}
+			newShares = append(newShares, models.TenantShare{TenantID: tenant.TenantID, RentShare: tenant.RentShare})
+		}
+		if !hasPrimary {
+			return nil, fmt.Errorf("the primary tenant must remain on the agreement")
+		}
+	} else if newRent != agreement.RentAmount {
+		// Scale the existing shares in proportion to the new rent
+		newShares = services.ScaleTenantShares(currentShares, agreement.RentAmount, newRent)
+	}
+
+	if newShares != nil {
+		total := 0.0
+		for _, share := range newShares {
+			total += share.RentShare
+		}
+		if math.Abs(total-newRent) > 0.01 {
+			return nil, fmt.Errorf("rent shares must add up to the rent amount of %.2f", newRent)
+		}
+		if !sameTenantShares(currentShares, newShares) {
+			changes = append(changes, models.FieldChange{Field: models.FieldTenants, From: currentShares, To: newShares})
+		}
+	}
+
+	return changes, nil
+}
+
+// sameTenantShares reports whether two tenant share lists contain the same tenants and amounts
+func sameTenantShares(a, b []models.TenantShare) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	shares := make(map[uuid.UUID]float64, len(a))
+	for _, share := range a {
+		shares[share.TenantID] = share.RentShare
+	}
+	for _, share := range b {
+		current, ok := shares[share.TenantID]
+		if !ok || math.Abs(current-share.RentShare) > 0.001 {
+			return false
+		}
+	}
+	return true
+}
+
+// loadAgreementParty loads the agreement from the route and works out which side of it the user is on
+func loadAgreementParty(c *gin.Context) (*models.RentalAgreement, agreementParty, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, partyNone, false
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return nil, partyNone, false
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return nil, partyNone, false
+	}
+
+	party := partyFor(&agreement, &userModel)
+	if party == partyNone {
+		utils.ForbiddenResponse(c, "You don't have access to this agreement")
+		return nil, partyNone, false
+	}
+
+	return &agreement, party, true
+}
+
+// loadPendingAmendment loads a proposed amendment and checks the user may respond to it
+func (rh *RentalHandler) loadPendingAmendment(c *gin.Context) (*models.RentalAgreement, *models.AgreementAmendment, bool) {
+	agreement, party, ok := loadAgreementParty(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	amendmentID, err := uuid.Parse(c.Param("amendmentId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amendment ID", err)
+		return nil, nil, false
+	}
+
+	var amendment models.AgreementAmendment
+	if err := config.DB.Where("id = ? AND agreement_id = ?", amendmentID, agreement.ID).First(&amendment).Error; err != nil {
+		utils.NotFoundResponse(c, "Amendment not found")
+		return nil, nil, false
+	}
+
+	if amendment.Status != models.AmendmentStatusProposed {
+		utils.ErrorResponse(c, http.StatusBadRequest, "This amendment is not awaiting a response", nil)
+		return nil, nil, false
+	}
+
+	// The response must come from the other side of the agreement; admins can respond to anything
+	if party != partyAdmin && amendment.ProposedByID != nil {
+		var proposer models.User
+		config.DB.First(&proposer, *amendment.ProposedByID)
+		if partyFor(agreement, &proposer) == party {
+			utils.ForbiddenResponse(c, "The other party to the agreement must respond to this amendment")
+			return nil, nil, false
+		}
+	}
+
+	return agreement, &amendment, true
+}
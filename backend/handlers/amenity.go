@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/utils"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AmenityHandler handles the amenity catalogue
+type AmenityHandler struct{}
+
+// NewAmenityHandler creates a new amenity handler
+func NewAmenityHandler() *AmenityHandler {
+	return &AmenityHandler{}
+}
+
+// AmenityRequest represents the request structure for creating an amenity
+type AmenityRequest struct {
+	Name     string `json:"name" binding:"required,min=2,max=100"`
+	Category string `json:"category" binding:"max=50"`
+}
+
+// UpdateAmenityRequest represents the request structure for updating an amenity
+type UpdateAmenityRequest struct {
+	Name     string  `json:"name" binding:"omitempty,min=2,max=100"`
+	Category *string `json:"category" binding:"omitempty,max=50"`
+	IsActive *bool   `json:"is_active"`
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// amenitySlug turns an amenity name into the slug used in search filters, e.g. "Solar Backup" to "solar-backup"
+func amenitySlug(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// GetAmenities handles getting the active amenity catalogue
+// @Summary Get amenities
+// @Description Get the amenities houses can offer, for listing forms and search filters
+// @Tags Amenities
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Amenities retrieved successfully"
+// @Router /amenities [get]
+func (ah *AmenityHandler) GetAmenities(c *gin.Context) {
+	var amenities []models.Amenity
+	if err := config.DB.Where("is_active = ?", true).Order("category ASC, name ASC").Find(&amenities).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch amenities", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Amenities retrieved successfully", gin.H{
+		"amenities": amenities,
+	})
+}
+
+// CreateAmenity handles an admin adding an amenity to the catalogue
+// @Summary Create amenity
+// @Description Add an amenity to the catalogue (admin only)
+// @Tags Amenities
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AmenityRequest true "Amenity details"
+// @Success 201 {object} map[string]interface{} "Amenity created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 409 {object} map[string]interface{} "Amenity already exists"
+// @Router /admin/amenities [post]
+func (ah *AmenityHandler) CreateAmenity(c *gin.Context) {
+	var req AmenityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	amenity := models.Amenity{
+		Name:     req.Name,
+		Slug:     amenitySlug(req.Name),
+		Category: req.Category,
+		IsActive: true,
+	}
+	if amenity.Slug == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Amenity name must contain letters or numbers", nil)
+		return
+	}
+	if ah.slugTaken(amenity.Slug, uuid.Nil) {
+		utils.ErrorResponse(c, http.StatusConflict, "An amenity with this name already exists", nil)
+		return
+	}
+
+	if err := config.DB.Create(&amenity).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create amenity", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Amenity created successfully", gin.H{
+		"amenity": amenity,
+	})
+}
+
+// UpdateAmenity handles an admin renaming, recategorising or deactivating an amenity.
+// Inactive amenities are hidden from the catalogue and search filters but stay linked to houses.
+func (ah *AmenityHandler) UpdateAmenity(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenity ID", err)
+		return
+	}
+
+	var amenity models.Amenity
+	if err := config.DB.First(&amenity, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Amenity not found")
+		return
+	}
+
+	var req UpdateAmenityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if req.Name != "" {
+		slug := amenitySlug(req.Name)
+		if slug == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Amenity name must contain letters or numbers", nil)
+			return
+		}
+		if ah.slugTaken(slug, amenity.ID) {
+			utils.ErrorResponse(c, http.StatusConflict, "An amenity with this name already exists", nil)
+			return
+		}
+		amenity.Name = req.Name
+		amenity.Slug = slug
+	}
+	if req.Category != nil {
+		amenity.Category = *req.Category
+	}
+	if req.IsActive != nil {
+		amenity.IsActive = *req.IsActive
+	}
+
+	if err := config.DB.Save(&amenity).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update amenity", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Amenity updated successfully", gin.H{
+		"amenity": amenity,
+	})
+}
+
+// DeleteAmenity handles an admin removing an amenity from the catalogue and from every house
+func (ah *AmenityHandler) DeleteAmenity(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenity ID", err)
+		return
+	}
+
+	var amenity models.Amenity
+	if err := config.DB.First(&amenity, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Amenity not found")
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("amenity_id = ?", amenity.ID).Delete(&models.HouseAmenity{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&amenity).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete amenity", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Amenity deleted successfully", nil)
+}
+
+// slugTaken reports whether another amenity already uses the slug
+func (ah *AmenityHandler) slugTaken(slug string, excludeID uuid.UUID) bool {
+	var count int64
+	config.DB.Model(&models.Amenity{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count)
+	return count > 0
+}
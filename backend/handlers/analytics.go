@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxAnalyticsDays caps the period an analytics request covers
+const maxAnalyticsDays = 365
+
+// AnalyticsHandler handles listing performance analytics for landlords
+type AnalyticsHandler struct{}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler() *AnalyticsHandler {
+	return &AnalyticsHandler{}
+}
+
+// houseAnalytics is the performance of one listing in a portfolio
+type houseAnalytics struct {
+	HouseID       uuid.UUID                `json:"house_id"`
+	Title         string                   `json:"title"`
+	ListingStatus models.ListingStatus     `json:"listing_status"`
+	Totals        services.ListingMetrics  `json:"totals"`
+	Rates         services.ConversionRates `json:"rates"`
+	Changes       map[string]*float64      `json:"changes"`
+}
+
+// GetHouseAnalytics handles getting the performance of a listing
+// @Summary Get house analytics
+// @Description Get a listing's impressions, views, favorites, viewings and agreements over the last days, with daily trends, changes from the previous period and a comparison with similar listings nearby. Stats are rolled up periodically, so the latest events may not be counted yet.
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param days query int false "Number of days up to today" default(30)
+// @Success 200 {object} map[string]interface{} "House analytics retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid house ID or days"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/analytics [get]
+func (ah *AnalyticsHandler) GetHouseAnalytics(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	from, to, ok := analyticsPeriod(c)
+	if !ok {
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+	if house.LandlordID != user.ID && user.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only view analytics for your own houses")
+		return
+	}
+
+	houseIDs := []uuid.UUID{house.ID}
+	totals, previous, daily, err := periodMetrics(houseIDs, from, to)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch house analytics", err)
+		return
+	}
+
+	comparison, err := services.CompareListingMetrics(&house, totals, from, to)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to compare with similar listings", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "House analytics retrieved successfully", gin.H{
+		"house_id":        house.ID,
+		"period":          analyticsPeriodJSON(from, to),
+		"totals":          totals,
+		"previous_totals": previous,
+		"changes":         totals.Changes(previous),
+		"rates":           totals.Rates(),
+		"daily":           daily,
+		"comparison":      comparison,
+	})
+}
+
+// GetPortfolioAnalytics handles getting the performance of all of a landlord's listings
+// @Summary Get portfolio analytics
+// @Description Get the combined impressions, views, favorites, viewings and agreements of the current landlord's listings over the last days, with daily trends, changes from the previous period and a breakdown per listing, most viewed first. Admins can pass landlord_id.
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Number of days up to today" default(30)
+// @Param landlord_id query string false "Landlord whose listings to report on (admins only)"
+// @Success 200 {object} map[string]interface{} "Portfolio analytics retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid days or landlord ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/mine/analytics [get]
+func (ah *AnalyticsHandler) GetPortfolioAnalytics(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	from, to, ok := analyticsPeriod(c)
+	if !ok {
+		return
+	}
+
+	landlordID := user.ID
+	if value := c.Query("landlord_id"); value != "" && user.Role == models.RoleAdmin {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid landlord ID", err)
+			return
+		}
+		landlordID = id
+	}
+
+	var houses []models.House
+	if err := config.DB.Where("landlord_id = ?", landlordID).Find(&houses).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch houses", err)
+		return
+	}
+	houseIDs := make([]uuid.UUID, 0, len(houses))
+	for _, house := range houses {
+		houseIDs = append(houseIDs, house.ID)
+	}
+
+	totals, previous, daily, err := periodMetrics(houseIDs, from, to)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch portfolio analytics", err)
+		return
+	}
+
+	// Break the totals down per listing
+	days := int(to.Sub(from).Hours()/24) + 1
+	byHouse, err := services.ListingMetricsByHouse(houseIDs, from, to)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch portfolio analytics", err)
+		return
+	}
+	previousByHouse, err := services.ListingMetricsByHouse(houseIDs, from.AddDate(0, 0, -days), from.AddDate(0, 0, -1))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch portfolio analytics", err)
+		return
+	}
+
+	breakdown := make([]houseAnalytics, 0, len(houses))
+	for _, house := range houses {
+		metrics := byHouse[house.ID]
+		breakdown = append(breakdown, houseAnalytics{
+			HouseID:       house.ID,
+			Title:         house.Title,
+			ListingStatus: house.ListingStatus,
+			Totals:        metrics,
+			Rates:         metrics.Rates(),
+			Changes:       metrics.Changes(previousByHouse[house.ID]),
+		})
+	}
+	sort.SliceStable(breakdown, func(i, j int) bool {
+		return breakdown[i].Totals.Views > breakdown[j].Totals.Views
+	})
+
+	utils.SuccessResponse(c, http.StatusOK, "Portfolio analytics retrieved successfully", gin.H{
+		"landlord_id":     landlordID,
+		"period":          analyticsPeriodJSON(from, to),
+		"totals":          totals,
+		"previous_totals": previous,
+		"changes":         totals.Changes(previous),
+		"rates":           totals.Rates(),
+		"daily":           daily,
+		"houses":          breakdown,
+	})
+}
+
+// analyticsPeriod parses the days query parameter into the period ending today. It writes the
+// error response and returns false when the request cannot continue.
+func analyticsPeriod(c *gin.Context) (from, to time.Time, ok bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > maxAnalyticsDays {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", maxAnalyticsDays), err)
+		return time.Time{}, time.Time{}, false
+	}
+
+	now := time.Now()
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return to.AddDate(0, 0, 1-days), to, true
+}
+
+// analyticsPeriodJSON describes an analytics period in a response
+func analyticsPeriodJSON(from, to time.Time) gin.H {
+	return gin.H{
+		"from": from.Format("2006-01-02"),
+		"to":   to.Format("2006-01-02"),
+		"days": int(to.Sub(from).Hours()/24) + 1,
+	}
+}
+
+// periodMetrics returns the combined metrics of listings over a period and the period of the same
+// length before it, and the daily metrics of the period
+func periodMetrics(houseIDs []uuid.UUID, from, to time.Time) (totals, previous services.ListingMetrics, daily []services.DailyListingMetrics, err error) {
+	days := int(to.Sub(from).Hours()/24) + 1
+	if totals, err = services.SumListingMetrics(houseIDs, from, to); err != nil {
+		return
+	}
+	if previous, err = services.SumListingMetrics(houseIDs, from.AddDate(0, 0, -days), from.AddDate(0, 0, -1)); err != nil {
+		return
+	}
+	daily, err = services.DailyMetrics(houseIDs, from, to)
+	return
+}
+
+// recordListingEvents records a listing event for each house on behalf of the current visitor.
+// Signed-in users are identified by their ID and anonymous visitors by a daily key derived from their
+// IP address and browser, so unique visitors can be counted without storing either. Requests from
+// crawlers and other bots are not counted.
+func recordListingEvents(c *gin.Context, kind models.ListingEventKind, houseIDs []uuid.UUID) {
+	if isBot(c.Request.UserAgent()) {
+		return
+	}
+
+	var viewerID *uuid.UUID
+	var visitorKey string
+	if user, exists := c.Get("user"); exists {
+		id := user.(models.User).ID
+		viewerID = &id
+		visitorKey = id.String()
+	} else {
+		visitorKey = services.VisitorKey(c.ClientIP(), c.Request.UserAgent(), time.Now())
+	}
+	services.RecordListingEvents(kind, houseIDs, viewerID, visitorKey)
+}
+
+// botUserAgents are user agent fragments of crawlers, link previews and scripted clients
+var botUserAgents = []string{
+	"bot", "crawl", "spider", "slurp", "preview", "facebookexternalhit", "headless",
+	"curl/", "wget/", "python-requests", "go-http-client",
+}
+
+// isBot reports whether a user agent belongs to a crawler or script rather than a person browsing.
+// Requests without a user agent are treated as scripts.
+func isBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, fragment := range botUserAgents {
+		if strings.Contains(userAgent, fragment) {
+			return true
+		}
+	}
+	return false
+}
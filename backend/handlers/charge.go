@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errLaterMeterReading is returned when a meter already has a reading after the one being recorded
+var errLaterMeterReading = errors.New("a later reading has already been recorded for this meter")
+
+// ChargeHandler handles utility and service charge requests
+type ChargeHandler struct {
+	billingService *services.BillingService
+}
+
+// NewChargeHandler creates a new charge handler
+func NewChargeHandler() *ChargeHandler {
+	return &ChargeHandler{
+		billingService: services.NewBillingService(),
+	}
+}
+
+// TariffBandRequest represents one block of a tiered tariff
+type TariffBandRequest struct {
+	UpTo *float64 `json:"up_to" binding:"omitempty,gt=0"`
+	Rate float64  `json:"rate" binding:"min=0"`
+}
+
+// CreateChargeTypeRequest represents the request structure for creating a charge type
+type CreateChargeTypeRequest struct {
+	Name    string  `json:"name" binding:"required,min=2,max=100"`
+	Billing string  `json:"billing" binding:"required,oneof=recurring metered"`
+	Amount  float64 `json:"amount" binding:"min=0"`
+	Unit    string  `json:"unit" binding:"max=20"`
+	// Tariff is required for metered charges; bands are listed cheapest block first
+	Tariff []TariffBandRequest `json:"tariff" binding:"omitempty,dive"`
+}
+
+// UpdateChargeTypeRequest represents the request structure for updating a charge type
+type UpdateChargeTypeRequest struct {
+	Name     string              `json:"name" binding:"omitempty,min=2,max=100"`
+	Amount   *float64            `json:"amount" binding:"omitempty,min=0"`
+	Unit     *string             `json:"unit" binding:"omitempty,max=20"`
+	Tariff   []TariffBandRequest `json:"tariff" binding:"omitempty,dive"`
+	IsActive *bool               `json:"is_active"`
+}
+
+// MeterReadingRequest represents the request structure for recording a meter reading
+type MeterReadingRequest struct {
+	Reading     float64 `json:"reading" binding:"min=0"`
+	ReadingDate string  `json:"reading_date"`
+}
+
+// CreateChargeType handles a landlord adding a utility or service charge to a house
+// @Summary Create charge type
+// @Description Add a recurring or metered charge, such as security or ZESCO electricity, to a house
+// @Tags Charges
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body CreateChargeTypeRequest true "Charge type details"
+// @Success 201 {object} map[string]interface{} "Charge type created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Router /houses/{id}/charge-types [post]
+func (ch *ChargeHandler) CreateChargeType(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	if house.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only add charges to your own houses")
+		return
+	}
+
+	var req CreateChargeTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	tariff, err := buildTariff(req.Tariff)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	billing := models.ChargeBilling(req.Billing)
+	if billing == models.BillingMetered && len(tariff) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Metered charges need a tariff", nil)
+		return
+	}
+	if billing == models.BillingRecurring && req.Amount <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Recurring charges need a monthly amount", nil)
+		return
+	}
+
+	chargeType := models.ChargeType{
+		HouseID:  house.ID,
+		Name:     req.Name,
+		Billing:  billing,
+		Amount:   req.Amount,
+		Unit:     req.Unit,
+		Tariff:   tariff,
+		IsActive: true,
+	}
+
+	if err := config.DB.Create(&chargeType).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create charge type", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Charge type created successfully", gin.H{
+		"charge_type": chargeType,
+	})
+}
+
+// GetChargeTypes handles getting the active charges of a house, so prospective tenants can see them alongside the rent
+// @Summary Get charge types
+// @Description Get the active recurring and metered charges of a house
+// @Tags Charges
+// @Produce json
+// @Param id path string true "House ID"
+// @Success 200 {object} map[string]interface{} "Charge types retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid house ID"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/charge-types [get]
+func (ch *ChargeHandler) GetChargeTypes(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil || !canViewListing(c, &house) {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	var chargeTypes []models.ChargeType
+	if err := config.DB.Where("house_id = ? AND is_active = ?", id, true).Order("name ASC").Find(&chargeTypes).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch charge types", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Charge types retrieved successfully", gin.H{
+		"charge_types": chargeTypes,
+	})
+}
+
+// UpdateChargeType handles a landlord changing a charge's amount, tariff or active state
+// @Summary Update charge type
+// @Description Update a charge's name, amount, unit, tariff or active state. Changes apply to charges billed from now on.
+// @Tags Charges
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param chargeTypeId path string true "Charge type ID"
+// @Param request body UpdateChargeTypeRequest true "Charge type updates"
+// @Success 200 {object} map[string]interface{} "Charge type updated successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Charge type not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/charge-types/{chargeTypeId} [put]
+func (ch *ChargeHandler) UpdateChargeType(c *gin.Context) {
+	chargeType, ok := ch.loadManagedChargeType(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateChargeTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Update fields; new amounts and tariffs apply to charges billed from now on
+	if req.Name != "" {
+		chargeType.Name = req.Name
+	}
+	if req.Amount != nil {
+		chargeType.Amount = *req.Amount
+	}
+	if req.Unit != nil {
+		chargeType.Unit = *req.Unit
+	}
+	if req.Tariff != nil {
+		tariff, err := buildTariff(req.Tariff)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if chargeType.Billing == models.BillingMetered && len(tariff) == 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Metered charges need a tariff", nil)
+			return
+		}
+		chargeType.Tariff = tariff
+	}
+	if req.IsActive != nil {
+		chargeType.IsActive = *req.IsActive
+	}
+
+	if err := config.DB.Omit(clause.Associations).Save(chargeType).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update charge type", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Charge type updated successfully", gin.H{
+		"charge_type": chargeType,
+	})
+}
+
+// RecordMeterReading handles a landlord entering a meter reading, billing the consumption since the last reading
+// @Summary Record meter reading
+// @Description Record a meter reading for a metered charge. The consumption since the previous reading is priced from the tariff and billed to the agreement occupying the house.
+// @Tags Charges
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param chargeTypeId path string true "Charge type ID"
+// @Param request body MeterReadingRequest true "Meter reading"
+// @Success 201 {object} map[string]interface{} "Meter reading recorded successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data, a later reading exists or the reading decreased"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Charge type not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/charge-types/{chargeTypeId}/readings [post]
+func (ch *ChargeHandler) RecordMeterReading(c *gin.Context) {
+	chargeType, ok := ch.loadManagedChargeType(c)
+	if !ok {
+		return
+	}
+
+	userModel := c.MustGet("user").(models.User)
+
+	if chargeType.Billing != models.BillingMetered {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Meter readings can only be recorded for metered charges", nil)
+		return
+	}
+
+	var req MeterReadingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	readingDate := time.Now()
+	if req.ReadingDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.ReadingDate)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid reading date format", err)
+			return
+		}
+		readingDate = parsed
+	}
+	if readingDate.After(time.Now()) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Reading date cannot be in the future", nil)
+		return
+	}
+
+	reading := models.MeterReading{
+		ChargeTypeID: chargeType.ID,
+		Reading:      req.Reading,
+		ReadingDate:  readingDate,
+		RecordedByID: userModel.ID,
+	}
+
+	var charge *models.Charge
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the meter so concurrent readings are recorded one after the other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.ChargeType{}, chargeType.ID).Error; err != nil {
+			return err
+		}
+
+		// Readings must be entered in order so consumption is always measured from the latest reading
+		var later int64
+		if err := tx.Model(&models.MeterReading{}).
+			Where("charge_type_id = ? AND reading_date > ?", chargeType.ID, readingDate).
+			Count(&later).Error; err != nil {
+			return err
+		}
+		if later > 0 {
+			return errLaterMeterReading
+		}
+
+		if err := tx.Create(&reading).Error; err != nil {
+			return err
+		}
+		var err error
+		charge, err = ch.billingService.ChargeMeterReading(tx, chargeType, &reading)
+		return err
+	})
+	if errors.Is(err, errLaterMeterReading) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "A later reading has already been recorded for this meter", nil)
+		return
+	}
+	if errors.Is(err, services.ErrMeterReadingDecreased) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Meter reading is lower than the previous reading", nil)
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to record meter reading", err)
+		return
+	}
+
+	if charge != nil {
+		var agreement models.RentalAgreement
+		if err := config.DB.Preload("House").First(&agreement, charge.AgreementID).Error; err == nil {
+			services.NotifyAll(services.AgreementTenantIDs(&agreement), "New Utility Charge",
+				fmt.Sprintf("%s: ZMW %.2f is due on %s", charge.Description, charge.Amount, charge.DueDate.Format("2006-01-02")),
+				"payment")
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Meter reading recorded successfully", gin.H{
+		"reading": reading,
+		"charge":  charge,
+	})
+}
+
+// GetMeterReadings handles getting the reading history of a meter
+// @Summary Get meter readings
+// @Description Get the readings recorded for a metered charge, latest first
+// @Tags Charges
+// @Produce json
+// @Security BearerAuth
+// @Param chargeTypeId path string true "Charge type ID"
+// @Success 200 {object} map[string]interface{} "Meter readings retrieved successfully"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Charge type not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/charge-types/{chargeTypeId}/readings [get]
+func (ch *ChargeHandler) GetMeterReadings(c *gin.Context) {
+	chargeType, ok := ch.loadManagedChargeType(c)
+	if !ok {
+		return
+	}
+
+	var readings []models.MeterReading
+	if err := config.DB.Where("charge_type_id = ?", chargeType.ID).
+		Order("reading_date DESC").
+		Find(&readings).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch meter readings", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Meter readings retrieved successfully", gin.H{
+		"readings": readings,
+	})
+}
+
+// GetAgreementCharges handles getting the rent and utility charges billed to an agreement
+// @Summary Get agreement charges
+// @Description Get the rent, recurring and metered charges billed to an agreement, latest due first
+// @Tags Charges
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Filter by status (unpaid, partial, paid)"
+// @Param kind query string false "Filter by kind (rent, recurring, metered)"
+// @Success 200 {object} map[string]interface{} "Charges retrieved successfully"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /rentals/{id}/charges [get]
+func (ch *ChargeHandler) GetAgreementCharges(c *gin.Context) {
+	agreement, _, ok := loadAgreementParty(c)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+	kind := c.Query("kind")
+
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Charge{}).Where("agreement_id = ?", agreement.ID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	var charges []models.Charge
+	if err := query.Preload("ChargeType").
+		Order("due_date DESC").
+		Offset(offset).Limit(limit).
+		Find(&charges).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch charges", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Charges retrieved successfully", gin.H{
+		"charges": charges,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// GetAgreementBalance handles getting an agreement's balance with its outstanding charges and recent payments
+// @Summary Get agreement balance
+// @Description Get the total billed in rent and utility charges, the total paid and the outstanding charges of an agreement
+// @Tags Charges
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Success 200 {object} map[string]interface{} "Balance retrieved successfully"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Router /rentals/{id}/balance [get]
+func (ch *ChargeHandler) GetAgreementBalance(c *gin.Context) {
+	agreement, _, ok := loadAgreementParty(c)
+	if !ok {
+		return
+	}
+
+	var outstanding []models.Charge
+	config.DB.Preload("ChargeType").
+		Where("agreement_id = ? AND status <> ?", agreement.ID, models.ChargeStatusPaid).
+		Order("due_date ASC").
+		Find(&outstanding)
+
+	var payments []models.Payment
+	config.DB.Preload("Tenant").
+		Where("agreement_id = ? AND status = ?", agreement.ID, models.PaymentStatusCompleted).
+		Order("payment_date DESC").
+		Limit(10).
+		Find(&payments)
+
+	// Totals by kind so rent and utilities can be shown side by side
+	var byKind []struct {
+		Kind   models.ChargeKind `json:"kind"`
+		Amount float64           `json:"amount"`
+	}
+	config.DB.Model(&models.Charge{}).
+		Where("agreement_id = ?", agreement.ID).
+		Select("kind, COALESCE(SUM(amount), 0) as amount").
+		Group("kind").
+		Scan(&byKind)
+
+	utils.SuccessResponse(c, http.StatusOK, "Balance retrieved successfully", gin.H{
+		"agreement_id":        agreement.ID,
+		"balance":             ch.billingService.Balance(agreement.ID),
+		"charged_by_kind":     byKind,
+		"outstanding_charges": outstanding,
+		"recent_payments":     payments,
+	})
+}
+
+// loadManagedChargeType loads the charge type from the route and checks the user manages its house
+func (ch *ChargeHandler) loadManagedChargeType(c *gin.Context) (*models.ChargeType, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, false
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("chargeTypeId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid charge type ID", err)
+		return nil, false
+	}
+
+	var chargeType models.ChargeType
+	if err := config.DB.Preload("House").First(&chargeType, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Charge type not found")
+		return nil, false
+	}
+
+	if chargeType.House.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only manage charges for your own houses")
+		return nil, false
+	}
+
+	return &chargeType, true
+}
+
+// buildTariff validates tariff bands and converts them to a tariff. Band limits must increase and
+// the last band must be open-ended so every unit is priced.
+func buildTariff(bands []TariffBandRequest) (models.Tariff, error) {
+	if len(bands) > 0 && bands[len(bands)-1].UpTo != nil {
+		return nil, errors.New("the last tariff band must be open-ended")
+	}
+
+	tariff := make(models.Tariff, 0, len(bands))
+	previous := 0.0
+	for i, band := range bands {
+		if band.UpTo == nil && i != len(bands)-1 {
+			return nil, errors.New("only the last tariff band can be open-ended")
+		}
+		if band.UpTo != nil {
+			if *band.UpTo <= previous {
+				return nil, errors.New("tariff band limits must increase")
+			}
+			previous = *band.UpTo
+		}
+		tariff = append(tariff, models.TariffBand{UpTo: band.UpTo, Rate: band.Rate})
+	}
+	return tariff, nil
+}
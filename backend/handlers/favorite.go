@@ -44,7 +44,7 @@ func (fh *FavoriteHandler) AddToFavorites(c *gin.Context) {
 
 	// Get house
 	var house models.House
-	if err := config.DB.First(&house, id).Error; err != nil {
+	if err := config.DB.First(&house, id).Error; err != nil || house.ListingStatus != models.ListingPublished {
 		utils.NotFoundResponse(c, "House not found")
 		return
 	}
@@ -67,6 +67,8 @@ func (fh *FavoriteHandler) AddToFavorites(c *gin.Context) {
 		return
 	}
 
+	recordListingEvents(c, models.EventFavorite, []uuid.UUID{id})
+
 	// Load relationships
 	config.DB.Preload("House").Preload("Tenant").First(&favorite, favorite.ID)
 
@@ -127,10 +129,12 @@ func (fh *FavoriteHandler) GetFavorites(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Build query
+	// Listings that are unpublished, under review or removed drop out of favorites until they are published again
 	query := config.DB.Model(&models.Favorite{}).
 		Where("tenant_id = ?", userModel.ID).
+		Where("house_id IN (?)", config.DB.Model(&models.House{}).Select("id").Where("listing_status = ?", models.ListingPublished)).
 		Preload("House.Landlord").
-		Preload("House.Images")
+		Preload("House.Images", orderedImages)
 
 	// Get total count
 	var total int64
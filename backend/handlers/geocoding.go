@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeocodingHandler handles address and map pin lookups for listing forms
+type GeocodingHandler struct {
+	geocoder services.Geocoder
+}
+
+// NewGeocodingHandler creates a new geocoding handler
+func NewGeocodingHandler(geocoder services.Geocoder) *GeocodingHandler {
+	return &GeocodingHandler{geocoder: geocoder}
+}
+
+// Geocode handles finding the coordinates of an address
+// @Summary Geocode address
+// @Description Find the coordinates and location of an address, to place the map pin of a listing form
+// @Tags Geocoding
+// @Produce json
+// @Security BearerAuth
+// @Param address query string true "Address, e.g. 12 Kabulonga Road, Lusaka"
+// @Success 200 {object} map[string]interface{} "Address geocoded successfully"
+// @Failure 400 {object} map[string]interface{} "Address is required"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - landlords and admins only"
+// @Failure 404 {object} map[string]interface{} "No matching place found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /geocode [get]
+func (gh *GeocodingHandler) Geocode(c *gin.Context) {
+	address := strings.TrimSpace(c.Query("address"))
+	if address == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Address is required", nil)
+		return
+	}
+
+	result, err := gh.geocoder.Geocode(c.Request.Context(), address)
+	if err != nil {
+		respondGeocodeError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Address geocoded successfully", gin.H{
+		"result": result,
+	})
+}
+
+// ReverseGeocode handles suggesting an address for a map pin
+// @Summary Reverse geocode coordinates
+// @Description Suggest the address and location of a map pin, to fill in the address of a listing form
+// @Tags Geocoding
+// @Produce json
+// @Security BearerAuth
+// @Param point query string true "Coordinates (lat,lng)"
+// @Success 200 {object} map[string]interface{} "Address suggested successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid coordinates"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - landlords and admins only"
+// @Failure 404 {object} map[string]interface{} "No matching place found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /geocode/reverse [get]
+func (gh *GeocodingHandler) ReverseGeocode(c *gin.Context) {
+	lat, lng, err := services.ParsePoint(c.Query("point"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid coordinates", err)
+		return
+	}
+
+	result, err := gh.geocoder.ReverseGeocode(c.Request.Context(), lat, lng)
+	if err != nil {
+		respondGeocodeError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Address suggested successfully", gin.H{
+		"result": result,
+	})
+}
+
+// respondGeocodeError writes the response for a failed lookup
+func respondGeocodeError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrNoGeocodeMatch) {
+		utils.NotFoundResponse(c, "No matching place found")
+		return
+	}
+	utils.InternalServerErrorResponse(c, "Failed to geocode", err)
+}
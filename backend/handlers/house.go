@@ -5,89 +5,91 @@ import (
 	"bondihub/models"
 	"bondihub/services"
 	"bondihub/utils"
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errHouseImageLimit is returned when a house already has the maximum number of images
+var errHouseImageLimit = errors.New("house image limit reached")
+
 // HouseHandler handles house-related requests
 type HouseHandler struct {
-	cloudinaryService *services.CloudinaryService
+	imageStorage services.ImageStorage
+	imageCleanup *services.ImageCleanupService
+	geocoder     services.Geocoder
 }
 
 // NewHouseHandler creates a new house handler
-func NewHouseHandler() *HouseHandler {
-	// Log config values for debugging (mask secret)
-	cfg := config.AppConfig
-	if cfg.CloudinaryURL != "" {
-		// Mask the secret in the URL
-		urlDisplay := cfg.CloudinaryURL
-		if len(urlDisplay) > 30 {
-			urlDisplay = urlDisplay[:20] + "***" + urlDisplay[len(urlDisplay)-10:]
-		}
-		log.Printf("Initializing Cloudinary service from CLOUDINARY_URL: %s", urlDisplay)
-	} else {
-		secretDisplay := "***"
-		if len(cfg.CloudinarySecret) > 10 {
-			secretDisplay = cfg.CloudinarySecret[:10] + "..."
-		}
-		log.Printf("Initializing Cloudinary service with Cloud: %s, Key: %s, Secret: %s...", 
-			cfg.CloudinaryCloud, cfg.CloudinaryKey, secretDisplay)
-	}
-	
-	cloudinaryService, err := services.NewCloudinaryService()
+func NewHouseHandler(geocoder services.Geocoder) *HouseHandler {
+	imageStorage, err := services.NewImageStorage()
 	if err != nil {
-		log.Printf("❌ ERROR: Failed to initialize Cloudinary service: %v", err)
-		log.Printf("   Cloud: %s, Key: %s, Secret length: %d", 
-			cfg.CloudinaryCloud, cfg.CloudinaryKey, len(cfg.CloudinarySecret))
-		log.Println("   Image uploads will not work until Cloudinary is properly configured")
-		// Continue without Cloudinary service - uploads will fail gracefully
+		log.Printf("❌ ERROR: Failed to initialize %s image storage: %v", config.AppConfig.StorageBackend, err)
+		log.Println("   Image uploads will not work until image storage is properly configured")
+		// Continue without image storage - uploads will fail gracefully
 	} else {
-		log.Println("✅ Cloudinary service initialized successfully")
+		log.Printf("✅ Image storage initialized (%s)", config.AppConfig.StorageBackend)
 	}
 	return &HouseHandler{
-		cloudinaryService: cloudinaryService,
+		imageStorage: imageStorage,
+		imageCleanup: services.NewImageCleanupService(imageStorage),
+		geocoder:     geocoder,
 	}
 }
 
 // CreateHouseRequest represents the request structure for creating a house
 type CreateHouseRequest struct {
-	Title       string   `json:"title" binding:"required,min=5,max=200"`
-	Description string   `json:"description" binding:"required,min=10"`
-	Address     string   `json:"address" binding:"required,min=10"`
-	MonthlyRent float64  `json:"monthly_rent" binding:"required,min=0"`
-	HouseType   string   `json:"house_type" binding:"required,oneof=apartment house studio townhouse commercial"`
-	Latitude    *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
-	Longitude   *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
-	Bedrooms    int      `json:"bedrooms" binding:"min=0"`
-	Bathrooms   int      `json:"bathrooms" binding:"min=0"`
-	Area        float64  `json:"area" binding:"min=0"`
-	IsFeatured  bool     `json:"is_featured"`
+	Title       string      `json:"title" binding:"required,min=5,max=200"`
+	Description string      `json:"description" binding:"required,min=10"`
+	Address     string      `json:"address" binding:"omitempty,min=10"` // suggested from the coordinates when omitted
+	LocationID  *uuid.UUID  `json:"location_id"`                        // the area, or failing that the town, of the house
+	MonthlyRent float64     `json:"monthly_rent" binding:"required,min=0"`
+	HouseType   string      `json:"house_type" binding:"required,oneof=apartment house studio townhouse commercial"`
+	Latitude    *float64    `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude   *float64    `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	Bedrooms    int         `json:"bedrooms" binding:"min=0"`
+	Bathrooms   int         `json:"bathrooms" binding:"min=0"`
+	Area        float64     `json:"area" binding:"min=0"`
+	IsFeatured  bool        `json:"is_featured"`
+	AmenityIDs  []uuid.UUID `json:"amenity_ids"`
+	PropertyID  *uuid.UUID  `json:"property_id"` // adds the house as a unit of a property, sharing its address
+	UnitLabel   string      `json:"unit_label" binding:"max=50"`
+	Draft       bool        `json:"draft"` // saves the listing without submitting it for review
 }
 
 // UpdateHouseRequest represents the request structure for updating a house
 type UpdateHouseRequest struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Address     string  `json:"address"`
-	MonthlyRent float64 `json:"monthly_rent"`
-	Status      string  `json:"status"`
-	HouseType   string  `json:"house_type"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	Bedrooms    int     `json:"bedrooms"`
-	Bathrooms   int     `json:"bathrooms"`
-	Area        float64 `json:"area"`
-	IsFeatured  bool    `json:"is_featured"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Address     string      `json:"address"`
+	LocationID  *string     `json:"location_id"` // "" clears the location
+	MonthlyRent float64     `json:"monthly_rent"`
+	Status      string      `json:"status"`
+	HouseType   string      `json:"house_type"`
+	Latitude    float64     `json:"latitude"`
+	Longitude   float64     `json:"longitude"`
+	Bedrooms    int         `json:"bedrooms"`
+	Bathrooms   int         `json:"bathrooms"`
+	Area        float64     `json:"area"`
+	IsFeatured  bool        `json:"is_featured"`
+	AmenityIDs  []uuid.UUID `json:"amenity_ids"` // replaces the house's amenities when set; [] clears them
+	PropertyID  *string     `json:"property_id"` // moves the house into a property; "" detaches it
+	UnitLabel   *string     `json:"unit_label" binding:"omitempty,max=50"`
 }
 
 // CreateHouse handles creating a new house
 // @Summary Create house
-// @Description Create a new house listing for rent (landlords and admins only)
+// @Description Create a new house listing for rent (landlords and admins only). Missing coordinates are geocoded from the address, and a missing address is suggested from the coordinates. Listings by landlords are submitted for review, or saved as drafts, and are only shown to the public once a moderator approves them.
 // @Tags Houses
 // @Accept json
 // @Produce json
@@ -121,7 +123,7 @@ func (hh *HouseHandler) CreateHouse(c *gin.Context) {
 	}
 
 	// Set default coordinates if not provided (default to 0, 0)
-	// This allows users to omit coordinates, which will default to (0, 0)
+	// This allows users to omit coordinates, which are then geocoded from the address
 	latitude := 0.0
 	longitude := 0.0
 	if req.Latitude != nil {
@@ -131,6 +133,14 @@ func (hh *HouseHandler) CreateHouse(c *gin.Context) {
 		longitude = *req.Longitude
 	}
 
+	// Coordinates come as a pair; half a pin would be geocoded over or saved at the equator
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": "Provide both latitude and longitude, or omit both to geocode the address.",
+		})
+		return
+	}
+
 	// Only validate coordinates if BOTH are explicitly provided (not nil)
 	// If both are provided and equal to (0, 0), reject as invalid
 	// If omitted, defaulting to (0, 0) is allowed
@@ -141,12 +151,56 @@ func (hh *HouseHandler) CreateHouse(c *gin.Context) {
 		return
 	}
 
+	amenities, err := services.LoadAmenities(req.AmenityIDs)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenities", err)
+		return
+	}
+
+	if req.LocationID != nil && !checkLocation(c, *req.LocationID) {
+		return
+	}
+
+	// Units of a property belong to the property's landlord and share its address, location and coordinates
+	landlordID := userModel.ID
+	address := req.Address
+	locationID := req.LocationID
+	var geocodeSource, geocodePrecision string
+	var geocoded *services.GeocodeResult
+	if req.PropertyID != nil {
+		property, ok := loadPropertyForUnit(c, *req.PropertyID, userModel)
+		if !ok {
+			return
+		}
+		landlordID = property.LandlordID
+		address = property.Address
+		locationID = property.LocationID
+		latitude = property.Latitude
+		longitude = property.Longitude
+		geocodeSource, geocodePrecision = property.GeocodeSource, property.GeocodePrecision
+	} else {
+		// Fill in the coordinates from the address, or the address from a map pin
+		place := services.Place{Address: address, Latitude: latitude, Longitude: longitude, LocationID: locationID}
+		geocoded = services.CompletePlace(c.Request.Context(), hh.geocoder, &place)
+		if place.Address == "" {
+			utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+				"error": "address is required unless property_id is set or the coordinates are in a known area",
+			})
+			return
+		}
+		address, latitude, longitude, locationID = place.Address, place.Latitude, place.Longitude, place.LocationID
+		geocodeSource, geocodePrecision = place.Source, place.Precision
+	}
+
 	// Create house
 	house := models.House{
-		LandlordID:  userModel.ID,
+		LandlordID:  landlordID,
+		PropertyID:  req.PropertyID,
+		UnitLabel:   req.UnitLabel,
 		Title:       req.Title,
 		Description: req.Description,
-		Address:     req.Address,
+		Address:     address,
+		LocationID:  locationID,
 		MonthlyRent: req.MonthlyRent,
 		Status:      models.StatusAvailable,
 		HouseType:   models.HouseType(req.HouseType),
@@ -156,6 +210,9 @@ func (hh *HouseHandler) CreateHouse(c *gin.Context) {
 		Bathrooms:   req.Bathrooms,
 		Area:        req.Area,
 		IsFeatured:  req.IsFeatured,
+
+		GeocodeSource:    geocodeSource,
+		GeocodePrecision: geocodePrecision,
 	}
 
 	// Set featured expiry if house is featured
@@ -164,23 +221,83 @@ func (hh *HouseHandler) CreateHouse(c *gin.Context) {
 		house.FeaturedUntil = &featuredUntil
 	}
 
-	if err := config.DB.Create(&house).Error; err != nil {
+	// Listings by landlords wait for a moderator; admins publish straight away
+	switch {
+	case userModel.Role == models.RoleAdmin:
+		now := time.Now()
+		house.ListingStatus = models.ListingPublished
+		house.SearchMatchQueuedAt = &now
+	case req.Draft:
+		house.ListingStatus = models.ListingDraft
+	default:
+		now := time.Now()
+		house.ListingStatus = models.ListingPendingReview
+		house.SubmittedAt = &now
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&house).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.ListingModeration{
+			HouseID:  house.ID,
+			ActorID:  &userModel.ID,
+			ToStatus: house.ListingStatus,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&house).Association("Amenities").Replace(amenities)
+	})
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to create house", err)
 		return
 	}
 
+	assessListingRisk(house.ID)
+	if house.ListingStatus == models.ListingPublished {
+		services.WakeSearchMatcher()
+	}
+
 	// Load landlord information
-	config.DB.Preload("Landlord").First(&house, house.ID)
+	config.DB.Preload("Landlord").Preload("Property").Preload("Location").Preload("Amenities", activeAmenities).First(&house, house.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, "House created successfully", gin.H{
-		"house": house,
+		"house":    house,
+		"geocoded": geocoded,
 	})
 }
 
+// orderedImages sorts preloaded house images in gallery order, primary image first
+func orderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("is_primary DESC, sort_order ASC, created_at ASC")
+}
+
+// activeAmenities limits preloaded amenities to the active catalogue
+func activeAmenities(db *gorm.DB) *gorm.DB {
+	return db.Where("is_active = ?", true).Order("category ASC, name ASC")
+}
+
+// splitQueryList splits a comma separated query parameter, dropping blanks and duplicates
+func splitQueryList(value string) []string {
+	var items []string
+	seen := map[string]bool{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// maxSearchRadiusKm caps the radius of a near search
+const maxSearchRadiusKm = 100.0
+
 // GetHouses handles getting all houses with pagination and filters
 // GetHouses retrieves a list of houses with filtering and pagination
 // @Summary Get houses
-// @Description Get a paginated list of houses with optional filtering
+// @Description Get a paginated list of houses with optional filtering. Each house returned is counted as an impression in its landlord's analytics.
 // @Tags Houses
 // @Accept json
 // @Produce json
@@ -188,12 +305,23 @@ func (hh *HouseHandler) CreateHouse(c *gin.Context) {
 // @Param limit query int false "Items per page" default(10)
 // @Param house_type query string false "House type filter"
 // @Param status query string false "Status filter"
+// @Param available_on query string false "Only houses free to move into on this date (YYYY-MM-DD)"
 // @Param min_rent query number false "Minimum rent filter"
 // @Param max_rent query number false "Maximum rent filter"
 // @Param bedrooms query int false "Number of bedrooms filter"
 // @Param bathrooms query int false "Number of bathrooms filter"
-// @Param search query string false "Search term"
+// @Param search query string false "Full-text search in title, address and description, tolerant of misspelt place names"
+// @Param near query string false "Only houses within radius_km of this point (lat,lng)"
+// @Param radius_km query number false "Search radius for near in km" default(10)
+// @Param bbox query string false "Only houses inside this box (min_lat,min_lng,max_lat,max_lng)"
+// @Param location query string false "Only houses in this location or the places inside it: an ID, a path such as lusaka/lusaka/lusaka/kabulonga, or a name such as Copperbelt. The response then includes the location's rent_stats."
+// @Param amenities query string false "Comma separated amenity slugs, e.g. borehole,solar-backup"
+// @Param amenities_match query string false "all or any of the amenities" default(all)
+// @Param property_id query string false "Only units of this property"
+// @Param group_by query string false "property to group units by building"
+// @Param sort query string false "newest, relevance or distance (default relevance when searching, then distance when near is set)"
 // @Success 200 {object} map[string]interface{} "Houses retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid location filter"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /houses [get]
 func (hh *HouseHandler) GetHouses(c *gin.Context) {
@@ -208,20 +336,46 @@ func (hh *HouseHandler) GetHouses(c *gin.Context) {
 	bathrooms, _ := strconv.Atoi(c.Query("bathrooms"))
 	featured := c.Query("featured") == "true"
 	search := c.Query("search")
+	availableOn := c.Query("available_on")
+	near := c.Query("near")
+	bbox := c.Query("bbox")
+	sortBy := c.Query("sort")
+	amenitySlugs := splitQueryList(c.Query("amenities"))
+	amenitiesMatch := c.DefaultQuery("amenities_match", "all")
+	propertyID := c.Query("property_id")
+	groupBy := c.Query("group_by")
+	locationRef := c.Query("location")
 
 	// Calculate offset
 	offset := (page - 1) * limit
 
-	// Build query
-	query := config.DB.Model(&models.House{}).Preload("Landlord").Preload("Images")
+	// Build query; only published listings are shown to the public
+	query := config.DB.Model(&models.House{}).Where("listing_status = ?", models.ListingPublished)
 
 	// Apply filters
+	if propertyID != "" {
+		query = query.Where("property_id = ?", propertyID)
+	}
 	if houseType != "" {
 		query = query.Where("house_type = ?", houseType)
 	}
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	if availableOn != "" {
+		date, err := time.Parse("2006-01-02", availableOn)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid available_on date format", err)
+			return
+		}
+		// Houses that are free by the date and not already let from it
+		bookedOn := config.DB.Model(&models.RentalAgreement{}).
+			Select("house_id").
+			Where("status IN ? AND start_date <= ? AND LEAST(termination_date, end_date) > ?",
+				services.BookedAgreementStatuses, date, date)
+		query = query.Where("status <> ? AND (available_from IS NULL OR available_from <= ?) AND id NOT IN (?)",
+			models.StatusMaintenance, date, bookedOn)
+	}
 	if minRent > 0 {
 		query = query.Where("monthly_rent >= ?", minRent)
 	}
@@ -237,40 +391,260 @@ func (hh *HouseHandler) GetHouses(c *gin.Context) {
 	if featured {
 		query = query.Where("is_featured = ? AND (featured_until IS NULL OR featured_until > ?)", true, time.Now())
 	}
+	if len(amenitySlugs) > 0 {
+		if amenitiesMatch != "all" && amenitiesMatch != "any" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenities_match, expected all or any", nil)
+			return
+		}
+		query = services.FilterByAmenities(query, amenitySlugs, amenitiesMatch == "all")
+	}
+
+	// Extra columns computed by text and location searches
+	selects := []string{"houses.*"}
+	var selectArgs []interface{}
+
 	if search != "" {
-		query = query.Where("title ILIKE ? OR description ILIKE ? OR address ILIKE ?",
-			"%"+search+"%", "%"+search+"%", "%"+search+"%")
+		condition, args := services.SearchConditionSQL(search)
+		query = query.Where(condition, args...)
+
+		rankSQL, rankArgs := services.SearchRankSQL(search)
+		snippetSQL, snippetArgs := services.SearchSnippetSQL(search)
+		selects = append(selects, rankSQL+" AS search_rank", snippetSQL+" AS search_snippet")
+		selectArgs = append(append(selectArgs, rankArgs...), snippetArgs...)
+	}
+
+	// Location filters
+	hasDistance := false
+	if near != "" {
+		lat, lng, err := services.ParsePoint(near)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid near coordinates", err)
+			return
+		}
+		radiusKm, err := strconv.ParseFloat(c.DefaultQuery("radius_km", "10"), 64)
+		if err != nil || radiusKm <= 0 || radiusKm > maxSearchRadiusKm {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("radius_km must be between 0 and %.0f", maxSearchRadiusKm), err)
+			return
+		}
+
+		distanceSQL, distanceArgs := services.DistanceSQL(lat, lng)
+		query = services.WithinBoundingBox(query, services.RadiusBoundingBox(lat, lng, radiusKm))
+		query = query.Where(distanceSQL+" <= ?", append(append([]interface{}{}, distanceArgs...), radiusKm)...)
+
+		// Pins geocoded to the centre of an area or town sort after pins placed on the house itself
+		approximateSQL, approximateArgs := services.ApproximatePinSQL()
+		selects = append(selects, distanceSQL+" AS distance_km", approximateSQL+" AS approximate_pin")
+		selectArgs = append(append(selectArgs, distanceArgs...), approximateArgs...)
+		hasDistance = true
+	}
+	if bbox != "" {
+		box, err := services.ParseBoundingBox(bbox)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid bbox", err)
+			return
+		}
+		query = services.WithinBoundingBox(query, box)
+	}
+
+	// Search results also summarise the rents of the whole location, whatever the other filters
+	summary := gin.H{}
+	if locationRef != "" {
+		location, ok := resolveLocationFilter(c, locationRef)
+		if !ok {
+			return
+		}
+		query = services.WithinLocation(query, location)
+
+		stats, err := services.LocationRentStats(location)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to calculate rent statistics", err)
+			return
+		}
+		summary["location"] = location
+		summary["rent_stats"] = stats
+	}
+
+	// Sort by relevance when searching, then by distance when searching near a point, otherwise newest first
+	if sortBy == "" {
+		switch {
+		case search != "":
+			sortBy = "relevance"
+		case hasDistance:
+			sortBy = "distance"
+		default:
+			sortBy = "newest"
+		}
+	}
+	var order string
+	switch sortBy {
+	case "newest":
+		order = "created_at DESC"
+	case "relevance":
+		if search == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Sorting by relevance requires search", nil)
+			return
+		}
+		order = "search_rank DESC, created_at DESC"
+	case "distance":
+		if !hasDistance {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Sorting by distance requires near", nil)
+			return
+		}
+		order = "approximate_pin ASC, distance_km ASC, created_at DESC"
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sort, expected newest, relevance or distance", nil)
+		return
 	}
 
 	// Get total count
 	var total int64
 	query.Count(&total)
 
+	// Count the matching houses offering each amenity, for faceted navigation
+	facets, err := services.AmenityFacets(query.Session(&gorm.Session{}).Select("houses.id"))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to count amenities", err)
+		return
+	}
+	summary["amenity_facets"] = facets
+
+	if len(selects) > 1 {
+		query = query.Select(strings.Join(selects, ", "), selectArgs...)
+	}
+
+	switch groupBy {
+	case "":
+	case "property":
+		hh.respondHouseGroups(c, query, sortBy, order, page, limit, summary)
+		return
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group_by, expected property", nil)
+		return
+	}
+
 	// Get houses
 	var houses []models.House
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&houses).Error; err != nil {
+	if err := query.Preload("Landlord").Preload("Location").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).Preload("Property.Images", orderedImages).
+		Offset(offset).Limit(limit).Order(order).Find(&houses).Error; err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to fetch houses", err)
 		return
 	}
+	recordImpressions(c, houses)
 
 	// Calculate pagination info
 	totalPages := (total + int64(limit) - 1) / int64(limit)
 
-	utils.SuccessResponse(c, http.StatusOK, "Houses retrieved successfully", gin.H{
-		"houses": houses,
-		"pagination": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": totalPages,
-		},
-	})
+	summary["houses"] = houses
+	summary["pagination"] = gin.H{
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": totalPages,
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Houses retrieved successfully", summary)
+}
+
+// houseGroup is one property, or one standalone house, in a search grouped by property
+type houseGroup struct {
+	GroupID    uuid.UUID        `json:"-"`
+	PropertyID *uuid.UUID       `json:"-"`
+	Property   *models.Property `json:"property" gorm:"-"` // nil for a standalone house
+	UnitCount  int64            `json:"unit_count"`
+	MinRent    float64          `json:"min_rent"`
+	MaxRent    float64          `json:"max_rent"`
+	Units      []models.House   `json:"units" gorm:"-"`
+}
+
+// respondHouseGroups responds with the matching houses grouped by property, so each building appears
+// once with its matching units. Groups are paginated and sorted by their best matching unit. The
+// summary holds the amenity facets and any location rent statistics.
+func (hh *HouseHandler) respondHouseGroups(c *gin.Context, query *gorm.DB, sortBy, order string, page, limit int, summary gin.H) {
+	groupSelect := "COALESCE(matches.property_id, matches.id) AS group_id, matches.property_id, COUNT(*) AS unit_count, " +
+		"MIN(matches.monthly_rent) AS min_rent, MAX(matches.monthly_rent) AS max_rent, MAX(matches.created_at) AS latest"
+	groupOrder := "latest DESC"
+	switch sortBy {
+	case "relevance":
+		groupSelect += ", MAX(matches.search_rank) AS search_rank"
+		groupOrder = "search_rank DESC, latest DESC"
+	case "distance":
+		groupSelect += ", MIN(matches.distance_km) AS distance_km, BOOL_AND(matches.approximate_pin) AS approximate_pin"
+		groupOrder = "approximate_pin ASC, distance_km ASC, latest DESC"
+	}
+
+	groupsQuery := config.DB.Table("(?) AS matches", query.Session(&gorm.Session{})).
+		Select(groupSelect).
+		Group("COALESCE(matches.property_id, matches.id), matches.property_id")
+
+	// Get total count of groups
+	var total int64
+	config.DB.Table("(?) AS grouped", groupsQuery).Count(&total)
+
+	var groups []houseGroup
+	if err := groupsQuery.Order(groupOrder).Offset((page - 1) * limit).Limit(limit).Scan(&groups).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch houses", err)
+		return
+	}
+
+	groupIDs := make([]uuid.UUID, 0, len(groups))
+	var propertyIDs []uuid.UUID
+	for _, group := range groups {
+		groupIDs = append(groupIDs, group.GroupID)
+		if group.PropertyID != nil {
+			propertyIDs = append(propertyIDs, *group.PropertyID)
+		}
+	}
+
+	// Load the matching units of the page's groups, and their properties
+	var houses []models.House
+	if len(groupIDs) > 0 {
+		if err := query.Preload("Landlord").Preload("Location").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).
+			Where("COALESCE(houses.property_id, houses.id) IN ?", groupIDs).
+			Order(order).Find(&houses).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch houses", err)
+			return
+		}
+	}
+	recordImpressions(c, houses)
+
+	properties := map[uuid.UUID]*models.Property{}
+	if len(propertyIDs) > 0 {
+		var loaded []models.Property
+		config.DB.Preload("Images", orderedImages).Preload("Amenities", activeAmenities).Where("id IN ?", propertyIDs).Find(&loaded)
+		for i := range loaded {
+			properties[loaded[i].ID] = &loaded[i]
+		}
+	}
+
+	for i := range groups {
+		group := &groups[i]
+		group.Units = []models.House{}
+		if group.PropertyID != nil {
+			group.Property = properties[*group.PropertyID]
+		}
+		for _, house := range houses {
+			if (house.PropertyID != nil && *house.PropertyID == group.GroupID) || house.ID == group.GroupID {
+				group.Units = append(group.Units, house)
+			}
+		}
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	summary["groups"] = groups
+	summary["pagination"] = gin.H{
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": totalPages,
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Houses retrieved successfully", summary)
 }
 
 // GetHouse handles getting a single house by ID
 // GetHouse retrieves a specific house by ID
 // @Summary Get house by ID
-// @Description Get detailed information about a specific house
+// @Description Get detailed information about a specific house, with the rent statistics of its location. Listings that are not published are only shown to their landlord and admins.
 // @Tags Houses
 // @Accept json
 // @Produce json
@@ -291,25 +665,163 @@ func (hh *HouseHandler) GetHouse(c *gin.Context) {
 	}
 
 	var house models.House
-	if err := config.DB.Preload("Landlord").Preload("Images").Preload("Reviews.Tenant").First(&house, id).Error; err != nil {
+	if err := config.DB.Preload("Landlord").Preload("Location").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).
+		Preload("Property.Images", orderedImages).Preload("Property.Amenities", activeAmenities).
+		Preload("Reviews.Tenant").First(&house, id).Error; err != nil {
 		utils.NotFoundResponse(c, "House not found")
 		return
 	}
+	if !canViewListing(c, &house) {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	// Count views of published listings by anyone but their landlord
+	if user, exists := c.Get("user"); house.ListingStatus == models.ListingPublished &&
+		(!exists || user.(models.User).ID != house.LandlordID) {
+		recordListingEvents(c, models.EventView, []uuid.UUID{house.ID})
+	}
 
 	// Calculate average rating
 	var avgRating float64
 	config.DB.Model(&models.Review{}).Where("house_id = ?", house.ID).Select("AVG(rating)").Scan(&avgRating)
 	house.Reviews = []models.Review{} // Clear reviews to avoid circular reference
 
+	// Rents of the house's area, so tenants can judge the asking rent
+	var areaRentStats *services.RentStats
+	if house.Location != nil {
+		areaRentStats, _ = services.LocationRentStats(house.Location)
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, "House retrieved successfully", gin.H{
-		"house":          house,
-		"average_rating": avgRating,
+		"house":           house,
+		"average_rating":  avgRating,
+		"area_rent_stats": areaRentStats,
+	})
+}
+
+// GetMyHouses handles getting the current landlord's listings in every moderation state
+// @Summary Get my houses
+// @Description Get the current user's own listings, including drafts and listings waiting for review or rejected
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param listing_status query string false "draft, pending_review, published, rejected or archived"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{} "Houses retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/mine [get]
+func (hh *HouseHandler) GetMyHouses(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	listingStatus := c.Query("listing_status")
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.House{}).Where("landlord_id = ?", userModel.ID)
+	if listingStatus != "" {
+		query = query.Where("listing_status = ?", listingStatus)
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	var houses []models.House
+	if err := query.Preload("Images", orderedImages).Preload("Property").Preload("Location").
+		Offset(offset).Limit(limit).Order("updated_at DESC").Find(&houses).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch houses", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	utils.SuccessResponse(c, http.StatusOK, "Houses retrieved successfully", gin.H{
+		"houses": houses,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
 	})
 }
 
+// recordImpressions records that houses were shown in search results. Landlords browsing their own
+// listings do not count.
+func recordImpressions(c *gin.Context, houses []models.House) {
+	var viewerID uuid.UUID
+	if user, exists := c.Get("user"); exists {
+		viewerID = user.(models.User).ID
+	}
+
+	houseIDs := make([]uuid.UUID, 0, len(houses))
+	for _, house := range houses {
+		if house.LandlordID != viewerID {
+			houseIDs = append(houseIDs, house.ID)
+		}
+	}
+	recordListingEvents(c, models.EventImpression, houseIDs)
+}
+
+// canViewListing reports whether the current user may see a listing. Listings that are not published
+// are only shown to their landlord and admins; public routes set the user with OptionalAuthMiddleware.
+func canViewListing(c *gin.Context, house *models.House) bool {
+	if house.ListingStatus == models.ListingPublished {
+		return true
+	}
+	user, exists := c.Get("user")
+	if !exists {
+		return false
+	}
+	userModel := user.(models.User)
+	return house.LandlordID == userModel.ID || userModel.Role == models.RoleAdmin
+}
+
+// reviewedChanges lists the changes to a listing that send it back for review once published. These are
+// the details scam listings fake, so moderators check them again.
+func reviewedChanges(before, after *models.House) []string {
+	var changed []string
+	if after.Title != before.Title {
+		changed = append(changed, "title")
+	}
+	if after.Description != before.Description {
+		changed = append(changed, "description")
+	}
+	if after.Address != before.Address {
+		changed = append(changed, "address")
+	}
+	if after.Latitude != before.Latitude || after.Longitude != before.Longitude || !sameLocation(after.LocationID, before.LocationID) {
+		changed = append(changed, "location")
+	}
+	if after.MonthlyRent != before.MonthlyRent {
+		changed = append(changed, "rent")
+	}
+	return changed
+}
+
+// sameLocation reports whether two optional location IDs are the same
+func sameLocation(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 // UpdateHouse handles updating a house
 // @Summary Update house
-// @Description Update an existing house listing (owner or admin only)
+// @Description Update an existing house listing (owner or admin only). When a landlord changes the title, description, address, location or rent of a published listing, it goes back for review.
 // @Tags Houses
 // @Accept json
 // @Produce json
@@ -360,6 +872,57 @@ func (hh *HouseHandler) UpdateHouse(c *gin.Context) {
 		})
 		return
 	}
+	before := house
+
+	// Units take their address, location and coordinates from their property
+	if house.PropertyID != nil && req.PropertyID == nil && (req.Address != "" || req.LocationID != nil || req.Latitude != 0 || req.Longitude != 0) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The address, location and coordinates of a unit are set on its property", nil)
+		return
+	}
+	if req.LocationID != nil {
+		if *req.LocationID == "" {
+			house.LocationID = nil
+		} else {
+			locationID, err := uuid.Parse(*req.LocationID)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+				return
+			}
+			if !checkLocation(c, locationID) {
+				return
+			}
+			house.LocationID = &locationID
+		}
+	}
+	if req.PropertyID != nil {
+		if *req.PropertyID == "" {
+			house.PropertyID = nil
+		} else {
+			propertyID, err := uuid.Parse(*req.PropertyID)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid property ID", err)
+				return
+			}
+			property, ok := loadPropertyForUnit(c, propertyID, userModel)
+			if !ok {
+				return
+			}
+			if property.LandlordID != house.LandlordID {
+				utils.ErrorResponse(c, http.StatusBadRequest, "The house and property must have the same landlord", nil)
+				return
+			}
+			house.PropertyID = &property.ID
+			house.Address = property.Address
+			house.LocationID = property.LocationID
+			house.Latitude = property.Latitude
+			house.Longitude = property.Longitude
+			house.GeocodeSource, house.GeocodePrecision = property.GeocodeSource, property.GeocodePrecision
+			req.Address, req.Latitude, req.Longitude = "", 0, 0
+		}
+	}
+	if req.UnitLabel != nil {
+		house.UnitLabel = *req.UnitLabel
+	}
 
 	// Update house fields
 	if req.Title != "" {
@@ -403,18 +966,64 @@ func (hh *HouseHandler) UpdateHouse(c *gin.Context) {
 		house.FeaturedUntil = &featuredUntil
 	}
 
+	// A pin moved by the landlord is exact; a pin geocoded from the old address is geocoded again
+	if req.Latitude != 0 || req.Longitude != 0 {
+		house.GeocodeSource, house.GeocodePrecision = "", ""
+	} else if house.PropertyID == nil && req.Address != "" && req.Address != before.Address && house.GeocodeSource != "" {
+		house.Latitude, house.Longitude = 0, 0
+		house.GeocodeSource, house.GeocodePrecision = "", ""
+	}
+
+	// Geocode a changed address when the house has no coordinates
+	var geocoded *services.GeocodeResult
+	if house.PropertyID == nil && (req.Address != "" || req.LocationID != nil || req.Latitude != 0 || req.Longitude != 0) {
+		place := services.Place{Address: house.Address, Latitude: house.Latitude, Longitude: house.Longitude, LocationID: house.LocationID}
+		geocoded = services.CompletePlace(c.Request.Context(), hh.geocoder, &place)
+		house.Latitude, house.Longitude, house.LocationID = place.Latitude, place.Longitude, place.LocationID
+		if place.Source != "" {
+			house.GeocodeSource, house.GeocodePrecision = place.Source, place.Precision
+		}
+	}
+
 	house.UpdatedAt = time.Now()
 
-	if err := config.DB.Save(&house).Error; err != nil {
+	var amenities []models.Amenity
+	if req.AmenityIDs != nil {
+		amenities, err = services.LoadAmenities(req.AmenityIDs)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenities", err)
+			return
+		}
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&house).Error; err != nil {
+			return err
+		}
+		if changed := reviewedChanges(&before, &house); len(changed) > 0 && userModel.Role != models.RoleAdmin {
+			if _, err := services.ReviewListingChange(tx, house.ID, "Changed "+strings.Join(changed, ", ")); err != nil {
+				return err
+			}
+		}
+		if req.AmenityIDs == nil {
+			return nil
+		}
+		return tx.Model(&house).Association("Amenities").Replace(amenities)
+	})
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to update house", err)
 		return
 	}
+	if len(reviewedChanges(&before, &house)) > 0 {
+		assessListingRisk(house.ID)
+	}
 
 	// Load landlord information
-	config.DB.Preload("Landlord").Preload("Images").First(&house, house.ID)
+	config.DB.Preload("Landlord").Preload("Property").Preload("Location").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).First(&house, house.ID)
 
 	utils.SuccessResponse(c, http.StatusOK, "House updated successfully", gin.H{
-		"house": house,
+		"house":    house,
+		"geocoded": geocoded,
 	})
 }
 
@@ -463,15 +1072,105 @@ func (hh *HouseHandler) DeleteHouse(c *gin.Context) {
 		return
 	}
 
-	// Soft delete house
-	if err := config.DB.Delete(&house).Error; err != nil {
+	// Soft delete house and clean up its images
+	var deletions []models.ImageDeletion
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&house).Error; err != nil {
+			return err
+		}
+
+		var images []models.HouseImage
+		if err := tx.Where("house_id = ?", house.ID).Find(&images).Error; err != nil {
+			return err
+		}
+		deletions, err = services.DeleteHouseImages(tx, images)
+		return err
+	})
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to delete house", err)
 		return
 	}
+	hh.imageCleanup.DeleteQueued(c.Request.Context(), deletions)
 
 	utils.SuccessResponse(c, http.StatusOK, "House deleted successfully", nil)
 }
 
+// SubmitHouse handles submitting a listing for review
+// @Summary Submit house for review
+// @Description Submit a draft, rejected or archived listing to the moderation queue (owner or admin only). The listing is published once a moderator approves it.
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Success 200 {object} map[string]interface{} "Listing submitted for review"
+// @Failure 400 {object} map[string]interface{} "Listing is already published or waiting for review"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only submit your own houses"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/submit [put]
+func (hh *HouseHandler) SubmitHouse(c *gin.Context) {
+	house, ok := hh.loadManagedHouse(c, "You can only submit your own houses")
+	if !ok {
+		return
+	}
+	userModel := c.MustGet("user").(models.User)
+
+	switch house.ListingStatus {
+	case models.ListingPublished:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Listing is already published", nil)
+		return
+	case models.ListingPendingReview:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Listing is already waiting for review", nil)
+		return
+	}
+
+	if err := services.SetListingStatus(config.DB, house, models.ListingPendingReview, &userModel.ID, "", ""); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to submit listing", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Listing submitted for review", gin.H{
+		"house": house,
+	})
+}
+
+// ArchiveHouse handles withdrawing a listing
+// @Summary Archive house
+// @Description Withdraw a listing from the public and from the moderation queue (owner or admin only). Submit it again to relist it.
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Success 200 {object} map[string]interface{} "Listing archived"
+// @Failure 400 {object} map[string]interface{} "Listing is already archived"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only archive your own houses"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/archive [put]
+func (hh *HouseHandler) ArchiveHouse(c *gin.Context) {
+	house, ok := hh.loadManagedHouse(c, "You can only archive your own houses")
+	if !ok {
+		return
+	}
+	userModel := c.MustGet("user").(models.User)
+
+	if house.ListingStatus == models.ListingArchived {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Listing is already archived", nil)
+		return
+	}
+
+	if err := services.SetListingStatus(config.DB, house, models.ListingArchived, &userModel.ID, "", ""); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to archive listing", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Listing archived", gin.H{
+		"house": house,
+	})
+}
+
 // UploadHouseImage handles uploading images for a house
 // @Summary Upload house image
 // @Description Upload an image for a house listing (owner or admin only)
@@ -518,44 +1217,369 @@ func (hh *HouseHandler) UploadHouseImage(c *gin.Context) {
 		return
 	}
 
-	// Get uploaded file
-	file, _, err := c.Request.FormFile("image")
+	// Check the house has room for another image
+	var imageCount int64
+	config.DB.Model(&models.HouseImage{}).Where("house_id = ?", house.ID).Count(&imageCount)
+	if imageCount >= int64(config.AppConfig.MaxHouseImages) {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+		return
+	}
+
+	// Stream the upload through validation to image storage
+	stored, ok := receiveImage(c, hh.imageStorage, "image", "bondihub/houses")
+	if !ok {
+		return
+	}
+
+	houseImage, err := hh.saveHouseImage(c.Request.Context(), house.ID, stored)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "No image file provided", err)
+		if errors.Is(err, errHouseImageLimit) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to save image record", err)
+		return
+	}
+	reviewNewImages(c, house.ID)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Image uploaded successfully", gin.H{
+		"image": houseImage,
+	})
+}
+
+// BatchImageResult reports the outcome of one file of a batch image upload
+type BatchImageResult struct {
+	FileName string             `json:"file_name"`
+	Success  bool               `json:"success"`
+	Image    *models.HouseImage `json:"image,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// BatchUploadHouseImages handles uploading many images for a house in one request
+// @Summary Batch upload house images
+// @Description Upload several images for a house listing at once (owner or admin only). Files are processed concurrently and each gets its own result; files that fail do not affect the others.
+// @Tags Houses
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param images formData file true "House image files (repeat the field for each file)"
+// @Success 200 {object} map[string]interface{} "Batch upload processed"
+// @Failure 400 {object} map[string]interface{} "Invalid house ID or no image files provided"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only upload images for your own houses"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/images/batch [post]
+func (hh *HouseHandler) BatchUploadHouseImages(c *gin.Context) {
+	house, ok := hh.loadImageHouse(c)
+	if !ok {
+		return
+	}
+
+	// Files beyond the house's remaining image allowance are rejected without being processed
+	var imageCount int64
+	config.DB.Model(&models.HouseImage{}).Where("house_id = ?", house.ID).Count(&imageCount)
+	remaining := config.AppConfig.MaxHouseImages - int(imageCount)
+	if remaining <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+		return
+	}
+
+	received, ok := receiveImages(c, hh.imageStorage, "images", "bondihub/houses", remaining)
+	if !ok {
+		return
+	}
+
+	// Records are created in the order the files were sent, so the gallery keeps that order
+	results := make([]BatchImageResult, len(received.Results))
+	uploaded := 0
+	for i, upload := range received.Results {
+		results[i].FileName = upload.FileName
+		if upload.Err != nil {
+			results[i].Error = imageUploadErrorMessage(upload.Err)
+			continue
+		}
+
+		houseImage, err := hh.saveHouseImage(c.Request.Context(), house.ID, upload.Stored)
+		if err != nil {
+			if errors.Is(err, errHouseImageLimit) {
+				results[i].Error = fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages)
+			} else {
+				results[i].Error = "Failed to save image record"
+			}
+			continue
+		}
+		results[i].Success = true
+		results[i].Image = houseImage
+		uploaded++
+	}
+	if uploaded > 0 {
+		reviewNewImages(c, house.ID)
+	}
+
+	response := gin.H{
+		"uploaded": uploaded,
+		"failed":   len(results) - uploaded,
+		"results":  results,
+	}
+	if message := received.IncompleteMessage(); message != "" {
+		response["error"] = message
+	}
+	utils.SuccessResponse(c, http.StatusOK, fmt.Sprintf("%d of %d images uploaded", uploaded, len(results)), response)
+}
+
+// CreateHouseImageUpload handles issuing signed parameters for uploading a house image straight to storage
+// @Summary Create direct house image upload
+// @Description Get short-lived signed parameters for uploading one image straight to image storage, bypassing the API server (owner or admin only). Confirm the upload afterwards to add the image to the house.
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Success 201 {object} map[string]interface{} "Upload parameters created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid house ID or image limit reached"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only upload images for your own houses"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 501 {object} map[string]interface{} "Image storage does not support direct uploads"
+// @Router /houses/{id}/images/direct-upload [post]
+func (hh *HouseHandler) CreateHouseImageUpload(c *gin.Context) {
+	house, ok := hh.loadImageHouse(c)
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	// Check if Cloudinary service is available
-	if hh.cloudinaryService == nil {
-		utils.InternalServerErrorResponse(c, "Image upload service is not configured", nil)
+	directStorage, supported := hh.imageStorage.(services.DirectUploadStorage)
+	if !supported {
+		utils.ErrorResponse(c, http.StatusNotImplemented, "Image storage does not support direct uploads", nil)
 		return
 	}
 
-	// Upload to Cloudinary
-	result, err := hh.cloudinaryService.UploadImage(c.Request.Context(), file, "bondihub/houses")
+	var imageCount int64
+	config.DB.Model(&models.HouseImage{}).Where("house_id = ?", house.ID).Count(&imageCount)
+	if imageCount >= int64(config.AppConfig.MaxHouseImages) {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+		return
+	}
+
+	expiresAt := time.Now().Add(services.DirectUploadTTL)
+	upload, err := directStorage.SignUpload("bondihub/houses", expiresAt)
 	if err != nil {
-		utils.InternalServerErrorResponse(c, "Failed to upload image", err)
+		utils.InternalServerErrorResponse(c, "Failed to create upload parameters", err)
+		return
+	}
+	if err := services.QueueUnconfirmedUpload(upload.PublicID, expiresAt); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create upload parameters", err)
 		return
 	}
 
-	// Create house image record
-	houseImage := models.HouseImage{
-		HouseID:   house.ID,
-		ImageURL:  result.SecureURL,
-		IsPrimary: false,
+	utils.SuccessResponse(c, http.StatusCreated, "Upload parameters created successfully", gin.H{
+		"upload":       upload,
+		"upload_token": services.SignUploadToken("house:"+house.ID.String(), upload.PublicID, expiresAt),
+	})
+}
+
+// ConfirmHouseImageUploadRequest represents the request structure for confirming a direct upload
+type ConfirmHouseImageUploadRequest struct {
+	PublicID    string `json:"public_id" binding:"required"`
+	UploadToken string `json:"upload_token" binding:"required"`
+}
+
+// ConfirmHouseImageUpload handles adding a directly uploaded image to a house
+// @Summary Confirm direct house image upload
+// @Description Verify an image uploaded with direct upload parameters and add it to the house (owner or admin only)
+// @Tags Houses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body ConfirmHouseImageUploadRequest true "Public ID and upload token from the direct upload"
+// @Success 201 {object} map[string]interface{} "Image uploaded successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data, invalid token or image rejected"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only upload images for your own houses"
+// @Failure 404 {object} map[string]interface{} "House or uploaded image not found"
+// @Failure 501 {object} map[string]interface{} "Image storage does not support direct uploads"
+// @Router /houses/{id}/images/direct-upload/confirm [post]
+func (hh *HouseHandler) ConfirmHouseImageUpload(c *gin.Context) {
+	house, ok := hh.loadImageHouse(c)
+	if !ok {
+		return
 	}
 
-	if err := config.DB.Create(&houseImage).Error; err != nil {
+	var req ConfirmHouseImageUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	directStorage, supported := hh.imageStorage.(services.DirectUploadStorage)
+	if !supported {
+		utils.ErrorResponse(c, http.StatusNotImplemented, "Image storage does not support direct uploads", nil)
+		return
+	}
+
+	// The token proves the upload was issued for this house and has not expired
+	if err := services.VerifyUploadToken(req.UploadToken, "house:"+house.ID.String(), req.PublicID); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload token", err)
+		return
+	}
+
+	// Claim the upload before processing it, so a confirmation sent twice cannot add the image twice
+	user := c.MustGet("user").(models.User)
+	claim := models.UploadClaim{PublicID: req.PublicID, ClaimedByID: user.ID}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+	if result.Error != nil {
+		utils.InternalServerErrorResponse(c, "Failed to confirm upload", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Upload has already been confirmed", nil)
+		return
+	}
+
+	stored, err := directStorage.ConfirmUpload(c.Request.Context(), req.PublicID, "bondihub/houses")
+	if err != nil {
+		// Release the claim so the upload can be confirmed again once it is there
+		config.DB.Delete(&claim)
+		if errors.Is(err, services.ErrUploadNotFound) {
+			utils.NotFoundResponse(c, "Uploaded image not found")
+			return
+		}
+		respondImageUploadError(c, err)
+		return
+	}
+	if err := services.CancelUnconfirmedUpload(req.PublicID); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to confirm upload", err)
+		return
+	}
+
+	houseImage, err := hh.saveHouseImage(c.Request.Context(), house.ID, stored)
+	if err != nil {
+		if errors.Is(err, errHouseImageLimit) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+			return
+		}
 		utils.InternalServerErrorResponse(c, "Failed to save image record", err)
 		return
 	}
+	reviewNewImages(c, house.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, "Image uploaded successfully", gin.H{
 		"image": houseImage,
 	})
 }
 
+// loadImageHouse loads the house in the URL and checks the current user may manage its images.
+// It writes the error response and returns false when the request cannot continue.
+func (hh *HouseHandler) loadImageHouse(c *gin.Context) (*models.House, bool) {
+	return hh.loadManagedHouse(c, "You can only upload images for your own houses")
+}
+
+// loadManagedHouse loads the house in the URL and checks the current user owns it or is an admin,
+// responding with the forbidden message otherwise. It writes the error response and returns false
+// when the request cannot continue.
+func (hh *HouseHandler) loadManagedHouse(c *gin.Context, forbidden string) (*models.House, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, false
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return nil, false
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return nil, false
+	}
+
+	if house.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, forbidden)
+		return nil, false
+	}
+	return &house, true
+}
+
+// reviewNewImages sends a published listing back for review after its landlord adds images, and
+// reassesses its risk score
+func reviewNewImages(c *gin.Context, houseID uuid.UUID) {
+	requestImageReview(c, "Changed images", houseID)
+	assessListingRisk(houseID)
+}
+
+// requestImageReview flags a landlord's change to the images listings show, such as a new cover photo,
+// for moderators. Changes made by admins do not need review.
+func requestImageReview(c *gin.Context, reason string, houseIDs ...uuid.UUID) {
+	if user, exists := c.Get("user"); exists && user.(models.User).Role == models.RoleAdmin {
+		return
+	}
+	for _, houseID := range houseIDs {
+		if _, err := services.ReviewListingChange(config.DB, houseID, reason); err != nil {
+			log.Printf("Failed to send house %s back for review: %v", houseID, err)
+		}
+	}
+}
+
+// assessListingRisk updates the duplicate and scam risk score moderators see for a listing
+func assessListingRisk(houseID uuid.UUID) {
+	if _, err := services.AssessListingRisk(houseID); err != nil {
+		log.Printf("Failed to assess risk of house %s: %v", houseID, err)
+	}
+}
+
+// saveHouseImage creates the record of a stored house image. The first image of a house becomes its
+// primary image and later images go to the end of the gallery. If the record cannot be created, the
+// stored image is queued for deletion so it is not left behind. New images are screened for photos
+// reused from other landlords' listings.
+func (hh *HouseHandler) saveHouseImage(ctx context.Context, houseID uuid.UUID, stored *services.StoredImage) (*models.HouseImage, error) {
+	houseImage := models.HouseImage{
+		HouseID:      houseID,
+		ImageURL:     stored.URL,
+		PublicID:     stored.PublicID,
+		MediumURL:    hh.imageStorage.URL(stored.PublicID, services.ImageSizeMedium),
+		ThumbnailURL: hh.imageStorage.URL(stored.PublicID, services.ImageSizeThumbnail),
+		ImageHash:    stored.Hash,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the house so concurrent uploads cannot exceed the image cap
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.House{}, houseID).Error; err != nil {
+			return err
+		}
+		var count int64
+		tx.Model(&models.HouseImage{}).Where("house_id = ?", houseID).Count(&count)
+		if count >= int64(config.AppConfig.MaxHouseImages) {
+			return errHouseImageLimit
+		}
+
+		houseImage.SortOrder = services.NextHouseImageSortOrder(tx, houseID)
+		houseImage.IsPrimary = houseImage.SortOrder == 0
+		return tx.Create(&houseImage).Error
+	})
+	if err != nil {
+		if deletions, queueErr := services.QueueImageDeletions(config.DB, stored.PublicID); queueErr == nil {
+			hh.imageCleanup.DeleteQueued(ctx, deletions)
+		}
+		return nil, err
+	}
+
+	// Photos reused from another landlord's listing send the listing to moderation
+	if _, err := services.ScreenHouseImage(&houseImage); err != nil {
+		log.Printf("Failed to screen image %s of house %s: %v", houseImage.ID, houseID, err)
+	}
+	return &houseImage, nil
+}
+
 // DeleteHouseImage handles deleting a house image
 // @Summary Delete house image
 // @Description Delete an image from a house listing (owner or admin only)
@@ -601,13 +1625,175 @@ func (hh *HouseHandler) DeleteHouseImage(c *gin.Context) {
 		return
 	}
 
-	// Delete from Cloudinary
-	// Note: You might want to extract public_id from the URL
-	// For now, we'll just delete from database
-	if err := config.DB.Delete(&image).Error; err != nil {
+	// Delete the record and queue the stored image for deletion in one transaction, so the
+	// stored image is retried later if image storage is unreachable now
+	var deletions []models.ImageDeletion
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deletions, err = services.DeleteHouseImages(tx, []models.HouseImage{image})
+		return err
+	})
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to delete image", err)
 		return
 	}
+	hh.imageCleanup.DeleteQueued(c.Request.Context(), deletions)
 
 	utils.SuccessResponse(c, http.StatusOK, "Image deleted successfully", nil)
 }
+
+// SetPrimaryHouseImage handles choosing the primary image of a house
+// @Summary Set primary house image
+// @Description Make an image the primary image of its house listing (owner or admin only)
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param imageId path string true "Image ID"
+// @Success 200 {object} map[string]interface{} "Primary image updated successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid image ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only manage images for your own houses"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/images/{imageId}/primary [put]
+func (hh *HouseHandler) SetPrimaryHouseImage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid image ID", err)
+		return
+	}
+
+	var image models.HouseImage
+	if err := config.DB.Preload("House").First(&image, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Image not found")
+		return
+	}
+
+	if image.House.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only manage images for your own houses")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.SetPrimaryHouseImage(tx, &image)
+	}); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update primary image", err)
+		return
+	}
+	requestImageReview(c, "Changed primary image", image.HouseID)
+
+	var images []models.HouseImage
+	orderedImages(config.DB).Where("house_id = ?", image.HouseID).Find(&images)
+
+	utils.SuccessResponse(c, http.StatusOK, "Primary image updated successfully", gin.H{
+		"images": images,
+	})
+}
+
+// ReorderHouseImagesRequest represents the request structure for reordering house images
+type ReorderHouseImagesRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids" binding:"required,min=1"` // every image of the house, in gallery order
+}
+
+// ReorderHouseImages handles setting the gallery order of a house's images
+// @Summary Reorder house images
+// @Description Set the gallery order of a house's images by listing all their IDs in order (owner or admin only)
+// @Tags Houses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body ReorderHouseImagesRequest true "Image IDs in gallery order"
+// @Success 200 {object} map[string]interface{} "Images reordered successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only manage images for your own houses"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/images/order [put]
+func (hh *HouseHandler) ReorderHouseImages(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var req ReorderHouseImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	if house.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only manage images for your own houses")
+		return
+	}
+
+	// The new order must list every image of the house exactly once
+	var imageIDs []uuid.UUID
+	config.DB.Model(&models.HouseImage{}).Where("house_id = ?", house.ID).Pluck("id", &imageIDs)
+	existing := make(map[uuid.UUID]bool, len(imageIDs))
+	for _, imageID := range imageIDs {
+		existing[imageID] = true
+	}
+	seen := make(map[uuid.UUID]bool, len(req.ImageIDs))
+	for _, imageID := range req.ImageIDs {
+		if !existing[imageID] || seen[imageID] {
+			utils.ErrorResponse(c, http.StatusBadRequest, "image_ids must list each of the house's images once", nil)
+			return
+		}
+		seen[imageID] = true
+	}
+	if len(seen) != len(existing) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "image_ids must list each of the house's images once", nil)
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for position, imageID := range req.ImageIDs {
+			if err := tx.Model(&models.HouseImage{}).Where("id = ?", imageID).
+				Update("sort_order", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to reorder images", err)
+		return
+	}
+	requestImageReview(c, "Reordered images", house.ID)
+
+	var images []models.HouseImage
+	orderedImages(config.DB).Where("house_id = ?", house.ID).Find(&images)
+
+	utils.SuccessResponse(c, http.StatusOK, "Images reordered successfully", gin.H{
+		"images": images,
+	})
+}
@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InspectionHandler handles move-in and move-out inspection report requests
+type InspectionHandler struct {
+	imageStorage services.ImageStorage
+}
+
+// NewInspectionHandler creates a new inspection handler
+func NewInspectionHandler() *InspectionHandler {
+	imageStorage, err := services.NewImageStorage()
+	if err != nil {
+		log.Printf("Failed to initialize image storage for inspections: %v", err)
+	}
+	return &InspectionHandler{
+		imageStorage: imageStorage,
+	}
+}
+
+// InspectionItemRequest represents a checklist item in an inspection report
+type InspectionItemRequest struct {
+	Room      string `json:"room" binding:"required,max=100"`
+	Item      string `json:"item" binding:"required,max=100"`
+	Condition string `json:"condition" binding:"required,oneof=excellent good fair poor damaged"`
+	Notes     string `json:"notes"`
+}
+
+// CreateInspectionRequest represents the request structure for creating an inspection report
+type CreateInspectionRequest struct {
+	Type        string                  `json:"type" binding:"required,oneof=move_in move_out"`
+	InspectedAt string                  `json:"inspected_at"`
+	Notes       string                  `json:"notes"`
+	Items       []InspectionItemRequest `json:"items" binding:"omitempty,dive"`
+}
+
+// agreementParty identifies which side of the agreement a user is on
+type agreementParty string
+
+const (
+	partyNone     agreementParty = ""
+	partyLandlord agreementParty = "landlord"
+	partyTenant   agreementParty = "tenant"
+	partyAdmin    agreementParty = "admin"
+)
+
+// CreateInspectionReport handles creating an inspection report for an agreement
+// @Summary Create inspection report
+// @Description Create a move-in or move-out inspection report with room-by-room checklist items
+// @Tags Inspections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param request body CreateInspectionRequest true "Inspection report details"
+// @Success 201 {object} map[string]interface{} "Inspection report created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Failure 409 {object} map[string]interface{} "Report already exists"
+// @Router /rentals/{id}/inspections [post]
+func (ih *InspectionHandler) CreateInspectionReport(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	agreementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, agreementID).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return
+	}
+
+	if partyFor(&agreement, &userModel) == partyNone {
+		utils.ForbiddenResponse(c, "You don't have access to this agreement")
+		return
+	}
+
+	var req CreateInspectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	inspectedAt := time.Now()
+	if req.InspectedAt != "" {
+		inspectedAt, err = time.Parse("2006-01-02", req.InspectedAt)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid inspection date format", err)
+			return
+		}
+	}
+
+	// Only one report of each type is kept per agreement so they can be compared
+	var existing int64
+	config.DB.Model(&models.InspectionReport{}).
+		Where("agreement_id = ? AND type = ?", agreement.ID, req.Type).
+		Count(&existing)
+	if existing > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "An inspection report of this type already exists for this agreement", nil)
+		return
+	}
+
+	report := models.InspectionReport{
+		AgreementID: agreement.ID,
+		Type:        models.InspectionType(req.Type),
+		InspectedAt: inspectedAt,
+		Notes:       req.Notes,
+		CreatedByID: userModel.ID,
+	}
+	for _, item := range req.Items {
+		report.Items = append(report.Items, models.InspectionItem{
+			Room:      item.Room,
+			Item:      item.Item,
+			Condition: models.ConditionRating(item.Condition),
+			Notes:     item.Notes,
+		})
+	}
+
+	if err := config.DB.Create(&report).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create inspection report", err)
+		return
+	}
+
+	// Let the other parties know a report is ready for review
+	recipients := append(services.AgreementTenantIDs(&agreement), agreement.House.LandlordID)
+	others := make([]uuid.UUID, 0, len(recipients))
+	for _, id := range recipients {
+		if id != userModel.ID {
+			others = append(others, id)
+		}
+	}
+	services.NotifyAll(others, "Inspection Report Created",
+		fmt.Sprintf("A %s inspection report for %s is ready for review and sign-off",
+			strings.ReplaceAll(req.Type, "_", "-"), agreement.House.Title),
+		"agreement")
+
+	utils.SuccessResponse(c, http.StatusCreated, "Inspection report created successfully", gin.H{
+		"report": report,
+	})
+}
+
+// GetInspectionReports handles getting the inspection reports for an agreement
+func (ih *InspectionHandler) GetInspectionReports(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	agreementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, agreementID).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return
+	}
+
+	if partyFor(&agreement, &userModel) == partyNone {
+		utils.ForbiddenResponse(c, "You don't have access to this agreement")
+		return
+	}
+
+	var reports []models.InspectionReport
+	if err := config.DB.Preload("Items.Photos").
+		Where("agreement_id = ?", agreement.ID).
+		Order("inspected_at ASC").
+		Find(&reports).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch inspection reports", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Inspection reports retrieved successfully", gin.H{
+		"reports": reports,
+	})
+}
+
+// GetInspectionReport handles getting a single inspection report
+func (ih *InspectionHandler) GetInspectionReport(c *gin.Context) {
+	report, _, ok := ih.loadReport(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Inspection report retrieved successfully", gin.H{
+		"report": report,
+	})
+}
+
+// AddInspectionItem handles adding a checklist item to an unsigned report
+func (ih *InspectionHandler) AddInspectionItem(c *gin.Context) {
+	report, _, ok := ih.loadReport(c)
+	if !ok {
+		return
+	}
+
+	if report.IsLocked() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Signed inspection reports cannot be changed", nil)
+		return
+	}
+
+	var req InspectionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	item := models.InspectionItem{
+		ReportID:  report.ID,
+		Room:      req.Room,
+		Item:      req.Item,
+		Condition: models.ConditionRating(req.Condition),
+		Notes:     req.Notes,
+	}
+
+	if err := config.DB.Create(&item).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to add inspection item", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Inspection item added successfully", gin.H{
+		"item": item,
+	})
+}
+
+// UpdateInspectionItem handles updating a checklist item on an unsigned report
+func (ih *InspectionHandler) UpdateInspectionItem(c *gin.Context) {
+	report, _, ok := ih.loadReport(c)
+	if !ok {
+		return
+	}
+
+	if report.IsLocked() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Signed inspection reports cannot be changed", nil)
+		return
+	}
+
+	item, ok := ih.findItem(c, report)
+	if !ok {
+		return
+	}
+
+	var req InspectionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	item.Room = req.Room
+	item.Item = req.Item
+	item.Condition = models.ConditionRating(req.Condition)
+	item.Notes = req.Notes
+
+	if err := config.DB.Omit("Photos").Save(item).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update inspection item", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Inspection item updated successfully", gin.H{
+		"item": item,
+	})
+}
+
+// UploadInspectionPhoto handles uploading a photo for a checklist item
+// @Summary Upload inspection photo
+// @Description Upload a photo for an inspection checklist item
+// @Tags Inspections
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Report ID"
+// @Param itemId path string true "Item ID"
+// @Param image formData file true "Photo file"
+// @Success 201 {object} map[string]interface{} "Photo uploaded successfully"
+// @Failure 400 {object} map[string]interface{} "No image file provided or report is signed"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Report or item not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /inspections/{id}/items/{itemId}/photos [post]
+func (ih *InspectionHandler) UploadInspectionPhoto(c *gin.Context) {
+	report, _, ok := ih.loadReport(c)
+	if !ok {
+		return
+	}
+
+	if report.IsLocked() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Signed inspection reports cannot be changed", nil)
+		return
+	}
+
+	item, ok := ih.findItem(c, report)
+	if !ok {
+		return
+	}
+
+	// Stream the upload through validation to image storage
+	stored, ok := receiveImage(c, ih.imageStorage, "image", "bondihub/inspections")
+	if !ok {
+		return
+	}
+
+	photo := models.InspectionPhoto{
+		ItemID:   item.ID,
+		ImageURL: stored.URL,
+		PublicID: stored.PublicID,
+	}
+
+	if err := config.DB.Create(&photo).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save photo record", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Photo uploaded successfully", gin.H{
+		"photo": photo,
+	})
+}
+
+// SignInspectionReport handles a landlord or tenant signing off on a report
+// @Summary Sign inspection report
+// @Description Sign off on an inspection report as the landlord or a tenant on the agreement
+// @Tags Inspections
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Report ID"
+// @Success 200 {object} map[string]interface{} "Inspection report signed successfully"
+// @Failure 400 {object} map[string]interface{} "Already signed"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Report not found"
+// @Router /inspections/{id}/sign [put]
+func (ih *InspectionHandler) SignInspectionReport(c *gin.Context) {
+	report, party, ok := ih.loadReport(c)
+	if !ok {
+		return
+	}
+
+	userModel := c.MustGet("user").(models.User)
+	now := time.Now()
+
+	switch party {
+	case partyLandlord:
+		if report.LandlordSignedAt != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "The landlord has already signed this report", nil)
+			return
+		}
+		report.LandlordSignedAt = &now
+		report.LandlordSignedByID = &userModel.ID
+	case partyTenant:
+		if report.TenantSignedAt != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "A tenant has already signed this report", nil)
+			return
+		}
+		report.TenantSignedAt = &now
+		report.TenantSignedByID = &userModel.ID
+	default:
+		utils.ForbiddenResponse(c, "Only the landlord or a tenant on the agreement can sign this report")
+		return
+	}
+
+	if err := config.DB.Omit("Agreement", "Items").Save(report).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to sign inspection report", err)
+		return
+	}
+
+	if report.IsComplete() {
+		recipients := append(services.AgreementTenantIDs(&report.Agreement), report.Agreement.House.LandlordID)
+		services.NotifyAll(recipients, "Inspection Report Signed",
+			fmt.Sprintf("The %s inspection report for %s has been signed by both parties",
+				strings.ReplaceAll(string(report.Type), "_", "-"), report.Agreement.House.Title),
+			"agreement")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Inspection report signed successfully", gin.H{
+		"report": report,
+	})
+}
+
+// CompareInspectionReports handles comparing the move-in and move-out reports of an agreement side by side
+// @Summary Compare inspection reports
+// @Description Compare the move-in and move-out inspection reports of an agreement item by item
+// @Tags Inspections
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Success 200 {object} map[string]interface{} "Inspection comparison generated successfully"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement or reports not found"
+// @Router /rentals/{id}/inspections/compare [get]
+func (ih *InspectionHandler) CompareInspectionReports(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	agreementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, agreementID).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return
+	}
+
+	if partyFor(&agreement, &userModel) == partyNone {
+		utils.ForbiddenResponse(c, "You don't have access to this agreement")
+		return
+	}
+
+	var moveIn, moveOut models.InspectionReport
+	if err := config.DB.Preload("Items.Photos").
+		Where("agreement_id = ? AND type = ?", agreement.ID, models.InspectionTypeMoveIn).
+		First(&moveIn).Error; err != nil {
+		utils.NotFoundResponse(c, "Move-in inspection report not found")
+		return
+	}
+	if err := config.DB.Preload("Items.Photos").
+		Where("agreement_id = ? AND type = ?", agreement.ID, models.InspectionTypeMoveOut).
+		First(&moveOut).Error; err != nil {
+		utils.NotFoundResponse(c, "Move-out inspection report not found")
+		return
+	}
+
+	// Match items by room and item name so both reports line up row by row
+	type comparisonRow struct {
+		Room         string                 `json:"room"`
+		Item         string                 `json:"item"`
+		MoveIn       *models.InspectionItem `json:"move_in"`
+		MoveOut      *models.InspectionItem `json:"move_out"`
+		Changed      bool                   `json:"changed"`
+		Deteriorated bool                   `json:"deteriorated"`
+	}
+
+	itemKey := func(item *models.InspectionItem) string {
+		return strings.ToLower(strings.TrimSpace(item.Room)) + "|" + strings.ToLower(strings.TrimSpace(item.Item))
+	}
+
+	rows := []*comparisonRow{}
+	byKey := map[string]*comparisonRow{}
+	for i := range moveIn.Items {
+		item := &moveIn.Items[i]
+		row := &comparisonRow{Room: item.Room, Item: item.Item, MoveIn: item}
+		byKey[itemKey(item)] = row
+		rows = append(rows, row)
+	}
+	for i := range moveOut.Items {
+		item := &moveOut.Items[i]
+		row, found := byKey[itemKey(item)]
+		if !found {
+			row = &comparisonRow{Room: item.Room, Item: item.Item}
+			byKey[itemKey(item)] = row
+			rows = append(rows, row)
+		}
+		row.MoveOut = item
+	}
+
+	deteriorated := 0
+	for _, row := range rows {
+		if row.MoveIn == nil || row.MoveOut == nil {
+			row.Changed = true
+			continue
+		}
+		row.Changed = row.MoveIn.Condition != row.MoveOut.Condition
+		row.Deteriorated = row.MoveOut.Condition.Score() < row.MoveIn.Condition.Score()
+		if row.Deteriorated {
+			deteriorated++
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Inspection comparison generated successfully", gin.H{
+		"move_in_report":  gin.H{"id": moveIn.ID, "inspected_at": moveIn.InspectedAt, "complete": moveIn.IsComplete()},
+		"move_out_report": gin.H{"id": moveOut.ID, "inspected_at": moveOut.InspectedAt, "complete": moveOut.IsComplete()},
+		"items":           rows,
+		"summary": gin.H{
+			"total_items":        len(rows),
+			"deteriorated_items": deteriorated,
+		},
+	})
+}
+
+// loadReport loads the report from the route, checks the user is a party to its agreement
+// and returns which party they are
+func (ih *InspectionHandler) loadReport(c *gin.Context) (*models.InspectionReport, agreementParty, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, partyNone, false
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report ID", err)
+		return nil, partyNone, false
+	}
+
+	var report models.InspectionReport
+	if err := config.DB.Preload("Agreement.House").Preload("Items.Photos").First(&report, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Inspection report not found")
+		return nil, partyNone, false
+	}
+
+	party := partyFor(&report.Agreement, &userModel)
+	if party == partyNone {
+		utils.ForbiddenResponse(c, "You don't have access to this inspection report")
+		return nil, partyNone, false
+	}
+
+	return &report, party, true
+}
+
+// findItem finds the item named in the route on an already loaded report
+func (ih *InspectionHandler) findItem(c *gin.Context, report *models.InspectionReport) (*models.InspectionItem, bool) {
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item ID", err)
+		return nil, false
+	}
+
+	for i := range report.Items {
+		if report.Items[i].ID == itemID {
+			return &report.Items[i], true
+		}
+	}
+
+	utils.NotFoundResponse(c, "Inspection item not found")
+	return nil, false
+}
+
+// partyFor works out which side of the agreement the user is on
+func partyFor(agreement *models.RentalAgreement, user *models.User) agreementParty {
+	switch {
+	case agreement.House.LandlordID == user.ID:
+		return partyLandlord
+	case user.Role == models.RoleTenant && services.IsAgreementTenant(agreement, user.ID):
+		return partyTenant
+	case user.Role == models.RoleAdmin:
+		return partyAdmin
+	default:
+		return partyNone
+	}
+}
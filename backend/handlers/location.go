@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxLocationMatches caps the number of locations a name search returns
+const maxLocationMatches = 20
+
+// LocationHandler handles the province, district, town and area hierarchy listings are filed under
+type LocationHandler struct{}
+
+// NewLocationHandler creates a new location handler
+func NewLocationHandler() *LocationHandler {
+	return &LocationHandler{}
+}
+
+// LocationRequest represents the request structure for adding a location
+type LocationRequest struct {
+	Name      string     `json:"name" binding:"required,min=2,max=100"`
+	ParentID  *uuid.UUID `json:"parent_id"` // omitted for a province
+	Latitude  float64    `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64    `json:"longitude" binding:"min=-180,max=180"`
+}
+
+// GetLocations handles browsing and searching the location hierarchy
+// @Summary Get locations
+// @Description Get the provinces, the children of a location, or the locations whose name contains a search term, for location pickers and search filters
+// @Tags Locations
+// @Produce json
+// @Param parent_id query string false "Only the children of this location"
+// @Param search query string false "Locations whose name contains this, at any level"
+// @Param level query string false "province, district, town or area"
+// @Success 200 {object} map[string]interface{} "Locations retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid parent ID"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /locations [get]
+func (lh *LocationHandler) GetLocations(c *gin.Context) {
+	parentID := c.Query("parent_id")
+	search := c.Query("search")
+	level := c.Query("level")
+
+	query := config.DB.Model(&models.Location{})
+	switch {
+	case parentID != "":
+		id, err := uuid.Parse(parentID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid parent ID", err)
+			return
+		}
+		query = query.Where("parent_id = ?", id).Order("name ASC")
+	case search != "":
+		// Matches higher in the hierarchy first
+		query = query.Where("name ILIKE ?", "%"+escapeLike(search)+"%").
+			Order("LENGTH(path) - LENGTH(REPLACE(path, '/', '')) ASC, name ASC").Limit(maxLocationMatches)
+	case level == "":
+		query = query.Where("parent_id IS NULL").Order("name ASC")
+	default:
+		query = query.Order("name ASC")
+	}
+	if level != "" {
+		query = query.Where("level = ?", level)
+	}
+
+	var locations []models.Location
+	if err := query.Find(&locations).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch locations", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Locations retrieved successfully", gin.H{
+		"locations": locations,
+	})
+}
+
+// GetLocation handles getting a location with its place in the hierarchy and its rents
+// @Summary Get location
+// @Description Get a location with its ancestors, its children and the rent statistics of the published listings in it and the places inside it
+// @Tags Locations
+// @Produce json
+// @Param id path string true "Location ID"
+// @Success 200 {object} map[string]interface{} "Location retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid location ID"
+// @Failure 404 {object} map[string]interface{} "Location not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /locations/{id} [get]
+func (lh *LocationHandler) GetLocation(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+		return
+	}
+
+	var location models.Location
+	if err := config.DB.Preload("Children", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name ASC")
+	}).First(&location, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Location not found")
+		return
+	}
+
+	ancestors, err := services.LocationAncestors(&location)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch location", err)
+		return
+	}
+	stats, err := services.LocationRentStats(&location)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to calculate rent statistics", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Location retrieved successfully", gin.H{
+		"location":   location,
+		"ancestors":  ancestors,
+		"rent_stats": stats,
+	})
+}
+
+// CreateLocation handles an admin adding a location missing from the hierarchy
+// @Summary Create location
+// @Description Add a province, or a place inside a location, e.g. a new compound of a town (admin only). The new location is one level below its parent.
+// @Tags Locations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body LocationRequest true "Location details"
+// @Success 201 {object} map[string]interface{} "Location created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 409 {object} map[string]interface{} "Location already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/locations [post]
+func (lh *LocationHandler) CreateLocation(c *gin.Context) {
+	var req LocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	location := models.Location{
+		Level:     models.LevelProvince,
+		Name:      req.Name,
+		Slug:      models.LocationSlug(req.Name),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
+	if location.Slug == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location name must contain letters or numbers", nil)
+		return
+	}
+	location.Path = location.Slug
+
+	if req.ParentID != nil {
+		parent, err := services.LoadLocation(*req.ParentID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid parent location", err)
+			return
+		}
+		location.Level = parent.Level.ChildLevel()
+		if location.Level == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Areas cannot contain other locations", nil)
+			return
+		}
+		location.ParentID = &parent.ID
+		location.Path = parent.Path + "/" + location.Slug
+	}
+
+	var count int64
+	config.DB.Model(&models.Location{}).Where("path = ?", location.Path).Count(&count)
+	if count > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "A location with this name already exists here", nil)
+		return
+	}
+
+	if err := config.DB.Create(&location).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create location", err)
+		return
+	}
+	services.InvalidateGazetteer()
+
+	utils.SuccessResponse(c, http.StatusCreated, "Location created successfully", gin.H{
+		"location": location,
+	})
+}
+
+// likeEscaper escapes the LIKE wildcards, and the backslash that escapes them, in a search term
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike returns a search term that matches literally inside a LIKE pattern
+func escapeLike(term string) string {
+	return likeEscaper.Replace(term)
+}
+
+// checkLocation checks that a listing's location exists. It writes the error response and returns
+// false when it does not.
+func checkLocation(c *gin.Context, id uuid.UUID) bool {
+	if _, err := services.LoadLocation(id); err != nil {
+		if errors.Is(err, services.ErrUnknownLocation) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location", err)
+		} else {
+			utils.InternalServerErrorResponse(c, "Failed to load location", err)
+		}
+		return false
+	}
+	return true
+}
+
+// resolveLocationFilter resolves the location query parameter of a search. It writes the error
+// response and returns false when the request cannot continue.
+func resolveLocationFilter(c *gin.Context, ref string) (*models.Location, bool) {
+	location, err := services.ResolveLocation(ref)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownLocation) || errors.Is(err, services.ErrAmbiguousLocation) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location", err)
+		} else {
+			utils.InternalServerErrorResponse(c, "Failed to resolve location", err)
+		}
+		return nil, false
+	}
+	return location, true
+}
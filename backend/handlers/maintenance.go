@@ -3,6 +3,7 @@ package handlers
 import (
 	"bondihub/config"
 	"bondihub/models"
+	"bondihub/services"
 	"bondihub/utils"
 	"fmt"
 	"net/http"
@@ -58,10 +59,10 @@ func (mh *MaintenanceHandler) CreateMaintenanceRequest(c *gin.Context) {
 		return
 	}
 
-	// Check if user has an active rental agreement for this house
+	// Check if user is a tenant on an active rental agreement for this house
 	var agreement models.RentalAgreement
-	if err := config.DB.Where("house_id = ? AND tenant_id = ? AND status = ?",
-		req.HouseID, userModel.ID, models.AgreementStatusActive).First(&agreement).Error; err != nil {
+	if err := config.DB.Where("house_id = ? AND status = ? AND id IN (?)",
+		req.HouseID, models.AgreementStatusActive, services.TenantAgreementIDs(userModel.ID)).First(&agreement).Error; err != nil {
 		utils.ForbiddenResponse(c, "You can only create maintenance requests for houses you rent")
 		return
 	}
@@ -70,6 +71,7 @@ func (mh *MaintenanceHandler) CreateMaintenanceRequest(c *gin.Context) {
 	maintenanceRequest := models.MaintenanceRequest{
 		TenantID:    userModel.ID,
 		HouseID:     req.HouseID,
+		AgreementID: &agreement.ID,
 		Title:       req.Title,
 		Description: req.Description,
 		Priority:    req.Priority,
@@ -123,7 +125,8 @@ func (mh *MaintenanceHandler) GetMaintenanceRequests(c *gin.Context) {
 
 	// Apply filters based on user role
 	if userModel.Role == models.RoleTenant {
-		query = query.Where("tenant_id = ?", userModel.ID)
+		query = query.Where("maintenance_requests.tenant_id = ? OR maintenance_requests.agreement_id IN (?)",
+			userModel.ID, services.TenantAgreementIDs(userModel.ID))
 	} else if userModel.Role == models.RoleLandlord {
 		query = query.Joins("JOIN houses ON maintenance_requests.house_id = houses.id").
 			Where("houses.landlord_id = ?", userModel.ID)
@@ -189,7 +192,7 @@ func (mh *MaintenanceHandler) GetMaintenanceRequest(c *gin.Context) {
 	hasAccess := false
 	if userModel.Role == models.RoleAdmin {
 		hasAccess = true
-	} else if userModel.Role == models.RoleTenant && request.TenantID == userModel.ID {
+	} else if userModel.Role == models.RoleTenant && mh.isRequestTenant(&request, userModel.ID) {
 		hasAccess = true
 	} else if userModel.Role == models.RoleLandlord && request.House.LandlordID == userModel.ID {
 		hasAccess = true
@@ -272,14 +275,17 @@ func (mh *MaintenanceHandler) UpdateMaintenanceRequest(c *gin.Context) {
 	// Load relationships
 	config.DB.Preload("Tenant").Preload("House").First(&request, request.ID)
 
-	// Create notification for tenant
-	notification := models.Notification{
-		UserID:  request.TenantID,
-		Title:   "Maintenance Request Updated",
-		Message: fmt.Sprintf("Your maintenance request for %s has been updated to: %s", request.House.Title, req.Status),
-		Type:    "maintenance",
+	// Create notifications for the tenants on the agreement
+	tenantIDs := []uuid.UUID{request.TenantID}
+	if request.AgreementID != nil {
+		var agreement models.RentalAgreement
+		if err := config.DB.First(&agreement, *request.AgreementID).Error; err == nil {
+			tenantIDs = services.AgreementTenantIDs(&agreement)
+		}
 	}
-	config.DB.Create(&notification)
+	services.NotifyAll(tenantIDs, "Maintenance Request Updated",
+		fmt.Sprintf("The maintenance request \"%s\" for %s has been updated to: %s", request.Title, request.House.Title, req.Status),
+		"maintenance")
 
 	utils.SuccessResponse(c, http.StatusOK, "Maintenance request updated successfully", gin.H{
 		"maintenance_request": request,
@@ -301,7 +307,8 @@ func (mh *MaintenanceHandler) GetMaintenanceStats(c *gin.Context) {
 
 	// Apply filters based on user role
 	if userModel.Role == models.RoleTenant {
-		query = query.Where("tenant_id = ?", userModel.ID)
+		query = query.Where("maintenance_requests.tenant_id = ? OR maintenance_requests.agreement_id IN (?)",
+			userModel.ID, services.TenantAgreementIDs(userModel.ID))
 	} else if userModel.Role == models.RoleLandlord {
 		query = query.Joins("JOIN houses ON maintenance_requests.house_id = houses.id").
 			Where("houses.landlord_id = ?", userModel.ID)
@@ -342,3 +349,19 @@ func (mh *MaintenanceHandler) GetMaintenanceStats(c *gin.Context) {
 		"avg_resolution_days":  avgResolutionDays,
 	})
 }
+
+// isRequestTenant reports whether the user raised the request or is a tenant on the agreement it was raised under
+func (mh *MaintenanceHandler) isRequestTenant(request *models.MaintenanceRequest, userID uuid.UUID) bool {
+	if request.TenantID == userID {
+		return true
+	}
+	if request.AgreementID == nil {
+		return false
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.First(&agreement, *request.AgreementID).Error; err != nil {
+		return false
+	}
+	return services.IsAgreementTenant(&agreement, userID)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ModerationHandler handles the listing moderation queue for admins
+type ModerationHandler struct{}
+
+// NewModerationHandler creates a new moderation handler
+func NewModerationHandler() *ModerationHandler {
+	return &ModerationHandler{}
+}
+
+// ApproveListingRequest represents the request structure for approving a listing
+type ApproveListingRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// RejectListingRequest represents the request structure for rejecting a listing
+type RejectListingRequest struct {
+	Reason string `json:"reason" binding:"required,oneof=suspected_scam misleading duplicate poor_photos incomplete prohibited other"`
+	Note   string `json:"note" binding:"max=1000"` // shown to the landlord; required when the reason is other
+}
+
+// GetListingQueue handles getting listings by moderation state, oldest submission first
+// @Summary Get listing moderation queue
+// @Description Get listings waiting for review, oldest submission first, with their moderation history and duplicate/scam risk score. Other moderation states can be listed with listing_status; sort=risk lists the riskiest first.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param listing_status query string false "draft, pending_review, published, rejected or archived (default pending_review)"
+// @Param sort query string false "risk to list the highest risk score first"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{} "Listings retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/listings [get]
+func (mh *ModerationHandler) GetListingQueue(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	listingStatus := models.ListingStatus(c.DefaultQuery("listing_status", string(models.ListingPendingReview)))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.House{}).Where("listing_status = ?", listingStatus)
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	order := "updated_at DESC"
+	if listingStatus == models.ListingPendingReview {
+		order = "submitted_at ASC"
+	}
+	if c.Query("sort") == "risk" {
+		order = "(SELECT score FROM listing_risks WHERE listing_risks.house_id = houses.id) DESC NULLS LAST, " + order
+	}
+
+	var houses []models.House
+	if err := query.Preload("Landlord").Preload("Property").Preload("Images", orderedImages).
+		Preload("Amenities", activeAmenities).
+		Preload("Moderations", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at DESC")
+		}).
+		Preload("Moderations.Actor").Preload("Risk").
+		Offset(offset).Limit(limit).Order(order).Find(&houses).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch listings", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	utils.SuccessResponse(c, http.StatusOK, "Listings retrieved successfully", gin.H{
+		"listings": houses,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// GetListingRisk handles reassessing a listing's duplicate and scam risk
+// @Summary Get listing risk
+// @Description Reassess a listing's duplicate and scam risk score from photos reused from other landlords' listings and near-identical titles, descriptions and addresses, and return it with the listings it matched.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Success 200 {object} map[string]interface{} "Listing risk assessed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/listings/{id}/risk [get]
+func (mh *ModerationHandler) GetListingRisk(c *gin.Context) {
+	house, ok := mh.loadListing(c)
+	if !ok {
+		return
+	}
+
+	risk, err := services.AssessListingRisk(house.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to assess listing risk", err)
+		return
+	}
+
+	// Load the listings it matched
+	matchedIDs := make([]uuid.UUID, 0, len(risk.Signals))
+	for _, signal := range risk.Signals {
+		matchedIDs = append(matchedIDs, signal.HouseID)
+	}
+	var matched []models.House
+	if len(matchedIDs) > 0 {
+		if err := config.DB.Preload("Landlord").Preload("Images", orderedImages).
+			Where("id IN ?", matchedIDs).Find(&matched).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch matched listings", err)
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Listing risk assessed", gin.H{
+		"risk":             risk,
+		"matched_listings": matched,
+	})
+}
+
+// ApproveListing handles publishing a listing
+// @Summary Approve listing
+// @Description Publish a listing that is waiting for review, or overturn a rejection. The landlord is notified.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body ApproveListingRequest false "Optional note for the landlord"
+// @Success 200 {object} map[string]interface{} "Listing approved"
+// @Failure 400 {object} map[string]interface{} "Listing is not waiting for review"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/listings/{id}/approve [put]
+func (mh *ModerationHandler) ApproveListing(c *gin.Context) {
+	house, ok := mh.loadListing(c)
+	if !ok {
+		return
+	}
+	admin := c.MustGet("user").(models.User)
+
+	var req ApproveListingRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	if house.ListingStatus != models.ListingPendingReview && house.ListingStatus != models.ListingRejected {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only listings waiting for review or rejected can be approved", nil)
+		return
+	}
+
+	if err := services.SetListingStatus(config.DB, house, models.ListingPublished, &admin.ID, "", req.Note); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to approve listing", err)
+		return
+	}
+
+	message := fmt.Sprintf("Your listing \"%s\" has been approved and is now visible to tenants.", house.Title)
+	if req.Note != "" {
+		message += " Note from the moderator: " + req.Note
+	}
+	services.Notify(house.LandlordID, "Listing Approved", message, "listing")
+	services.WakeSearchMatcher()
+
+	utils.SuccessResponse(c, http.StatusOK, "Listing approved", gin.H{
+		"house": house,
+	})
+}
+
+// RejectListing handles turning a listing down
+// @Summary Reject listing
+// @Description Reject a listing that is waiting for review, or take down a published one, with a reason. The landlord is notified and can edit and resubmit the listing.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body RejectListingRequest true "Rejection reason and note for the landlord"
+// @Success 200 {object} map[string]interface{} "Listing rejected"
+// @Failure 400 {object} map[string]interface{} "Invalid request data or listing cannot be rejected"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/listings/{id}/reject [put]
+func (mh *ModerationHandler) RejectListing(c *gin.Context) {
+	house, ok := mh.loadListing(c)
+	if !ok {
+		return
+	}
+	admin := c.MustGet("user").(models.User)
+
+	var req RejectListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if models.RejectionReason(req.Reason) == models.RejectionOther && req.Note == "" {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": "note is required when the reason is other",
+		})
+		return
+	}
+
+	if house.ListingStatus != models.ListingPendingReview && house.ListingStatus != models.ListingPublished {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only listings waiting for review or published can be rejected", nil)
+		return
+	}
+
+	if err := services.SetListingStatus(config.DB, house, models.ListingRejected, &admin.ID, req.Reason, req.Note); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to reject listing", err)
+		return
+	}
+
+	notifyListingRejected(house, req.Reason, req.Note)
+
+	utils.SuccessResponse(c, http.StatusOK, "Listing rejected", gin.H{
+		"house": house,
+	})
+}
+
+// notifyListingRejected tells a landlord why their listing was rejected and how to relist it
+func notifyListingRejected(house *models.House, reason, note string) {
+	message := fmt.Sprintf("Your listing \"%s\" was not approved (reason: %s).", house.Title, reason)
+	if note != "" {
+		message += " Note from the moderator: " + note
+	}
+	message += " You can update the listing and submit it for review again."
+	services.Notify(house.LandlordID, "Listing Rejected", message, "listing")
+}
+
+// loadListing loads the house in the URL. It writes the error response and returns false when the
+// request cannot continue.
+func (mh *ModerationHandler) loadListing(c *gin.Context) (*models.House, bool) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return nil, false
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return nil, false
+	}
+	return &house, true
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func setListingStatus(t *testing.T, house *models.House, status models.ListingStatus) {
+	t.Helper()
+
+	house.ListingStatus = status
+	if err := config.DB.Model(house).Update("listing_status", status).Error; err != nil {
+		t.Fatalf("set listing status: %v", err)
+	}
+}
+
+func TestUnpublishedListingsAreHidden(t *testing.T) {
+	useTestDB(t)
+
+	landlord := createTestUser(t, models.RoleLandlord)
+	tenant := createTestUser(t, models.RoleTenant)
+	house := createTestHouse(t, landlord)
+
+	start := time.Now().Add(48 * time.Hour)
+	slot := models.ViewingSlot{HouseID: house.ID, StartTime: start, EndTime: start.Add(time.Hour)}
+	if err := config.DB.Create(&slot).Error; err != nil {
+		t.Fatalf("create slot: %v", err)
+	}
+	if err := config.DB.Create(&models.Favorite{TenantID: tenant.ID, HouseID: house.ID}).Error; err != nil {
+		t.Fatalf("create favorite: %v", err)
+	}
+	setListingStatus(t, &house, models.ListingPendingReview)
+
+	viewingHandler := NewViewingHandler()
+	chargeHandler := NewChargeHandler()
+	favoriteHandler := NewFavoriteHandler()
+	housePath := "/houses/" + house.ID.String()
+
+	for _, tt := range []struct {
+		name    string
+		handler func(*testing.T, *models.User) int
+		user    *models.User
+		want    int
+	}{
+		{
+			name: "viewing slots for the public",
+			handler: func(t *testing.T, user *models.User) int {
+				return serve(t, viewingHandler.GetViewingSlots, http.MethodGet, "/houses/:id/viewing-slots", housePath+"/viewing-slots", user, nil).Code
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "viewing slots for the landlord",
+			handler: func(t *testing.T, user *models.User) int {
+				return serve(t, viewingHandler.GetViewingSlots, http.MethodGet, "/houses/:id/viewing-slots", housePath+"/viewing-slots", user, nil).Code
+			},
+			user: &landlord,
+			want: http.StatusOK,
+		},
+		{
+			name: "charge types for a tenant",
+			handler: func(t *testing.T, user *models.User) int {
+				return serve(t, chargeHandler.GetChargeTypes, http.MethodGet, "/houses/:id/charge-types", housePath+"/charge-types", user, nil).Code
+			},
+			user: &tenant,
+			want: http.StatusNotFound,
+		},
+		{
+			name: "booking a viewing",
+			handler: func(t *testing.T, user *models.User) int {
+				body := BookViewingRequest{SlotID: slot.ID}
+				return serve(t, viewingHandler.BookViewing, http.MethodPost, "/viewings", "/viewings", user, body).Code
+			},
+			user: &tenant,
+			want: http.StatusNotFound,
+		},
+		{
+			name: "adding to favorites",
+			handler: func(t *testing.T, user *models.User) int {
+				return serve(t, favoriteHandler.AddToFavorites, http.MethodPost, "/favorites/:id", "/favorites/"+house.ID.String(), user, nil).Code
+			},
+			user: &tenant,
+			want: http.StatusNotFound,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := tt.handler(t, tt.user); code != tt.want {
+				t.Errorf("status %d, want %d", code, tt.want)
+			}
+		})
+	}
+
+	w := serve(t, favoriteHandler.GetFavorites, http.MethodGet, "/favorites", "/favorites", &tenant, nil)
+	var response struct {
+		Data struct {
+			Favorites []models.Favorite `json:"favorites"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode favorites: %v", err)
+	}
+	if len(response.Data.Favorites) != 0 {
+		t.Errorf("got %d favorites, want the listing under review left out", len(response.Data.Favorites))
+	}
+}
+
+func TestImageChangesAreReviewed(t *testing.T) {
+	useTestDB(t)
+
+	landlord := createTestUser(t, models.RoleLandlord)
+	house := createTestHouse(t, landlord)
+	images := []models.HouseImage{
+		{HouseID: house.ID, ImageURL: "https://example.com/1.jpg", IsPrimary: true, SortOrder: 0},
+		{HouseID: house.ID, ImageURL: "https://example.com/2.jpg", SortOrder: 1},
+	}
+	if err := config.DB.Create(&images).Error; err != nil {
+		t.Fatalf("create images: %v", err)
+	}
+
+	handler := &HouseHandler{}
+	setPrimary := func() {
+		w := serve(t, handler.SetPrimaryHouseImage, http.MethodPut, "/houses/images/:imageId/primary",
+			"/houses/images/"+images[1].ID.String()+"/primary", &landlord, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("set primary image: status %d", w.Code)
+		}
+	}
+
+	setPrimary()
+	config.DB.First(&house, house.ID)
+	if house.ListingStatus != models.ListingPendingReview {
+		t.Fatalf("listing is %s after a new cover photo, want %s", house.ListingStatus, models.ListingPendingReview)
+	}
+
+	// A further change while the listing waits for review is added to its history
+	setPrimary()
+	var recorded int64
+	config.DB.Model(&models.ListingModeration{}).
+		Where("house_id = ? AND from_status = ? AND to_status = ?", house.ID, models.ListingPendingReview, models.ListingPendingReview).
+		Count(&recorded)
+	if recorded != 1 {
+		t.Errorf("recorded %d changes made while under review, want 1", recorded)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bondihub/models"
 	"bondihub/services"
 	"bondihub/utils"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -12,17 +13,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // PaymentHandler handles payment-related requests
 type PaymentHandler struct {
 	paymentService *services.PaymentService
+	billingService *services.BillingService
 }
 
 // NewPaymentHandler creates a new payment handler
 func NewPaymentHandler() *PaymentHandler {
 	return &PaymentHandler{
 		paymentService: services.NewPaymentService(),
+		billingService: services.NewBillingService(),
 	}
 }
 
@@ -32,6 +36,10 @@ type CreatePaymentRequest struct {
 	Amount      float64   `json:"amount" binding:"required,min=0"`
 	Method      string    `json:"method" binding:"required,oneof=MTN Airtel Cash Bank"`
 	ReferenceNo string    `json:"reference_no"`
+	// TenantID attributes the payment to a tenant on the agreement; tenants always pay as themselves
+	TenantID *uuid.UUID `json:"tenant_id"`
+	// ChargeID settles a specific rent or utility charge; payments without one settle the oldest open charges
+	ChargeID *uuid.UUID `json:"charge_id"`
 }
 
 // ProcessPayment handles processing a payment
@@ -44,7 +52,7 @@ type CreatePaymentRequest struct {
 // @Security BearerAuth
 // @Param request body CreatePaymentRequest true "Payment details"
 // @Success 201 {object} map[string]interface{} "Payment processed successfully"
-// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 400 {object} map[string]interface{} "Invalid request data or payment exceeds the outstanding balance"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /payments [post]
@@ -72,18 +80,54 @@ func (ph *PaymentHandler) ProcessPayment(c *gin.Context) {
 		return
 	}
 
-	// Check if user is the tenant or admin
-	if agreement.TenantID != userModel.ID && userModel.Role != models.RoleAdmin {
+	// Check if user is a tenant on the agreement or admin
+	isTenant := services.IsAgreementTenant(&agreement, userModel.ID)
+	if !isTenant && userModel.Role != models.RoleAdmin {
 		utils.ForbiddenResponse(c, "You can only make payments for your own agreements")
 		return
 	}
 
-	// Check if agreement is active
-	if agreement.Status != models.AgreementStatusActive {
+	// Work out which tenant the payment is attributed to
+	payerID := agreement.TenantID
+	if isTenant {
+		payerID = userModel.ID
+	} else if req.TenantID != nil {
+		if !services.IsAgreementTenant(&agreement, *req.TenantID) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Tenant is not on this agreement", nil)
+			return
+		}
+		payerID = *req.TenantID
+	}
+
+	// Check if agreement is active; pre-leased agreements can take payments such as the deposit before they start
+	if agreement.Status != models.AgreementStatusActive && agreement.Status != models.AgreementStatusUpcoming {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Cannot make payment for inactive agreement", nil)
 		return
 	}
 
+	// Check the charge being paid belongs to the agreement and is still outstanding
+	if req.ChargeID != nil {
+		var charge models.Charge
+		if err := config.DB.Where("id = ? AND agreement_id = ?", *req.ChargeID, agreement.ID).First(&charge).Error; err != nil {
+			utils.NotFoundResponse(c, "Charge not found on this agreement")
+			return
+		}
+		if charge.Status == models.ChargeStatusPaid {
+			utils.ErrorResponse(c, http.StatusBadRequest, "This charge has already been paid", nil)
+			return
+		}
+	}
+
+	// Payments cannot be larger than what is owed on the charge, or on the agreement when no charge is named
+	if err := ph.billingService.CheckPaymentAmount(agreement.ID, req.ChargeID, req.Amount); err != nil {
+		if errors.Is(err, services.ErrPaymentExceedsBalance) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Payment exceeds the outstanding balance", err)
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to check outstanding balance", err)
+		return
+	}
+
 	// Generate reference number if not provided
 	if req.ReferenceNo == "" {
 		req.ReferenceNo = fmt.Sprintf("PAY_%d", time.Now().Unix())
@@ -92,6 +136,8 @@ func (ph *PaymentHandler) ProcessPayment(c *gin.Context) {
 	// Create payment record
 	payment := models.Payment{
 		AgreementID: agreement.ID,
+		TenantID:    &payerID,
+		ChargeID:    req.ChargeID,
 		Amount:      req.Amount,
 		PaymentDate: time.Now(),
 		Method:      models.PaymentMethod(req.Method),
@@ -124,7 +170,22 @@ func (ph *PaymentHandler) ProcessPayment(c *gin.Context) {
 		payment.Status = models.PaymentStatusFailed
 	}
 
-	config.DB.Save(&payment)
+	// Record the outcome and what it paid off together, so the charge never disagrees with its payments
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusCompleted {
+			return nil
+		}
+		if payment.ChargeID != nil {
+			return ph.billingService.ApplyChargePayment(tx, *payment.ChargeID, payment.Amount)
+		}
+		return ph.billingService.AllocatePayment(tx, payment.AgreementID, payment.Amount)
+	}); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to record payment", err)
+		return
+	}
 
 	// Create notification for landlord
 	notification := models.Notification{
@@ -177,11 +238,12 @@ func (ph *PaymentHandler) GetPayments(c *gin.Context) {
 	query := config.DB.Model(&models.Payment{}).
 		Joins("JOIN rental_agreements ON payments.agreement_id = rental_agreements.id").
 		Preload("Agreement.House").
-		Preload("Agreement.Tenant")
+		Preload("Agreement.Tenant").
+		Preload("Tenant")
 
 	// Apply filters based on user role
 	if userModel.Role == models.RoleTenant {
-		query = query.Where("rental_agreements.tenant_id = ?", userModel.ID)
+		query = query.Where("rental_agreements.id IN (?)", services.TenantAgreementIDs(userModel.ID))
 	} else if userModel.Role == models.RoleLandlord {
 		query = query.Joins("JOIN houses ON rental_agreements.house_id = houses.id").
 			Where("houses.landlord_id = ?", userModel.ID)
@@ -238,7 +300,7 @@ func (ph *PaymentHandler) GetPayment(c *gin.Context) {
 	}
 
 	var payment models.Payment
-	if err := config.DB.Preload("Agreement.House").Preload("Agreement.Tenant").First(&payment, id).Error; err != nil {
+	if err := config.DB.Preload("Agreement.House").Preload("Agreement.Tenant").Preload("Tenant").First(&payment, id).Error; err != nil {
 		utils.NotFoundResponse(c, "Payment not found")
 		return
 	}
@@ -247,7 +309,7 @@ func (ph *PaymentHandler) GetPayment(c *gin.Context) {
 	hasAccess := false
 	if userModel.Role == models.RoleAdmin {
 		hasAccess = true
-	} else if userModel.Role == models.RoleTenant && payment.Agreement.TenantID == userModel.ID {
+	} else if userModel.Role == models.RoleTenant && services.IsAgreementTenant(&payment.Agreement, userModel.ID) {
 		hasAccess = true
 	} else if userModel.Role == models.RoleLandlord && payment.Agreement.House.LandlordID == userModel.ID {
 		hasAccess = true
@@ -279,7 +341,7 @@ func (ph *PaymentHandler) GetPaymentStats(c *gin.Context) {
 
 	// Apply filters based on user role
 	if userModel.Role == models.RoleTenant {
-		query = query.Where("rental_agreements.tenant_id = ?", userModel.ID)
+		query = query.Where("rental_agreements.id IN (?)", services.TenantAgreementIDs(userModel.ID))
 	} else if userModel.Role == models.RoleLandlord {
 		query = query.Joins("JOIN houses ON rental_agreements.house_id = houses.id").
 			Where("houses.landlord_id = ?", userModel.ID)
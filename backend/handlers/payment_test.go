@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createTestCharge(t *testing.T, agreement models.RentalAgreement, periodStart time.Time, amount float64) models.Charge {
+	t.Helper()
+
+	charge := models.Charge{
+		AgreementID: agreement.ID,
+		Kind:        models.ChargeKindRent,
+		Description: "Rent " + periodStart.Format("January 2006"),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodStart.AddDate(0, 1, 0),
+		Amount:      amount,
+		DueDate:     periodStart,
+		Status:      models.ChargeStatusUnpaid,
+	}
+	if err := config.DB.Create(&charge).Error; err != nil {
+		t.Fatalf("create charge: %v", err)
+	}
+	return charge
+}
+
+func TestProcessPaymentAllocatesToOldestCharges(t *testing.T) {
+	useTestDB(t)
+
+	landlord := createTestUser(t, models.RoleLandlord)
+	tenant := createTestUser(t, models.RoleTenant)
+	agreement := createTestAgreement(t, createTestHouse(t, landlord), tenant)
+	first := createTestCharge(t, agreement, agreement.StartDate, 2500)
+	second := createTestCharge(t, agreement, agreement.StartDate.AddDate(0, 1, 0), 2500)
+
+	handler := NewPaymentHandler()
+	pay := func(amount float64, chargeID *uuid.UUID) int {
+		body := CreatePaymentRequest{AgreementID: agreement.ID, Amount: amount, Method: "Cash", ChargeID: chargeID}
+		return serve(t, handler.ProcessPayment, http.MethodPost, "/payments", "/payments", &tenant, body).Code
+	}
+
+	if code := pay(5000.01, nil); code != http.StatusBadRequest {
+		t.Fatalf("payment over the balance: status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := pay(2500.01, &second.ID); code != http.StatusBadRequest {
+		t.Fatalf("payment over the charge: status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := pay(3000, nil); code != http.StatusCreated {
+		t.Fatalf("payment: status %d, want %d", code, http.StatusCreated)
+	}
+
+	var charges []models.Charge
+	config.DB.Where("id IN ?", []uuid.UUID{first.ID, second.ID}).Order("due_date ASC").Find(&charges)
+	if len(charges) != 2 {
+		t.Fatalf("found %d charges, want 2", len(charges))
+	}
+	if charges[0].Status != models.ChargeStatusPaid || charges[0].AmountPaid != 2500 {
+		t.Errorf("oldest charge is %s with %.2f paid, want paid in full", charges[0].Status, charges[0].AmountPaid)
+	}
+	if charges[1].Status != models.ChargeStatusPartial || charges[1].AmountPaid != 500 {
+		t.Errorf("next charge is %s with %.2f paid, want partial with 500.00", charges[1].Status, charges[1].AmountPaid)
+	}
+
+	if code := pay(2000, &second.ID); code != http.StatusCreated {
+		t.Fatalf("payment against a charge: status %d, want %d", code, http.StatusCreated)
+	}
+	if code := pay(1, nil); code != http.StatusBadRequest {
+		t.Fatalf("payment with nothing owed: status %d, want %d", code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,645 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errPropertyImageLimit is returned when a property already has the maximum number of images
+var errPropertyImageLimit = errors.New("property image limit reached")
+
+// PropertyHandler handles buildings and compounds with rentable units
+type PropertyHandler struct {
+	imageStorage services.ImageStorage
+	imageCleanup *services.ImageCleanupService
+	geocoder     services.Geocoder
+}
+
+// NewPropertyHandler creates a new property handler
+func NewPropertyHandler(geocoder services.Geocoder) *PropertyHandler {
+	imageStorage, err := services.NewImageStorage()
+	if err != nil {
+		log.Printf("Property image uploads will not work until image storage is properly configured: %v", err)
+	}
+	return &PropertyHandler{
+		imageStorage: imageStorage,
+		imageCleanup: services.NewImageCleanupService(imageStorage),
+		geocoder:     geocoder,
+	}
+}
+
+// CreatePropertyRequest represents the request structure for creating a property
+type CreatePropertyRequest struct {
+	Name        string      `json:"name" binding:"required,min=3,max=200"`
+	Description string      `json:"description"`
+	Address     string      `json:"address" binding:"omitempty,min=10"` // suggested from the coordinates when omitted
+	LocationID  *uuid.UUID  `json:"location_id"`
+	Latitude    *float64    `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude   *float64    `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	AmenityIDs  []uuid.UUID `json:"amenity_ids"` // amenities shared by all units
+}
+
+// UpdatePropertyRequest represents the request structure for updating a property
+type UpdatePropertyRequest struct {
+	Name        string      `json:"name" binding:"omitempty,min=3,max=200"`
+	Description *string     `json:"description"`
+	Address     string      `json:"address" binding:"omitempty,min=10"`
+	LocationID  *string     `json:"location_id"` // "" clears the location
+	Latitude    *float64    `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude   *float64    `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	AmenityIDs  []uuid.UUID `json:"amenity_ids"` // replaces the shared amenities when set; [] clears them
+}
+
+// CreateProperty handles a landlord adding a building or compound
+// @Summary Create property
+// @Description Add a building or compound whose units are listed as separate houses (landlords and admins only)
+// @Tags Properties
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreatePropertyRequest true "Property details"
+// @Success 201 {object} map[string]interface{} "Property created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /properties [post]
+func (ph *PropertyHandler) CreateProperty(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	var req CreatePropertyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	amenities, err := services.LoadAmenities(req.AmenityIDs)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenities", err)
+		return
+	}
+	if req.LocationID != nil && !checkLocation(c, *req.LocationID) {
+		return
+	}
+
+	// Coordinates come as a pair; half a pin would be geocoded over or saved at the equator
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": "Provide both latitude and longitude, or omit both to geocode the address.",
+		})
+		return
+	}
+	place := services.Place{Address: req.Address, LocationID: req.LocationID}
+	if req.Latitude != nil {
+		place.Latitude, place.Longitude = *req.Latitude, *req.Longitude
+	}
+
+	// Fill in the coordinates from the address, or the address from a map pin
+	geocoded := services.CompletePlace(c.Request.Context(), ph.geocoder, &place)
+	if place.Address == "" {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": "address is required unless the coordinates are in a known area",
+		})
+		return
+	}
+
+	property := models.Property{
+		LandlordID:  userModel.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Address:     place.Address,
+		LocationID:  place.LocationID,
+		Latitude:    place.Latitude,
+		Longitude:   place.Longitude,
+
+		GeocodeSource:    place.Source,
+		GeocodePrecision: place.Precision,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&property).Error; err != nil {
+			return err
+		}
+		return tx.Model(&property).Association("Amenities").Replace(amenities)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create property", err)
+		return
+	}
+
+	config.DB.Preload("Location").Preload("Amenities", activeAmenities).First(&property, property.ID)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Property created successfully", gin.H{
+		"property": property,
+		"geocoded": geocoded,
+	})
+}
+
+// GetProperties handles getting the properties of the current landlord, or all properties for admins
+func (ph *PropertyHandler) GetProperties(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Property{})
+	if userModel.Role != models.RoleAdmin {
+		query = query.Where("landlord_id = ?", userModel.ID)
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	var properties []models.Property
+	if err := query.Preload("Images", orderedImages).Preload("Units").
+		Offset(offset).Limit(limit).Order("created_at DESC").
+		Find(&properties).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch properties", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	utils.SuccessResponse(c, http.StatusOK, "Properties retrieved successfully", gin.H{
+		"properties": properties,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// GetProperty handles getting a property with its shared images, amenities and units
+// @Summary Get property by ID
+// @Description Get a building or compound with its shared images and amenities and its units
+// @Tags Properties
+// @Produce json
+// @Param id path string true "Property ID"
+// @Success 200 {object} map[string]interface{} "Property retrieved successfully"
+// @Failure 404 {object} map[string]interface{} "Property not found"
+// @Router /properties/{id} [get]
+func (ph *PropertyHandler) GetProperty(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid property ID", err)
+		return
+	}
+
+	// Units that are not published are only shown to their landlord and admins. The viewer is set
+	// by OptionalAuthMiddleware and is a zero user when not signed in.
+	user, _ := c.Get("user")
+	viewer, _ := user.(models.User)
+
+	var property models.Property
+	if err := config.DB.Preload("Landlord").Preload("Location").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).
+		Preload("Units", func(db *gorm.DB) *gorm.DB {
+			if viewer.Role != models.RoleAdmin {
+				db = db.Where("listing_status = ? OR landlord_id = ?", models.ListingPublished, viewer.ID)
+			}
+			return db.Order("unit_label ASC, title ASC")
+		}).
+		Preload("Units.Images", orderedImages).
+		Preload("Units.Amenities", activeAmenities).
+		First(&property, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Property not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Property retrieved successfully", gin.H{
+		"property": property,
+	})
+}
+
+// UpdateProperty handles updating a property. Address, location and coordinate changes are copied to its units.
+func (ph *PropertyHandler) UpdateProperty(c *gin.Context) {
+	property, ok := ph.loadManagedProperty(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePropertyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	before := *property
+	if req.Name != "" {
+		property.Name = req.Name
+	}
+	if req.Description != nil {
+		property.Description = *req.Description
+	}
+	if req.Address != "" {
+		property.Address = req.Address
+	}
+	if req.LocationID != nil {
+		if *req.LocationID == "" {
+			property.LocationID = nil
+		} else {
+			locationID, err := uuid.Parse(*req.LocationID)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+				return
+			}
+			if !checkLocation(c, locationID) {
+				return
+			}
+			property.LocationID = &locationID
+		}
+	}
+	if req.Latitude != nil {
+		property.Latitude = *req.Latitude
+	}
+	if req.Longitude != nil {
+		property.Longitude = *req.Longitude
+	}
+
+	// A pin moved by the landlord is exact; a pin geocoded from the old address is geocoded again
+	if req.Latitude != nil || req.Longitude != nil {
+		property.GeocodeSource, property.GeocodePrecision = "", ""
+	} else if req.Address != "" && req.Address != before.Address && property.GeocodeSource != "" {
+		property.Latitude, property.Longitude = 0, 0
+		property.GeocodeSource, property.GeocodePrecision = "", ""
+	}
+
+	// Geocode a changed address when the property has no coordinates
+	var geocoded *services.GeocodeResult
+	if req.Address != "" || req.LocationID != nil || req.Latitude != nil || req.Longitude != nil {
+		place := services.Place{Address: property.Address, Latitude: property.Latitude, Longitude: property.Longitude, LocationID: property.LocationID}
+		geocoded = services.CompletePlace(c.Request.Context(), ph.geocoder, &place)
+		property.Latitude, property.Longitude, property.LocationID = place.Latitude, place.Longitude, place.LocationID
+		if place.Source != "" {
+			property.GeocodeSource, property.GeocodePrecision = place.Source, place.Precision
+		}
+	}
+
+	var amenities []models.Amenity
+	if req.AmenityIDs != nil {
+		var err error
+		amenities, err = services.LoadAmenities(req.AmenityIDs)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid amenities", err)
+			return
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(property).Error; err != nil {
+			return err
+		}
+		if err := services.SyncPropertyUnits(tx, property); err != nil {
+			return err
+		}
+		// Units go back for review when a landlord moves them
+		locationChanged := property.Latitude != before.Latitude || property.Longitude != before.Longitude ||
+			!sameLocation(property.LocationID, before.LocationID)
+		if (property.Address != before.Address || locationChanged) && c.MustGet("user").(models.User).Role != models.RoleAdmin {
+			var unitIDs []uuid.UUID
+			if err := tx.Model(&models.House{}).Where("property_id = ?", property.ID).Pluck("id", &unitIDs).Error; err != nil {
+				return err
+			}
+			for _, unitID := range unitIDs {
+				if _, err := services.ReviewListingChange(tx, unitID, "Changed address"); err != nil {
+					return err
+				}
+			}
+		}
+		if req.AmenityIDs == nil {
+			return nil
+		}
+		return tx.Model(property).Association("Amenities").Replace(amenities)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update property", err)
+		return
+	}
+
+	config.DB.Preload("Images", orderedImages).Preload("Location").Preload("Amenities", activeAmenities).First(property, property.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, "Property updated successfully", gin.H{
+		"property": property,
+		"geocoded": geocoded,
+	})
+}
+
+// DeleteProperty handles deleting a property that has no units left
+func (ph *PropertyHandler) DeleteProperty(c *gin.Context) {
+	property, ok := ph.loadManagedProperty(c)
+	if !ok {
+		return
+	}
+
+	var unitCount int64
+	config.DB.Model(&models.House{}).Where("property_id = ?", property.ID).Count(&unitCount)
+	if unitCount > 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Delete or detach the property's units first", nil)
+		return
+	}
+
+	var deletions []models.ImageDeletion
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(property).Error; err != nil {
+			return err
+		}
+
+		var publicIDs []string
+		if err := tx.Model(&models.PropertyImage{}).Where("property_id = ?", property.ID).
+			Pluck("public_id", &publicIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("property_id = ?", property.ID).Delete(&models.PropertyImage{}).Error; err != nil {
+			return err
+		}
+		var err error
+		deletions, err = services.QueueImageDeletions(tx, publicIDs...)
+		return err
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete property", err)
+		return
+	}
+	ph.imageCleanup.DeleteQueued(c.Request.Context(), deletions)
+
+	utils.SuccessResponse(c, http.StatusOK, "Property deleted successfully", nil)
+}
+
+// GetPropertyOccupancy handles the building-level occupancy view of a property
+// @Summary Get property occupancy
+// @Description Unit-by-unit occupancy, occupancy rate and rent roll of a property (owner or admin only)
+// @Tags Properties
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Property ID"
+// @Success 200 {object} map[string]interface{} "Property occupancy retrieved successfully"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Property not found"
+// @Router /properties/{id}/occupancy [get]
+func (ph *PropertyHandler) GetPropertyOccupancy(c *gin.Context) {
+	property, ok := ph.loadManagedProperty(c)
+	if !ok {
+		return
+	}
+
+	occupancy, err := services.GetPropertyOccupancy(property.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch property occupancy", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Property occupancy retrieved successfully", gin.H{
+		"property":  property,
+		"occupancy": occupancy,
+	})
+}
+
+// UploadPropertyImage handles uploading an image shared by all units of a property
+func (ph *PropertyHandler) UploadPropertyImage(c *gin.Context) {
+	property, ok := ph.loadManagedProperty(c)
+	if !ok {
+		return
+	}
+
+	// Check the property has room for another image before receiving it
+	var imageCount int64
+	config.DB.Model(&models.PropertyImage{}).Where("property_id = ?", property.ID).Count(&imageCount)
+	if imageCount >= int64(config.AppConfig.MaxHouseImages) {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A property can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+		return
+	}
+
+	stored, ok := receiveImage(c, ph.imageStorage, "image", "bondihub/properties")
+	if !ok {
+		return
+	}
+
+	propertyImage := models.PropertyImage{
+		PropertyID:   property.ID,
+		ImageURL:     stored.URL,
+		PublicID:     stored.PublicID,
+		MediumURL:    ph.imageStorage.URL(stored.PublicID, services.ImageSizeMedium),
+		ThumbnailURL: ph.imageStorage.URL(stored.PublicID, services.ImageSizeThumbnail),
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the property so concurrent uploads cannot exceed the image cap
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Property{}, property.ID).Error; err != nil {
+			return err
+		}
+		var count int64
+		tx.Model(&models.PropertyImage{}).Where("property_id = ?", property.ID).Count(&count)
+		if count >= int64(config.AppConfig.MaxHouseImages) {
+			return errPropertyImageLimit
+		}
+
+		propertyImage.SortOrder = services.NextPropertyImageSortOrder(tx, property.ID)
+		propertyImage.IsPrimary = count == 0
+		return tx.Create(&propertyImage).Error
+	})
+	if err != nil {
+		if deletions, queueErr := services.QueueImageDeletions(config.DB, stored.PublicID); queueErr == nil {
+			ph.imageCleanup.DeleteQueued(c.Request.Context(), deletions)
+		}
+		if errors.Is(err, errPropertyImageLimit) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("A property can have at most %d images", config.AppConfig.MaxHouseImages), nil)
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to save image record", err)
+		return
+	}
+
+	// Property images are shown on every unit, so each unit's listing is reviewed
+	var unitIDs []uuid.UUID
+	config.DB.Model(&models.House{}).Where("property_id = ?", property.ID).Pluck("id", &unitIDs)
+	requestImageReview(c, "Changed property images", unitIDs...)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Image uploaded successfully", gin.H{
+		"image": propertyImage,
+	})
+}
+
+// DeletePropertyImage handles deleting a property image
+func (ph *PropertyHandler) DeletePropertyImage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid image ID", err)
+		return
+	}
+
+	var image models.PropertyImage
+	if err := config.DB.Preload("Property").First(&image, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Image not found")
+		return
+	}
+
+	if image.Property.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only delete images for your own properties")
+		return
+	}
+
+	var deletions []models.ImageDeletion
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deletions, err = services.DeletePropertyImage(tx, &image)
+		return err
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete image", err)
+		return
+	}
+	ph.imageCleanup.DeleteQueued(c.Request.Context(), deletions)
+
+	utils.SuccessResponse(c, http.StatusOK, "Image deleted successfully", nil)
+}
+
+// SetPrimaryPropertyImage handles choosing the primary image of a property
+// @Summary Set primary property image
+// @Description Make an image the primary image of its property (owner or admin only)
+// @Tags Properties
+// @Produce json
+// @Security BearerAuth
+// @Param imageId path string true "Image ID"
+// @Success 200 {object} map[string]interface{} "Primary image updated successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid image ID"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - You can only manage images for your own properties"
+// @Failure 404 {object} map[string]interface{} "Image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /properties/images/{imageId}/primary [put]
+func (ph *PropertyHandler) SetPrimaryPropertyImage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid image ID", err)
+		return
+	}
+
+	var image models.PropertyImage
+	if err := config.DB.Preload("Property").First(&image, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Image not found")
+		return
+	}
+
+	if image.Property.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only manage images for your own properties")
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.SetPrimaryPropertyImage(tx, &image)
+	}); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update primary image", err)
+		return
+	}
+
+	// Property images are shown on every unit, so each unit's listing is reviewed
+	var unitIDs []uuid.UUID
+	config.DB.Model(&models.House{}).Where("property_id = ?", image.PropertyID).Pluck("id", &unitIDs)
+	requestImageReview(c, "Changed primary property image", unitIDs...)
+
+	var images []models.PropertyImage
+	orderedImages(config.DB).Where("property_id = ?", image.PropertyID).Find(&images)
+
+	utils.SuccessResponse(c, http.StatusOK, "Primary image updated successfully", gin.H{
+		"images": images,
+	})
+}
+
+// loadManagedProperty loads the property in the URL and checks the current user owns it or is an admin.
+// It writes the error response and returns false when the request cannot continue.
+func (ph *PropertyHandler) loadManagedProperty(c *gin.Context) (*models.Property, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, false
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid property ID", err)
+		return nil, false
+	}
+
+	var property models.Property
+	if err := config.DB.First(&property, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Property not found")
+		return nil, false
+	}
+
+	if property.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only manage your own properties")
+		return nil, false
+	}
+
+	return &property, true
+}
+
+// loadPropertyForUnit loads a property a house is being added to as a unit, checking the user may manage it.
+// It writes the error response and returns false when the request cannot continue.
+func loadPropertyForUnit(c *gin.Context, propertyID uuid.UUID, userModel models.User) (*models.Property, bool) {
+	var property models.Property
+	if err := config.DB.First(&property, propertyID).Error; err != nil {
+		utils.NotFoundResponse(c, "Property not found")
+		return nil, false
+	}
+
+	if property.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only add units to your own properties")
+		return nil, false
+	}
+
+	return &property, true
+}
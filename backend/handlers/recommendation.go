@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRecommendations caps the number of houses a recommendation request returns
+const maxRecommendations = 20
+
+// RecommendationHandler handles similar-listing and personalised house recommendations
+type RecommendationHandler struct{}
+
+// NewRecommendationHandler creates a new recommendation handler
+func NewRecommendationHandler() *RecommendationHandler {
+	return &RecommendationHandler{}
+}
+
+// GetSimilarHouses handles getting the listings most like a house
+// @Summary Get similar houses
+// @Description Get available listings like a house, best match first, ranked by proximity, rent band, type, bedrooms and amenity overlap. Occupied listings are left out.
+// @Tags Houses
+// @Produce json
+// @Param id path string true "House ID"
+// @Param limit query int false "Number of houses" default(6)
+// @Success 200 {object} map[string]interface{} "Similar houses retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid house ID"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/similar [get]
+func (rh *RecommendationHandler) GetSimilarHouses(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil || !canViewListing(c, &house) {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	query, err := services.SimilarHousesQuery(&house)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to find similar houses", err)
+		return
+	}
+
+	var houses []models.House
+	if err := query.Preload("Landlord").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).
+		Limit(recommendationLimit(c)).Find(&houses).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to find similar houses", err)
+		return
+	}
+	recordImpressions(c, houses)
+
+	utils.SuccessResponse(c, http.StatusOK, "Similar houses retrieved successfully", gin.H{
+		"houses": houses,
+	})
+}
+
+// GetRecommendedHouses handles getting the current user's home feed
+// @Summary Get recommended houses
+// @Description Get available listings picked for the current user from the listings they favorited and recently viewed and their saved searches, best match first. Listings they already favorited or viewed are left out. Until the user has shown interest in any listing, the newest listings are returned, featured ones first, and personalised is false.
+// @Tags Houses
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of houses" default(6)
+// @Success 200 {object} map[string]interface{} "Recommended houses retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/recommended [get]
+func (rh *RecommendationHandler) GetRecommendedHouses(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	query, personalised, err := services.RecommendedHousesQuery(user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to recommend houses", err)
+		return
+	}
+
+	var houses []models.House
+	if err := query.Preload("Landlord").Preload("Images", orderedImages).Preload("Amenities", activeAmenities).
+		Limit(recommendationLimit(c)).Find(&houses).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to recommend houses", err)
+		return
+	}
+	recordImpressions(c, houses)
+
+	utils.SuccessResponse(c, http.StatusOK, "Recommended houses retrieved successfully", gin.H{
+		"houses":       houses,
+		"personalised": personalised,
+	})
+}
+
+// recommendationLimit parses the limit query parameter, defaulting to 6
+func recommendationLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "6"))
+	if err != nil || limit < 1 {
+		return 6
+	}
+	return min(limit, maxRecommendations)
+}
@@ -3,22 +3,34 @@ package handlers
 import (
 	"bondihub/config"
 	"bondihub/models"
+	"bondihub/services"
 	"bondihub/utils"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errAgreementOverlap is returned from transactions when another agreement already holds the house for the dates
+var errAgreementOverlap = errors.New("agreement dates overlap another agreement for the house")
+
 // RentalHandler handles rental agreement-related requests
-type RentalHandler struct{}
+type RentalHandler struct {
+	rentalService *services.RentalService
+}
 
 // NewRentalHandler creates a new rental handler
 func NewRentalHandler() *RentalHandler {
-	return &RentalHandler{}
+	return &RentalHandler{
+		rentalService: services.NewRentalService(),
+	}
 }
 
 // CreateRentalAgreementRequest represents the request structure for creating a rental agreement
@@ -29,6 +41,66 @@ type CreateRentalAgreementRequest struct {
 	EndDate    string    `json:"end_date" binding:"required"`
 	RentAmount float64   `json:"rent_amount" binding:"required,min=0"`
 	Deposit    float64   `json:"deposit" binding:"required,min=0"`
+	// NoticePeriodDays defaults to 30 days when omitted
+	NoticePeriodDays *int `json:"notice_period_days" binding:"omitempty,min=0,max=365"`
+	// CoTenants share the agreement with the primary tenant; the primary tenant pays the remaining rent
+	CoTenants []CoTenantRequest `json:"co_tenants" binding:"omitempty,dive"`
+	// Escalation schedules rent increases on the agreement's anniversaries
+	Escalation *EscalationRequest `json:"escalation"`
+}
+
+// EscalationRequest represents a rent escalation clause. Type "none" removes the clause in amendments.
+type EscalationRequest struct {
+	Type  string  `json:"type" binding:"required,oneof=none fixed percentage"`
+	Value float64 `json:"value" binding:"min=0"`
+	// IntervalMonths defaults to 12 months when omitted
+	IntervalMonths int `json:"interval_months" binding:"omitempty,min=1,max=120"`
+}
+
+// Terms validates the request and converts it to the clause stored on the agreement
+func (er *EscalationRequest) Terms() (models.EscalationTerms, error) {
+	if er.Type == "none" {
+		return models.EscalationTerms{}, nil
+	}
+	if er.Value <= 0 {
+		return models.EscalationTerms{}, errors.New("escalation value must be greater than zero")
+	}
+	if er.Type == string(models.EscalationPercentage) && er.Value > 100 {
+		return models.EscalationTerms{}, errors.New("escalation percentage cannot exceed 100")
+	}
+
+	intervalMonths := er.IntervalMonths
+	if intervalMonths == 0 {
+		intervalMonths = 12
+	}
+	return models.EscalationTerms{
+		Type:           models.EscalationType(er.Type),
+		Value:          er.Value,
+		IntervalMonths: intervalMonths,
+	}, nil
+}
+
+// CoTenantRequest represents a co-tenant and their share of the monthly rent
+type CoTenantRequest struct {
+	TenantID  uuid.UUID `json:"tenant_id" binding:"required"`
+	RentShare float64   `json:"rent_share" binding:"min=0"`
+}
+
+// UpdateRentSharesRequest represents the request structure for redistributing rent between tenants
+type UpdateRentSharesRequest struct {
+	Shares []CoTenantRequest `json:"shares" binding:"required,min=1,dive"`
+}
+
+// TerminateRentalAgreementRequest represents the request structure for terminating a rental agreement
+type TerminateRentalAgreementRequest struct {
+	// TerminationDate schedules the termination for a future date; omit to terminate immediately
+	TerminationDate string `json:"termination_date"`
+}
+
+// MoveOutNoticeRequest represents the request structure for giving a move-out notice
+type MoveOutNoticeRequest struct {
+	MoveOutDate string `json:"move_out_date" binding:"required"`
+	Reason      string `json:"reason" binding:"max=1000"`
 }
 
 // CreateRentalAgreement handles creating a new rental agreement
@@ -98,12 +170,19 @@ func (rh *RentalHandler) CreateRentalAgreement(c *gin.Context) {
 		return
 	}
 
-	// Check if house is available
-	if house.Status != models.StatusAvailable {
+	// Houses under maintenance cannot be let; occupied houses can be pre-leased from when they are free
+	if house.Status == models.StatusMaintenance {
 		utils.ErrorResponse(c, http.StatusBadRequest, "House is not available for rent", nil)
 		return
 	}
 
+	today := time.Now().Truncate(24 * time.Hour)
+	if startDate.After(today.AddDate(0, 0, config.AppConfig.PreLeaseWindowDays)) {
+		utils.ErrorResponse(c, http.StatusBadRequest,
+			fmt.Sprintf("Agreements can be signed at most %d days before they start", config.AppConfig.PreLeaseWindowDays), nil)
+		return
+	}
+
 	// Get tenant
 	var tenant models.User
 	if err := config.DB.Where("id = ? AND role = ?", req.TenantID, models.RoleTenant).First(&tenant).Error; err != nil {
@@ -111,44 +190,133 @@ func (rh *RentalHandler) CreateRentalAgreement(c *gin.Context) {
 		return
 	}
 
-	// Check if there's already an active agreement for this house
-	var existingAgreement models.RentalAgreement
-	if err := config.DB.Where("house_id = ? AND status = ?", req.HouseID, models.AgreementStatusActive).First(&existingAgreement).Error; err == nil {
-		utils.ErrorResponse(c, http.StatusConflict, "House already has an active rental agreement", nil)
+	// Validate co-tenants and work out the primary tenant's share of the rent
+	primaryShare := req.RentAmount
+	seenTenants := map[uuid.UUID]bool{req.TenantID: true}
+	for _, coTenant := range req.CoTenants {
+		if seenTenants[coTenant.TenantID] {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Each tenant can only appear once on an agreement", nil)
+			return
+		}
+		seenTenants[coTenant.TenantID] = true
+
+		var count int64
+		config.DB.Model(&models.User{}).Where("id = ? AND role = ?", coTenant.TenantID, models.RoleTenant).Count(&count)
+		if count == 0 {
+			utils.NotFoundResponse(c, fmt.Sprintf("Co-tenant %s not found", coTenant.TenantID))
+			return
+		}
+		primaryShare -= coTenant.RentShare
+	}
+	if primaryShare < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Co-tenant rent shares exceed the rent amount", nil)
 		return
 	}
 
+	var escalation models.EscalationTerms
+	if req.Escalation != nil {
+		escalation, err = req.Escalation.Terms()
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	noticePeriodDays := 30
+	if req.NoticePeriodDays != nil {
+		noticePeriodDays = *req.NoticePeriodDays
+	}
+
+	// Agreements starting in the future are pre-leases and start when their start date arrives
+	status := models.AgreementStatusActive
+	if startDate.After(today) {
+		status = models.AgreementStatusUpcoming
+	}
+
 	// Create rental agreement
 	agreement := models.RentalAgreement{
-		HouseID:    req.HouseID,
-		TenantID:   req.TenantID,
-		StartDate:  startDate,
-		EndDate:    endDate,
-		RentAmount: req.RentAmount,
-		Deposit:    req.Deposit,
-		Status:     models.AgreementStatusActive,
+		HouseID:          req.HouseID,
+		TenantID:         req.TenantID,
+		StartDate:        startDate,
+		EndDate:          endDate,
+		RentAmount:       req.RentAmount,
+		Deposit:          req.Deposit,
+		Status:           status,
+		NoticePeriodDays: noticePeriodDays,
+		Escalation:       escalation,
+	}
+
+	// Every tenant, including the primary tenant, gets a row with their rent share
+	agreement.Tenants = []models.AgreementTenant{{
+		TenantID:  req.TenantID,
+		RentShare: primaryShare,
+		IsPrimary: true,
+	}}
+	for _, coTenant := range req.CoTenants {
+		agreement.Tenants = append(agreement.Tenants, models.AgreementTenant{
+			TenantID:  coTenant.TenantID,
+			RentShare: coTenant.RentShare,
+		})
 	}
 
-	if err := config.DB.Create(&agreement).Error; err != nil {
+	// The house row is locked while checking dates so two agreements cannot be let for the same period
+	var overlap *models.RentalAgreement
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.LockHouse(tx, house.ID); err != nil {
+			return err
+		}
+
+		var err error
+		overlap, err = services.FindOverlappingAgreement(tx, house.ID, startDate, endDate, nil)
+		if err != nil {
+			return err
+		}
+		if overlap != nil {
+			return errAgreementOverlap
+		}
+
+		if err := tx.Create(&agreement).Error; err != nil {
+			return err
+		}
+
+		// Version 1 records the original terms
+		terms := models.FieldChanges{
+			{Field: models.FieldRentAmount, To: agreement.RentAmount},
+			{Field: models.FieldDeposit, To: agreement.Deposit},
+			{Field: models.FieldStartDate, To: req.StartDate},
+			{Field: models.FieldEndDate, To: req.EndDate},
+			{Field: models.FieldTenants, To: services.AgreementTenantShares(tx, &agreement)},
+		}
+		if escalation.Type != "" {
+			terms = append(terms, models.FieldChange{Field: models.FieldEscalations, To: escalation})
+		}
+		if err := services.RecordAgreementChange(tx, agreement.ID, &userModel.ID, terms, "Original agreement terms"); err != nil {
+			return err
+		}
+
+		if err := services.SyncEscalationSchedule(tx, &agreement, agreement.StartDate); err != nil {
+			return err
+		}
+
+		return services.RefreshHouseAvailability(tx, house.ID)
+	})
+	if errors.Is(err, errAgreementOverlap) {
+		utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("House is already let from %s to %s",
+			overlap.StartDate.Format("2006-01-02"), overlap.OccupiedUntil().Format("2006-01-02")), nil)
+		return
+	}
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to create rental agreement", err)
 		return
 	}
-
-	// Update house status to occupied
-	house.Status = models.StatusOccupied
-	config.DB.Save(&house)
+	services.RecordListingEvents(models.EventAgreement, []uuid.UUID{house.ID}, &agreement.TenantID, agreement.TenantID.String())
 
 	// Load relationships
-	config.DB.Preload("House").Preload("Tenant").First(&agreement, agreement.ID)
+	config.DB.Preload("House").Preload("Tenant").Preload("Tenants.Tenant").First(&agreement, agreement.ID)
 
 	// Create notifications
-	tenantNotification := models.Notification{
-		UserID:  req.TenantID,
-		Title:   "New Rental Agreement",
-		Message: fmt.Sprintf("You have a new rental agreement for %s", house.Title),
-		Type:    "agreement",
-	}
-	config.DB.Create(&tenantNotification)
+	services.NotifyAll(services.AgreementTenantIDs(&agreement), "New Rental Agreement",
+		fmt.Sprintf("You have a new rental agreement for %s", house.Title), "agreement")
 
 	utils.SuccessResponse(c, http.StatusCreated, "Rental agreement created successfully", gin.H{
 		"agreement": agreement,
@@ -174,18 +342,18 @@ func (rh *RentalHandler) GetRentalAgreements(c *gin.Context) {
 	offset := (page - 1) * limit
 
 	// Build query
-	query := config.DB.Model(&models.RentalAgreement{}).Preload("House").Preload("Tenant")
+	query := config.DB.Model(&models.RentalAgreement{}).Preload("House").Preload("Tenant").Preload("Tenants.Tenant")
 
 	// Apply filters based on user role
 	if userModel.Role == models.RoleTenant {
-		query = query.Where("tenant_id = ?", userModel.ID)
+		query = query.Where("rental_agreements.id IN (?)", services.TenantAgreementIDs(userModel.ID))
 	} else if userModel.Role == models.RoleLandlord {
 		query = query.Joins("JOIN houses ON rental_agreements.house_id = houses.id").
 			Where("houses.landlord_id = ?", userModel.ID)
 	}
 
 	if status != "" {
-		query = query.Where("status = ?", status)
+		query = query.Where("rental_agreements.status = ?", status)
 	}
 
 	// Get total count
@@ -194,7 +362,7 @@ func (rh *RentalHandler) GetRentalAgreements(c *gin.Context) {
 
 	// Get agreements
 	var agreements []models.RentalAgreement
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&agreements).Error; err != nil {
+	if err := query.Offset(offset).Limit(limit).Order("rental_agreements.created_at DESC").Find(&agreements).Error; err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to fetch rental agreements", err)
 		return
 	}
@@ -232,7 +400,10 @@ func (rh *RentalHandler) GetRentalAgreement(c *gin.Context) {
 	}
 
 	var agreement models.RentalAgreement
-	if err := config.DB.Preload("House").Preload("Tenant").Preload("Payments").First(&agreement, id).Error; err != nil {
+	if err := config.DB.Preload("House").Preload("Tenant").Preload("Payments").Preload("MoveOutNotices").
+		Preload("Tenants.Tenant").Preload("Escalations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("effective_date ASC")
+	}).First(&agreement, id).Error; err != nil {
 		utils.NotFoundResponse(c, "Rental agreement not found")
 		return
 	}
@@ -241,7 +412,7 @@ func (rh *RentalHandler) GetRentalAgreement(c *gin.Context) {
 	hasAccess := false
 	if userModel.Role == models.RoleAdmin {
 		hasAccess = true
-	} else if userModel.Role == models.RoleTenant && agreement.TenantID == userModel.ID {
+	} else if userModel.Role == models.RoleTenant && services.IsAgreementTenant(&agreement, userModel.ID) {
 		hasAccess = true
 	} else if userModel.Role == models.RoleLandlord && agreement.House.LandlordID == userModel.ID {
 		hasAccess = true
@@ -252,8 +423,36 @@ func (rh *RentalHandler) GetRentalAgreement(c *gin.Context) {
 		return
 	}
 
+	// Summarise what each tenant has paid against their share
+	var paidByTenant []struct {
+		TenantID uuid.UUID
+		Paid     float64
+	}
+	config.DB.Model(&models.Payment{}).
+		Where("agreement_id = ? AND status = ? AND tenant_id IS NOT NULL", agreement.ID, models.PaymentStatusCompleted).
+		Select("tenant_id, COALESCE(SUM(amount), 0) as paid").
+		Group("tenant_id").
+		Scan(&paidByTenant)
+
+	paid := make(map[uuid.UUID]float64, len(paidByTenant))
+	for _, row := range paidByTenant {
+		paid[row.TenantID] = row.Paid
+	}
+
+	tenantPayments := make([]gin.H, 0, len(agreement.Tenants))
+	for _, tenant := range agreement.Tenants {
+		tenantPayments = append(tenantPayments, gin.H{
+			"tenant_id":  tenant.TenantID,
+			"rent_share": tenant.RentShare,
+			"is_primary": tenant.IsPrimary,
+			"total_paid": paid[tenant.TenantID],
+		})
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, "Rental agreement retrieved successfully", gin.H{
-		"agreement": agreement,
+		"agreement":       agreement,
+		"current_rent":    services.RentOn(config.DB, &agreement, time.Now()),
+		"tenant_payments": tenantPayments,
 	})
 }
 
@@ -296,7 +495,7 @@ func (rh *RentalHandler) UpdateRentalAgreement(c *gin.Context) {
 	}
 
 	var req struct {
-		Status string `json:"status" binding:"oneof=active terminated expired"`
+		Status string `json:"status" binding:"oneof=upcoming active terminated expired"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -306,23 +505,32 @@ func (rh *RentalHandler) UpdateRentalAgreement(c *gin.Context) {
 		return
 	}
 
-	// Update agreement status
+	// Update agreement status and record the change as a new version
+	previousStatus := agreement.Status
 	agreement.Status = models.AgreementStatus(req.Status)
 	agreement.UpdatedAt = time.Now()
 
-	if err := config.DB.Save(&agreement).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&agreement).Error; err != nil {
+			return err
+		}
+		if previousStatus == agreement.Status {
+			return nil
+		}
+		if err := services.RecordAgreementChange(tx, agreement.ID, &userModel.ID, models.FieldChanges{
+			{Field: models.FieldStatus, From: previousStatus, To: agreement.Status},
+		}, "Status updated"); err != nil {
+			return err
+		}
+
+		// Recompute the house status and availability from its remaining agreements
+		return services.RefreshHouseAvailability(tx, agreement.HouseID)
+	})
+	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to update rental agreement", err)
 		return
 	}
 
-	// If agreement is terminated or expired, update house status to available
-	if agreement.Status == models.AgreementStatusTerminated || agreement.Status == models.AgreementStatusExpired {
-		var house models.House
-		config.DB.First(&house, agreement.HouseID)
-		house.Status = models.StatusAvailable
-		config.DB.Save(&house)
-	}
-
 	// Load relationships
 	config.DB.Preload("House").Preload("Tenant").First(&agreement, agreement.ID)
 
@@ -332,6 +540,19 @@ func (rh *RentalHandler) UpdateRentalAgreement(c *gin.Context) {
 }
 
 // TerminateRentalAgreement handles terminating a rental agreement
+// @Summary Terminate rental agreement
+// @Description Terminate a rental agreement immediately, or schedule the termination for a future date (landlord or admin only)
+// @Tags Rentals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param request body TerminateRentalAgreementRequest false "Optional termination date"
+// @Success 200 {object} map[string]interface{} "Rental agreement terminated or termination scheduled"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Router /rentals/{id}/terminate [put]
 func (rh *RentalHandler) TerminateRentalAgreement(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -369,37 +590,713 @@ func (rh *RentalHandler) TerminateRentalAgreement(c *gin.Context) {
 		return
 	}
 
-	// Check if agreement is active
-	if agreement.Status != models.AgreementStatusActive {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Only active agreements can be terminated", nil)
+	// Check if agreement is active or a pre-lease that has not started yet
+	if agreement.Status != models.AgreementStatusActive && agreement.Status != models.AgreementStatusUpcoming {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only active or upcoming agreements can be terminated", nil)
 		return
 	}
 
-	// Terminate agreement
-	agreement.Status = models.AgreementStatusTerminated
-	agreement.UpdatedAt = time.Now()
+	// The request body is optional for immediate terminations
+	var req TerminateRentalAgreementRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	// Schedule the termination if a future date was given
+	if req.TerminationDate != "" {
+		terminationDate, err := time.Parse("2006-01-02", req.TerminationDate)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid termination date format", err)
+			return
+		}
+
+		if terminationDate.After(time.Now()) {
+			change := models.FieldChange{
+				Field: models.FieldTerminationDate,
+				From:  services.FormatOptionalDate(agreement.TerminationDate),
+				To:    req.TerminationDate,
+			}
+			agreement.TerminationDate = &terminationDate
+			agreement.UpdatedAt = time.Now()
+
+			err := config.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Omit(clause.Associations).Save(&agreement).Error; err != nil {
+					return err
+				}
+				if err := services.RecordAgreementChange(tx, agreement.ID, &userModel.ID,
+					models.FieldChanges{change}, "Termination scheduled by landlord"); err != nil {
+					return err
+				}
+				return services.RefreshHouseAvailability(tx, agreement.HouseID)
+			})
+			if err != nil {
+				utils.InternalServerErrorResponse(c, "Failed to schedule termination", err)
+				return
+			}
+
+			// Create notifications for tenants
+			services.NotifyAll(services.AgreementTenantIDs(&agreement), "Rental Agreement Termination Scheduled",
+				fmt.Sprintf("Your rental agreement for %s will terminate on %s", agreement.House.Title, req.TerminationDate),
+				"agreement")
+
+			utils.SuccessResponse(c, http.StatusOK, "Rental agreement termination scheduled successfully", gin.H{
+				"agreement": agreement,
+			})
+			return
+		}
+	}
 
-	if err := config.DB.Save(&agreement).Error; err != nil {
+	// Terminate agreement
+	if err := rh.rentalService.TerminateAgreement(&agreement, &userModel.ID); err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to terminate rental agreement", err)
 		return
 	}
 
-	// Update house status to available
-	var house models.House
-	config.DB.First(&house, agreement.HouseID)
-	house.Status = models.StatusAvailable
-	config.DB.Save(&house)
+	// Create notifications for tenants
+	services.NotifyAll(services.AgreementTenantIDs(&agreement), "Rental Agreement Terminated",
+		fmt.Sprintf("Your rental agreement for %s has been terminated", agreement.House.Title), "agreement")
+
+	utils.SuccessResponse(c, http.StatusOK, "Rental agreement terminated successfully", gin.H{
+		"agreement": agreement,
+	})
+}
 
-	// Create notification for tenant
-	notification := models.Notification{
-		UserID:  agreement.TenantID,
-		Title:   "Rental Agreement Terminated",
-		Message: fmt.Sprintf("Your rental agreement for %s has been terminated", house.Title),
-		Type:    "agreement",
+// GiveMoveOutNotice handles a tenant giving notice to move out
+// @Summary Give move-out notice
+// @Description Give notice to vacate the house on a proposed move-out date. The date must respect the agreement's notice period.
+// @Tags Rentals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param request body MoveOutNoticeRequest true "Move-out notice details"
+// @Success 201 {object} map[string]interface{} "Move-out notice submitted successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement not found"
+// @Failure 409 {object} map[string]interface{} "A notice is already open"
+// @Router /rentals/{id}/notices [post]
+func (rh *RentalHandler) GiveMoveOutNotice(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
 	}
-	config.DB.Create(&notification)
 
-	utils.SuccessResponse(c, http.StatusOK, "Rental agreement terminated successfully", gin.H{
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return
+	}
+
+	var req MoveOutNoticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	moveOutDate, err := time.Parse("2006-01-02", req.MoveOutDate)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid move-out date format", err)
+		return
+	}
+
+	// Get agreement
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return
+	}
+
+	// Only a tenant on the agreement can give notice
+	if !services.IsAgreementTenant(&agreement, userModel.ID) {
+		utils.ForbiddenResponse(c, "Only a tenant on this agreement can give notice")
+		return
+	}
+
+	if agreement.Status != models.AgreementStatusActive {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Notice can only be given on active agreements", nil)
+		return
+	}
+
+	// Check the proposed date against the notice period
+	today := time.Now().Truncate(24 * time.Hour)
+	earliest := today.AddDate(0, 0, agreement.NoticePeriodDays)
+	if moveOutDate.Before(earliest) {
+		utils.ErrorResponse(c, http.StatusBadRequest,
+			fmt.Sprintf("This agreement requires %d days notice; the earliest move-out date is %s",
+				agreement.NoticePeriodDays, earliest.Format("2006-01-02")), nil)
+		return
+	}
+	if moveOutDate.After(agreement.EndDate) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Move-out date must be on or before the agreement end date", nil)
+		return
+	}
+
+	// Only one open notice is allowed per agreement
+	var openNotices int64
+	config.DB.Model(&models.MoveOutNotice{}).
+		Where("agreement_id = ? AND status IN ?", agreement.ID,
+			[]models.NoticeStatus{models.NoticeStatusPending, models.NoticeStatusAcknowledged}).
+		Count(&openNotices)
+	if openNotices > 0 {
+		utils.ErrorResponse(c, http.StatusConflict, "A move-out notice is already open for this agreement", nil)
+		return
+	}
+
+	notice := models.MoveOutNotice{
+		AgreementID: agreement.ID,
+		TenantID:    userModel.ID,
+		NoticeDate:  time.Now(),
+		MoveOutDate: moveOutDate,
+		Reason:      req.Reason,
+		Status:      models.NoticeStatusPending,
+	}
+
+	if err := config.DB.Create(&notice).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to submit move-out notice", err)
+		return
+	}
+
+	// Notify both parties
+	services.Notify(agreement.House.LandlordID, "Move-Out Notice Received",
+		fmt.Sprintf("%s has given notice to move out of %s on %s", userModel.FullName, agreement.House.Title, req.MoveOutDate),
+		"agreement")
+	services.NotifyAll(services.AgreementTenantIDs(&agreement), "Move-Out Notice Submitted",
+		fmt.Sprintf("%s gave notice to move out of %s on %s", userModel.FullName, agreement.House.Title, req.MoveOutDate),
+		"agreement")
+
+	utils.SuccessResponse(c, http.StatusCreated, "Move-out notice submitted successfully", gin.H{
+		"notice": notice,
+	})
+}
+
+// GetMoveOutNotices handles getting the move-out notices for an agreement
+func (rh *RentalHandler) GetMoveOutNotices(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return
+	}
+
+	// Check if user has access to this agreement
+	hasAccess := false
+	if userModel.Role == models.RoleAdmin {
+		hasAccess = true
+	} else if userModel.Role == models.RoleTenant && services.IsAgreementTenant(&agreement, userModel.ID) {
+		hasAccess = true
+	} else if userModel.Role == models.RoleLandlord && agreement.House.LandlordID == userModel.ID {
+		hasAccess = true
+	}
+
+	if !hasAccess {
+		utils.ForbiddenResponse(c, "You don't have access to this agreement")
+		return
+	}
+
+	var notices []models.MoveOutNotice
+	if err := config.DB.Where("agreement_id = ?", agreement.ID).Order("created_at DESC").Find(&notices).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch move-out notices", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Move-out notices retrieved successfully", gin.H{
+		"notices": notices,
+	})
+}
+
+// AcknowledgeMoveOutNotice handles a landlord acknowledging a move-out notice
+// @Summary Acknowledge move-out notice
+// @Description Acknowledge a tenant's move-out notice and schedule the agreement termination for the move-out date
+// @Tags Rentals
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param noticeId path string true "Notice ID"
+// @Success 200 {object} map[string]interface{} "Move-out notice acknowledged successfully"
+// @Failure 400 {object} map[string]interface{} "Notice is not pending"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Notice not found"
+// @Router /rentals/{id}/notices/{noticeId}/acknowledge [put]
+func (rh *RentalHandler) AcknowledgeMoveOutNotice(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	notice, ok := rh.loadMoveOutNotice(c)
+	if !ok {
+		return
+	}
+
+	// Only the landlord or an admin can acknowledge
+	if notice.Agreement.House.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "Only the landlord can acknowledge this notice")
+		return
+	}
+
+	if notice.Status != models.NoticeStatusPending {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only pending notices can be acknowledged", nil)
+		return
+	}
+
+	now := time.Now()
+	notice.Status = models.NoticeStatusAcknowledged
+	notice.AcknowledgedAt = &now
+	notice.AcknowledgedByID = &userModel.ID
+	notice.PreviousTerminationDate = notice.Agreement.TerminationDate
+
+	agreement := notice.Agreement
+	change := models.FieldChange{
+		Field: models.FieldTerminationDate,
+		From:  services.FormatOptionalDate(agreement.TerminationDate),
+		To:    notice.MoveOutDate.Format("2006-01-02"),
+	}
+	agreement.TerminationDate = &notice.MoveOutDate
+	agreement.UpdatedAt = now
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&notice).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&agreement).Error; err != nil {
+			return err
+		}
+		if err := services.RecordAgreementChange(tx, agreement.ID, &userModel.ID,
+			models.FieldChanges{change}, "Move-out notice acknowledged"); err != nil {
+			return err
+		}
+		return services.RefreshHouseAvailability(tx, agreement.HouseID)
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to acknowledge move-out notice", err)
+		return
+	}
+
+	services.NotifyAll(services.AgreementTenantIDs(&agreement), "Move-Out Notice Acknowledged",
+		fmt.Sprintf("The landlord acknowledged the move-out notice. The agreement for %s will terminate on %s",
+			agreement.House.Title, notice.MoveOutDate.Format("2006-01-02")),
+		"agreement")
+
+	utils.SuccessResponse(c, http.StatusOK, "Move-out notice acknowledged successfully", gin.H{
+		"notice":    notice,
 		"agreement": agreement,
 	})
 }
+
+// WithdrawMoveOutNotice handles a tenant withdrawing a move-out notice
+func (rh *RentalHandler) WithdrawMoveOutNotice(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	notice, ok := rh.loadMoveOutNotice(c)
+	if !ok {
+		return
+	}
+
+	if notice.TenantID != userModel.ID {
+		utils.ForbiddenResponse(c, "Only the tenant who gave this notice can withdraw it")
+		return
+	}
+
+	if notice.Status != models.NoticeStatusPending && notice.Status != models.NoticeStatusAcknowledged {
+		utils.ErrorResponse(c, http.StatusBadRequest, "This notice can no longer be withdrawn", nil)
+		return
+	}
+
+	wasAcknowledged := notice.Status == models.NoticeStatusAcknowledged
+	notice.Status = models.NoticeStatusWithdrawn
+
+	agreement := notice.Agreement
+	var overlap *models.RentalAgreement
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&notice).Error; err != nil {
+			return err
+		}
+		// Restore the termination date the agreement had before this notice, unless the termination
+		// has been rescheduled since or the house has been pre-leased from the move-out date
+		if wasAcknowledged && agreement.TerminationDate != nil && agreement.TerminationDate.Equal(notice.MoveOutDate) {
+			if err := services.LockHouse(tx, agreement.HouseID); err != nil {
+				return err
+			}
+			change := models.FieldChange{
+				Field: models.FieldTerminationDate,
+				From:  services.FormatOptionalDate(agreement.TerminationDate),
+				To:    services.FormatOptionalDate(notice.PreviousTerminationDate),
+			}
+			agreement.TerminationDate = notice.PreviousTerminationDate
+			agreement.UpdatedAt = time.Now()
+
+			var err error
+			overlap, err = services.FindOverlappingAgreement(tx, agreement.HouseID, agreement.StartDate, agreement.OccupiedUntil(), &agreement.ID)
+			if err != nil {
+				return err
+			}
+			if overlap != nil {
+				return errAgreementOverlap
+			}
+
+			if err := tx.Omit(clause.Associations).Save(&agreement).Error; err != nil {
+				return err
+			}
+			if err := services.RecordAgreementChange(tx, agreement.ID, &userModel.ID,
+				models.FieldChanges{change}, "Move-out notice withdrawn"); err != nil {
+				return err
+			}
+			return services.RefreshHouseAvailability(tx, agreement.HouseID)
+		}
+		return nil
+	})
+	if errors.Is(err, errAgreementOverlap) {
+		utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("The notice can no longer be withdrawn because the house has been let from %s",
+			overlap.StartDate.Format("2006-01-02")), nil)
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to withdraw move-out notice", err)
+		return
+	}
+
+	services.Notify(agreement.House.LandlordID, "Move-Out Notice Withdrawn",
+		fmt.Sprintf("%s has withdrawn their notice to move out of %s", userModel.FullName, agreement.House.Title),
+		"agreement")
+
+	utils.SuccessResponse(c, http.StatusOK, "Move-out notice withdrawn successfully", gin.H{
+		"notice": notice,
+	})
+}
+
+// loadMoveOutNotice loads the notice identified by the route parameters together with its agreement and house
+func (rh *RentalHandler) loadMoveOutNotice(c *gin.Context) (*models.MoveOutNotice, bool) {
+	agreementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return nil, false
+	}
+
+	noticeID, err := uuid.Parse(c.Param("noticeId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid notice ID", err)
+		return nil, false
+	}
+
+	var notice models.MoveOutNotice
+	if err := config.DB.Preload("Agreement.House").
+		Where("id = ? AND agreement_id = ?", noticeID, agreementID).
+		First(&notice).Error; err != nil {
+		utils.NotFoundResponse(c, "Move-out notice not found")
+		return nil, false
+	}
+
+	return &notice, true
+}
+
+// AddCoTenant handles adding a co-tenant to a rental agreement
+// @Summary Add co-tenant
+// @Description Add a tenant to an existing agreement. The co-tenant's rent share is taken from the primary tenant's share.
+// @Tags Rentals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Agreement ID"
+// @Param request body CoTenantRequest true "Co-tenant details"
+// @Success 201 {object} map[string]interface{} "Co-tenant added successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Rental agreement or tenant not found"
+// @Failure 409 {object} map[string]interface{} "Tenant already on agreement"
+// @Router /rentals/{id}/tenants [post]
+func (rh *RentalHandler) AddCoTenant(c *gin.Context) {
+	agreement, ok := rh.loadManagedAgreement(c)
+	if !ok {
+		return
+	}
+
+	var req CoTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if agreement.Status != models.AgreementStatusActive {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Co-tenants can only be added to active agreements", nil)
+		return
+	}
+
+	var tenant models.User
+	if err := config.DB.Where("id = ? AND role = ?", req.TenantID, models.RoleTenant).First(&tenant).Error; err != nil {
+		utils.NotFoundResponse(c, "Tenant not found")
+		return
+	}
+
+	if services.IsAgreementTenant(agreement, req.TenantID) {
+		utils.ErrorResponse(c, http.StatusConflict, "Tenant is already on this agreement", nil)
+		return
+	}
+
+	primary, err := rh.primaryTenantShare(agreement)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to load tenant shares", err)
+		return
+	}
+	if req.RentShare > primary.RentShare {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Rent share exceeds the primary tenant's remaining share", nil)
+		return
+	}
+
+	coTenant := models.AgreementTenant{
+		AgreementID: agreement.ID,
+		TenantID:    req.TenantID,
+		RentShare:   req.RentShare,
+	}
+	primary.RentShare -= req.RentShare
+
+	actorID := c.MustGet("user").(models.User).ID
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		before := services.AgreementTenantShares(tx, agreement)
+		if err := tx.Save(primary).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&coTenant).Error; err != nil {
+			return err
+		}
+		return services.RecordAgreementChange(tx, agreement.ID, &actorID, models.FieldChanges{
+			{Field: models.FieldTenants, From: before, To: services.AgreementTenantShares(tx, agreement)},
+		}, "Co-tenant added")
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to add co-tenant", err)
+		return
+	}
+
+	services.Notify(req.TenantID, "Added to Rental Agreement",
+		fmt.Sprintf("You have been added as a co-tenant on the rental agreement for %s", agreement.House.Title),
+		"agreement")
+
+	config.DB.Preload("Tenant").First(&coTenant, coTenant.ID)
+
+	utils.SuccessResponse(c, http.StatusCreated, "Co-tenant added successfully", gin.H{
+		"tenant": coTenant,
+	})
+}
+
+// RemoveCoTenant handles removing a co-tenant from a rental agreement
+func (rh *RentalHandler) RemoveCoTenant(c *gin.Context) {
+	agreement, ok := rh.loadManagedAgreement(c)
+	if !ok {
+		return
+	}
+
+	tenantID, err := uuid.Parse(c.Param("tenantId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid tenant ID", err)
+		return
+	}
+
+	if tenantID == agreement.TenantID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The primary tenant cannot be removed from an agreement", nil)
+		return
+	}
+
+	var coTenant models.AgreementTenant
+	if err := config.DB.Where("agreement_id = ? AND tenant_id = ?", agreement.ID, tenantID).First(&coTenant).Error; err != nil {
+		utils.NotFoundResponse(c, "Co-tenant not found on this agreement")
+		return
+	}
+
+	primary, err := rh.primaryTenantShare(agreement)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to load tenant shares", err)
+		return
+	}
+
+	// The removed co-tenant's share goes back to the primary tenant
+	primary.RentShare += coTenant.RentShare
+
+	actorID := c.MustGet("user").(models.User).ID
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		before := services.AgreementTenantShares(tx, agreement)
+		if err := tx.Save(primary).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&coTenant).Error; err != nil {
+			return err
+		}
+		return services.RecordAgreementChange(tx, agreement.ID, &actorID, models.FieldChanges{
+			{Field: models.FieldTenants, From: before, To: services.AgreementTenantShares(tx, agreement)},
+		}, "Co-tenant removed")
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to remove co-tenant", err)
+		return
+	}
+
+	services.Notify(tenantID, "Removed from Rental Agreement",
+		fmt.Sprintf("You have been removed from the rental agreement for %s", agreement.House.Title),
+		"agreement")
+
+	utils.SuccessResponse(c, http.StatusOK, "Co-tenant removed successfully", nil)
+}
+
+// UpdateRentShares handles redistributing the rent between the tenants on an agreement
+func (rh *RentalHandler) UpdateRentShares(c *gin.Context) {
+	agreement, ok := rh.loadManagedAgreement(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRentSharesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if _, err := rh.primaryTenantShare(agreement); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to load tenant shares", err)
+		return
+	}
+
+	var tenants []models.AgreementTenant
+	config.DB.Where("agreement_id = ?", agreement.ID).Find(&tenants)
+
+	shares := make(map[uuid.UUID]float64, len(req.Shares))
+	total := 0.0
+	for _, share := range req.Shares {
+		shares[share.TenantID] = share.RentShare
+		total += share.RentShare
+	}
+
+	// Every tenant must be given a share and the shares must add up to the rent
+	if len(shares) != len(tenants) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "A share must be given for every tenant on the agreement", nil)
+		return
+	}
+	for _, tenant := range tenants {
+		if _, ok := shares[tenant.TenantID]; !ok {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Missing share for tenant %s", tenant.TenantID), nil)
+			return
+		}
+	}
+	if math.Abs(total-agreement.RentAmount) > 0.01 {
+		utils.ErrorResponse(c, http.StatusBadRequest,
+			fmt.Sprintf("Rent shares must add up to the rent amount of %.2f", agreement.RentAmount), nil)
+		return
+	}
+
+	actorID := c.MustGet("user").(models.User).ID
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		before := services.AgreementTenantShares(tx, agreement)
+		for i := range tenants {
+			tenants[i].RentShare = shares[tenants[i].TenantID]
+			if err := tx.Save(&tenants[i]).Error; err != nil {
+				return err
+			}
+		}
+		return services.RecordAgreementChange(tx, agreement.ID, &actorID, models.FieldChanges{
+			{Field: models.FieldTenants, From: before, To: services.AgreementTenantShares(tx, agreement)},
+		}, "Rent shares updated")
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update rent shares", err)
+		return
+	}
+
+	services.NotifyAll(services.AgreementTenantIDs(agreement), "Rent Shares Updated",
+		fmt.Sprintf("The rent shares for %s have been updated", agreement.House.Title), "agreement")
+
+	config.DB.Preload("Tenant").Where("agreement_id = ?", agreement.ID).Find(&tenants)
+
+	utils.SuccessResponse(c, http.StatusOK, "Rent shares updated successfully", gin.H{
+		"tenants": tenants,
+	})
+}
+
+// loadManagedAgreement loads the agreement from the route and checks the user is its landlord or an admin
+func (rh *RentalHandler) loadManagedAgreement(c *gin.Context) (*models.RentalAgreement, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, false
+	}
+
+	userModel := user.(models.User)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid agreement ID", err)
+		return nil, false
+	}
+
+	var agreement models.RentalAgreement
+	if err := config.DB.Preload("House").First(&agreement, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Rental agreement not found")
+		return nil, false
+	}
+
+	if agreement.House.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You don't have access to this agreement")
+		return nil, false
+	}
+
+	return &agreement, true
+}
+
+// primaryTenantShare returns the primary tenant's share row, creating it for agreements
+// that were signed before co-tenants were supported
+func (rh *RentalHandler) primaryTenantShare(agreement *models.RentalAgreement) (*models.AgreementTenant, error) {
+	primary := models.AgreementTenant{
+		AgreementID: agreement.ID,
+		TenantID:    agreement.TenantID,
+	}
+
+	var coTenantShares float64
+	config.DB.Model(&models.AgreementTenant{}).
+		Where("agreement_id = ? AND tenant_id <> ?", agreement.ID, agreement.TenantID).
+		Select("COALESCE(SUM(rent_share), 0)").
+		Scan(&coTenantShares)
+
+	err := config.DB.Where(models.AgreementTenant{AgreementID: agreement.ID, TenantID: agreement.TenantID}).
+		Attrs(models.AgreementTenant{RentShare: agreement.RentAmount - coTenantShares, IsPrimary: true}).
+		FirstOrCreate(&primary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &primary, nil
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errDuplicateReport is returned when the user already has an open report of the listing
+var errDuplicateReport = errors.New("listing already reported")
+
+// ReportHandler handles reports of suspicious listings
+type ReportHandler struct{}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{}
+}
+
+// ReportHouseRequest represents the request structure for reporting a listing
+type ReportHouseRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=fraudulent duplicate misleading unavailable offensive other"`
+	Details string `json:"details" binding:"max=2000"` // required when the reason is other
+}
+
+// ResolveReportsRequest represents the request structure for resolving a listing's reports.
+// The note is sent to the landlord and is required to warn them. RejectionReason is used when
+// the listing is removed and defaults to suspected_scam.
+type ResolveReportsRequest struct {
+	Action          string `json:"action" binding:"required,oneof=dismiss warn_landlord remove_listing deactivate_user"`
+	Note            string `json:"note" binding:"max=1000"`
+	RejectionReason string `json:"rejection_reason" binding:"omitempty,oneof=suspected_scam misleading duplicate poor_photos incomplete prohibited other"`
+}
+
+// reportedListing is a listing with open reports in the report queue
+type reportedListing struct {
+	HouseID         uuid.UUID              `json:"-"`
+	ReportCount     int64                  `json:"report_count"`
+	FirstReportedAt time.Time              `json:"first_reported_at"`
+	LastReportedAt  time.Time              `json:"last_reported_at"`
+	Reasons         map[string]int64       `json:"reasons" gorm:"-"` // open reports by reason
+	House           *models.House          `json:"house" gorm:"-"`
+	Reports         []models.ListingReport `json:"reports" gorm:"-"`
+}
+
+// ReportHouse handles reporting a listing as suspicious
+// @Summary Report house
+// @Description Report a listing as fraudulent, duplicated, misleading or otherwise suspicious. A listing reported by enough different users is hidden until a moderator reviews it.
+// @Tags Houses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body ReportHouseRequest true "Report reason and details"
+// @Success 201 {object} map[string]interface{} "Report submitted successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 409 {object} map[string]interface{} "You have already reported this listing"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /houses/{id}/reports [post]
+func (rh *ReportHandler) ReportHouse(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil || !canViewListing(c, &house) {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+	if house.LandlordID == userModel.ID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "You cannot report your own listing", nil)
+		return
+	}
+
+	var req ReportHouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if models.ReportReason(req.Reason) == models.ReportOther && req.Details == "" {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": "details are required when the reason is other",
+		})
+		return
+	}
+
+	report := models.ListingReport{
+		HouseID:    house.ID,
+		ReporterID: userModel.ID,
+		Reason:     models.ReportReason(req.Reason),
+		Details:    req.Details,
+		Status:     models.ReportStatusOpen,
+	}
+
+	hidden := false
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// A user has one open report per listing, enforced by a partial unique index so that
+		// reports sent twice at once are not both counted
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&report)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errDuplicateReport
+		}
+		hidden, err = services.HideReportedListing(tx, house.ID)
+		return err
+	})
+	if errors.Is(err, errDuplicateReport) {
+		utils.ErrorResponse(c, http.StatusConflict, "You have already reported this listing", nil)
+		return
+	}
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to submit report", err)
+		return
+	}
+
+	if hidden {
+		message := fmt.Sprintf("Your listing \"%s\" has been reported by several users and is hidden until a moderator reviews it.", house.Title)
+		services.Notify(house.LandlordID, "Listing Hidden for Review", message, "listing")
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Report submitted successfully", gin.H{
+		"report": report,
+	})
+}
+
+// GetReportQueue handles getting the listings with open reports, most reported first
+// @Summary Get report queue
+// @Description Get listings with open reports, most reported first, with their reports and a count by reason
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{} "Reported listings retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/listing-reports [get]
+func (rh *ReportHandler) GetReportQueue(c *gin.Context) {
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	groupsQuery := config.DB.Model(&models.ListingReport{}).
+		Select("house_id, COUNT(*) AS report_count, MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at").
+		Where("status = ?", models.ReportStatusOpen).
+		Group("house_id")
+
+	// Get total count of reported listings
+	var total int64
+	config.DB.Table("(?) AS reported", groupsQuery).Count(&total)
+
+	var listings []reportedListing
+	if err := groupsQuery.Order("report_count DESC, first_reported_at ASC").
+		Offset(offset).Limit(limit).Scan(&listings).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch reported listings", err)
+		return
+	}
+
+	houseIDs := make([]uuid.UUID, 0, len(listings))
+	for _, listing := range listings {
+		houseIDs = append(houseIDs, listing.HouseID)
+	}
+
+	// Load the page's listings and their open reports
+	houses := map[uuid.UUID]*models.House{}
+	var reports []models.ListingReport
+	if len(houseIDs) > 0 {
+		var loaded []models.House
+		if err := config.DB.Unscoped().Preload("Landlord").Preload("Images", orderedImages).
+			Where("id IN ?", houseIDs).Find(&loaded).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch reported listings", err)
+			return
+		}
+		for i := range loaded {
+			houses[loaded[i].ID] = &loaded[i]
+		}
+
+		if err := config.DB.Preload("Reporter").
+			Where("house_id IN ? AND status = ?", houseIDs, models.ReportStatusOpen).
+			Order("created_at ASC").Find(&reports).Error; err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to fetch reports", err)
+			return
+		}
+	}
+
+	for i := range listings {
+		listing := &listings[i]
+		listing.House = houses[listing.HouseID]
+		listing.Reasons = map[string]int64{}
+		listing.Reports = []models.ListingReport{}
+		for _, report := range reports {
+			if report.HouseID == listing.HouseID {
+				listing.Reasons[string(report.Reason)]++
+				listing.Reports = append(listing.Reports, report)
+			}
+		}
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	utils.SuccessResponse(c, http.StatusOK, "Reported listings retrieved successfully", gin.H{
+		"listings": listings,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// ResolveReports handles resolving all open reports of a listing
+// @Summary Resolve listing reports
+// @Description Resolve a listing's open reports by dismissing them, warning the landlord, removing the listing or deactivating the landlord. Dismissing republishes a listing the reports hid; deactivating the landlord also removes their other live listings. Reporters are told the outcome.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body ResolveReportsRequest true "Resolution action and note"
+// @Success 200 {object} map[string]interface{} "Reports resolved"
+// @Failure 400 {object} map[string]interface{} "Invalid request data or no open reports"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/listing-reports/{id}/resolve [put]
+func (rh *ReportHandler) ResolveReports(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var req ResolveReportsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if req.Action == "warn_landlord" && req.Note == "" {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": "note is required to warn the landlord",
+		})
+		return
+	}
+	if req.RejectionReason == "" {
+		req.RejectionReason = string(models.RejectionSuspectedScam)
+	}
+
+	var house models.House
+	if err := config.DB.Unscoped().Preload("Landlord").First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	var openReports int64
+	config.DB.Model(&models.ListingReport{}).Where("house_id = ? AND status = ?", house.ID, models.ReportStatusOpen).Count(&openReports)
+	if openReports == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Listing has no open reports", nil)
+		return
+	}
+
+	var resolution models.ReportResolution
+	var reporterIDs []uuid.UUID
+	var removed []models.House // listings taken down, whose landlord is told why
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		houseIDs := []uuid.UUID{house.ID}
+
+		switch req.Action {
+		case "dismiss":
+			resolution = models.ResolutionDismissed
+			if _, err := services.RestoreReportedListing(tx, &house, admin.ID); err != nil {
+				return err
+			}
+
+		case "warn_landlord":
+			resolution = models.ResolutionLandlordWarned
+
+		case "remove_listing":
+			resolution = models.ResolutionListingRemoved
+			if house.ListingStatus != models.ListingRejected {
+				if err := services.SetListingStatus(tx, &house, models.ListingRejected, &admin.ID, req.RejectionReason, req.Note); err != nil {
+					return err
+				}
+				removed = append(removed, house)
+			}
+
+		case "deactivate_user":
+			resolution = models.ResolutionUserDeactivated
+			if err := setUserActive(tx, &house.Landlord, false); err != nil {
+				return err
+			}
+
+			// Take down the landlord's other live listings too, and close their reports
+			var listings []models.House
+			if err := tx.Where("landlord_id = ? AND listing_status IN ?", house.LandlordID,
+				[]models.ListingStatus{models.ListingPublished, models.ListingPendingReview}).Find(&listings).Error; err != nil {
+				return err
+			}
+			for i := range listings {
+				if err := services.SetListingStatus(tx, &listings[i], models.ListingRejected, &admin.ID, req.RejectionReason, req.Note); err != nil {
+					return err
+				}
+				if listings[i].ID != house.ID {
+					houseIDs = append(houseIDs, listings[i].ID)
+				}
+			}
+		}
+
+		reporterIDs, err = services.ResolveListingReports(tx, houseIDs, resolution, admin.ID, req.Note)
+		return err
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to resolve reports", err)
+		return
+	}
+
+	switch resolution {
+	case models.ResolutionLandlordWarned:
+		message := fmt.Sprintf("Your listing \"%s\" has been reported by users. Message from the moderator: %s", house.Title, req.Note)
+		services.Notify(house.LandlordID, "Warning About Your Listing", message, "listing")
+	case models.ResolutionListingRemoved:
+		for i := range removed {
+			notifyListingRejected(&removed[i], req.RejectionReason, req.Note)
+		}
+	}
+
+	message := fmt.Sprintf("Thank you for reporting \"%s\". A moderator has reviewed it and taken action.", house.Title)
+	if resolution == models.ResolutionDismissed {
+		message = fmt.Sprintf("Thank you for reporting \"%s\". A moderator has reviewed it and found no problem.", house.Title)
+	}
+	services.NotifyAll(reporterIDs, "Report Reviewed", message, "listing")
+
+	house.Landlord.PasswordHash = ""
+	utils.SuccessResponse(c, http.StatusOK, "Reports resolved", gin.H{
+		"resolution": resolution,
+		"house":      house,
+	})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bondihub/config"
 	"bondihub/models"
+	"bondihub/services"
 	"bondihub/utils"
 	"fmt"
 	"net/http"
@@ -58,8 +59,8 @@ func (rh *ReviewHandler) CreateReview(c *gin.Context) {
 
 	// Check if user has an active rental agreement for this house
 	var agreement models.RentalAgreement
-	if err := config.DB.Where("house_id = ? AND tenant_id = ? AND status = ?",
-		req.HouseID, userModel.ID, models.AgreementStatusActive).First(&agreement).Error; err != nil {
+	if err := config.DB.Where("house_id = ? AND status = ? AND id IN (?)",
+		req.HouseID, models.AgreementStatusActive, services.TenantAgreementIDs(userModel.ID)).First(&agreement).Error; err != nil {
 		utils.ForbiddenResponse(c, "You can only review houses you have rented")
 		return
 	}
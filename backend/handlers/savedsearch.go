@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSavedSearches caps the number of saved searches a user can keep
+const maxSavedSearches = 20
+
+// SavedSearchHandler handles saved searches and their new-listing alerts
+type SavedSearchHandler struct {
+	savedSearchService *services.SavedSearchService
+}
+
+// NewSavedSearchHandler creates a new saved search handler
+func NewSavedSearchHandler() *SavedSearchHandler {
+	return &SavedSearchHandler{
+		savedSearchService: services.NewSavedSearchService(),
+	}
+}
+
+// SavedSearchRequest represents the request structure for creating or replacing a saved search.
+// The filters work as in GetHouses; a location needs both coordinates, and radius_km defaults to 10.
+type SavedSearchRequest struct {
+	Name           string   `json:"name" binding:"required,max=100"`
+	HouseType      string   `json:"house_type" binding:"omitempty,oneof=apartment house studio townhouse commercial"`
+	MinRent        float64  `json:"min_rent" binding:"min=0"`
+	MaxRent        float64  `json:"max_rent" binding:"min=0"`
+	Bedrooms       int      `json:"bedrooms" binding:"min=0"`
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+	RadiusKm       float64  `json:"radius_km" binding:"min=0"`
+	Amenities      []string `json:"amenities"`
+	Search         string   `json:"search" binding:"max=200"`
+	AlertFrequency string   `json:"alert_frequency" binding:"omitempty,oneof=instant daily off"`
+}
+
+// apply validates the request and copies it onto a saved search. It returns a message describing
+// the first invalid field.
+func (req *SavedSearchRequest) apply(search *models.SavedSearch) string {
+	if req.MaxRent > 0 && req.MaxRent < req.MinRent {
+		return "max_rent must not be below min_rent"
+	}
+
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return "latitude and longitude must be given together"
+	}
+	radiusKm := 0.0
+	if req.Latitude != nil {
+		if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+			return "coordinates are out of range"
+		}
+		radiusKm = req.RadiusKm
+		if radiusKm == 0 {
+			radiusKm = 10
+		}
+		if radiusKm > maxSearchRadiusKm {
+			return fmt.Sprintf("radius_km must be between 0 and %.0f", maxSearchRadiusKm)
+		}
+	}
+
+	amenities := models.StringList{}
+	if len(req.Amenities) > 0 {
+		var count int64
+		config.DB.Model(&models.Amenity{}).Where("slug IN ? AND is_active = ?", req.Amenities, true).Count(&count)
+		unique := map[string]bool{}
+		for _, slug := range req.Amenities {
+			if !unique[slug] {
+				unique[slug] = true
+				amenities = append(amenities, slug)
+			}
+		}
+		if int(count) != len(amenities) {
+			return services.ErrUnknownAmenity.Error()
+		}
+	}
+
+	search.Name = req.Name
+	search.HouseType = models.HouseType(req.HouseType)
+	search.MinRent = req.MinRent
+	search.MaxRent = req.MaxRent
+	search.Bedrooms = req.Bedrooms
+	search.Latitude = req.Latitude
+	search.Longitude = req.Longitude
+	search.RadiusKm = radiusKm
+	search.Amenities = amenities
+	search.Search = req.Search
+	search.AlertFrequency = models.AlertInstant
+	if req.AlertFrequency != "" {
+		search.AlertFrequency = models.AlertFrequency(req.AlertFrequency)
+	}
+	return ""
+}
+
+// CreateSavedSearch handles saving a house search
+// @Summary Save a search
+// @Description Save house search filters to be alerted when a newly published listing matches them, instantly or in a daily digest
+// @Tags Saved Searches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SavedSearchRequest true "Search filters and alert frequency"
+// @Success 201 {object} map[string]interface{} "Search saved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data or too many saved searches"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /saved-searches [post]
+func (ssh *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.SavedSearch{}).Where("user_id = ?", user.ID).Count(&count)
+	if count >= maxSavedSearches {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("You can keep at most %d saved searches", maxSavedSearches), nil)
+		return
+	}
+
+	search := models.SavedSearch{UserID: user.ID}
+	if message := req.apply(&search); message != "" {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": message,
+		})
+		return
+	}
+
+	if err := config.DB.Create(&search).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to save search", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Search saved successfully", gin.H{
+		"saved_search": search,
+	})
+}
+
+// GetSavedSearches handles getting the current user's saved searches
+// @Summary Get saved searches
+// @Description Get the current user's saved searches, newest first
+// @Tags Saved Searches
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Saved searches retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /saved-searches [get]
+func (ssh *SavedSearchHandler) GetSavedSearches(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var searches []models.SavedSearch
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&searches).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch saved searches", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Saved searches retrieved successfully", gin.H{
+		"saved_searches": searches,
+	})
+}
+
+// UpdateSavedSearch handles replacing a saved search's filters and alert frequency
+// @Summary Update saved search
+// @Description Replace a saved search's filters and alert frequency. Listings it already alerted about are not alerted again.
+// @Tags Saved Searches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Saved search ID"
+// @Param request body SavedSearchRequest true "Search filters and alert frequency"
+// @Success 200 {object} map[string]interface{} "Saved search updated successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Saved search not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /saved-searches/{id} [put]
+func (ssh *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	search, ok := ssh.loadSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	frequency := search.AlertFrequency
+	if message := req.apply(search); message != "" {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": message,
+		})
+		return
+	}
+
+	if err := config.DB.Save(search).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to update saved search", err)
+		return
+	}
+
+	// Matches held for the daily digest would never be sent once the search stops alerting daily
+	if frequency == models.AlertDaily && search.AlertFrequency != models.AlertDaily {
+		if err := ssh.savedSearchService.FlushPendingMatches(*search); err != nil {
+			log.Printf("Failed to flush pending matches of saved search %s: %v", search.ID, err)
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Saved search updated successfully", gin.H{
+		"saved_search": search,
+	})
+}
+
+// DeleteSavedSearch handles deleting a saved search
+// @Summary Delete saved search
+// @Description Delete a saved search and stop its alerts
+// @Tags Saved Searches
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Saved search ID"
+// @Success 200 {object} map[string]interface{} "Saved search deleted successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Saved search not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /saved-searches/{id} [delete]
+func (ssh *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	search, ok := ssh.loadSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(search).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to delete saved search", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Saved search deleted successfully", nil)
+}
+
+// GetSavedSearchMatches handles getting the listings a saved search has matched
+// @Summary Get saved search matches
+// @Description Get the published listings a saved search has matched since it was saved, newest first
+// @Tags Saved Searches
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Saved search ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{} "Matches retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Saved search not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /saved-searches/{id}/matches [get]
+func (ssh *SavedSearchHandler) GetSavedSearchMatches(c *gin.Context) {
+	search, ok := ssh.loadSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.House{}).
+		Joins("JOIN saved_search_matches ON saved_search_matches.house_id = houses.id").
+		Where("saved_search_matches.saved_search_id = ? AND houses.listing_status = ?", search.ID, models.ListingPublished)
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	var houses []models.House
+	if err := query.Preload("Images", orderedImages).Preload("Amenities", activeAmenities).
+		Order("saved_search_matches.created_at DESC").Offset(offset).Limit(limit).Find(&houses).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch matches", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := (total + int64(limit) - 1) / int64(limit)
+
+	utils.SuccessResponse(c, http.StatusOK, "Matches retrieved successfully", gin.H{
+		"houses": houses,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// loadSavedSearch loads the current user's saved search in the URL. It writes the error response
+// and returns false when the request cannot continue.
+func (ssh *SavedSearchHandler) loadSavedSearch(c *gin.Context) (*models.SavedSearch, bool) {
+	user := c.MustGet("user").(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid saved search ID", err)
+		return nil, false
+	}
+
+	var search models.SavedSearch
+	if err := config.DB.Where("user_id = ?", user.ID).First(&search, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Saved search not found")
+		return nil, false
+	}
+	return &search, true
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	testDBOnce sync.Once
+	testDBErr  error
+)
+
+// useTestDB points config.DB at the database named by TEST_DATABASE_URL and migrates it. Tests that
+// need a database are skipped when it is not set. Fixtures use fresh IDs, so tests share the database.
+func useTestDB(t *testing.T) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	testDBOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		config.InitConfig()
+		config.DB, testDBErr = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		if testDBErr == nil {
+			config.AutoMigrate()
+		}
+	})
+	if testDBErr != nil {
+		t.Fatalf("connect to test database: %v", testDBErr)
+	}
+}
+
+// createTestUser creates a user with the given role
+func createTestUser(t *testing.T, role models.UserRole) models.User {
+	t.Helper()
+
+	id := uuid.New()
+	user := models.User{
+		ID:           id,
+		FullName:     "Test " + string(role),
+		Email:        id.String() + "@example.com",
+		PasswordHash: "x",
+		Phone:        "+260970000000",
+		Role:         role,
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createTestHouse creates a published house owned by the landlord
+func createTestHouse(t *testing.T, landlord models.User) models.House {
+	t.Helper()
+
+	house := models.House{
+		LandlordID:    landlord.ID,
+		Title:         "Test house",
+		Address:       "Plot 1, Independence Avenue, Lusaka",
+		MonthlyRent:   2500,
+		Status:        models.StatusAvailable,
+		HouseType:     models.TypeHouse,
+		ListingStatus: models.ListingPublished,
+	}
+	if err := config.DB.Create(&house).Error; err != nil {
+		t.Fatalf("create house: %v", err)
+	}
+	return house
+}
+
+// createTestAgreement creates an active agreement for the tenant that started a year before now
+func createTestAgreement(t *testing.T, house models.House, tenant models.User) models.RentalAgreement {
+	t.Helper()
+
+	start := time.Now().AddDate(-1, 0, 0)
+	agreement := models.RentalAgreement{
+		HouseID:    house.ID,
+		TenantID:   tenant.ID,
+		StartDate:  start,
+		EndDate:    start.AddDate(2, 0, 0),
+		RentAmount: house.MonthlyRent,
+		Status:     models.AgreementStatusActive,
+	}
+	if err := config.DB.Create(&agreement).Error; err != nil {
+		t.Fatalf("create agreement: %v", err)
+	}
+	return agreement
+}
+
+// serve runs a single request through handler, routed at pattern, as user when one is given
+func serve(t *testing.T, handler gin.HandlerFunc, method, pattern, path string, user *models.User, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	router := gin.New()
+	router.Handle(method, pattern, func(c *gin.Context) {
+		if user != nil {
+			c.Set("user", *user)
+		}
+		handler(c)
+	})
+
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadHandler accepts direct uploads for the local image storage backend, standing in for the
+// upload endpoints of Cloudinary and S3 in development and tests
+type UploadHandler struct {
+	localStorage *services.LocalStorage
+}
+
+// NewUploadHandler creates a new upload handler
+func NewUploadHandler() *UploadHandler {
+	handler := &UploadHandler{}
+	if config.AppConfig.StorageBackend == "local" {
+		localStorage, err := services.NewLocalStorage()
+		if err != nil {
+			log.Printf("Direct uploads will not work until local storage is available: %v", err)
+		}
+		handler.localStorage = localStorage
+	}
+	return handler
+}
+
+// ReceiveDirectUpload handles a file uploaded with parameters signed for the local storage backend.
+// The form fields are the ones returned by the direct upload endpoints; no other authentication is needed.
+func (uh *UploadHandler) ReceiveDirectUpload(c *gin.Context) {
+	if uh.localStorage == nil {
+		utils.NotFoundResponse(c, "Direct uploads are not served by this API")
+		return
+	}
+
+	maxBytes := int64(config.AppConfig.MaxImageSizeMB)<<20 + multipartOverhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No image file provided", err)
+		return
+	}
+
+	// Fields come before the file, as with storage services' upload forms
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "No image file provided", nil)
+			return
+		}
+		if err != nil {
+			respondImageUploadError(c, err)
+			return
+		}
+
+		if part.FileName() == "" {
+			value, _ := io.ReadAll(io.LimitReader(part, 1024))
+			fields[part.FormName()] = string(value)
+			part.Close()
+			continue
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		err = uh.localStorage.SaveDirectUpload(fields["key"], fields["expires"], fields["signature"], part)
+		part.Close()
+		if err != nil {
+			var invalid *services.ImageValidationError
+			if errors.As(err, &invalid) {
+				utils.ErrorResponse(c, http.StatusBadRequest, invalid.Message, nil)
+				return
+			}
+			utils.ErrorResponse(c, http.StatusForbidden, "Invalid or expired upload signature", err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+}
+
+// multipartOverhead allows for the part headers and boundaries around the files of a multipart upload
+const multipartOverhead = 1 << 20
+
+// batchUploadConcurrency is how many images of a batch upload are processed at the same time
+const batchUploadConcurrency = 4
+
+// receiveImage streams the file in a multipart form field through validation to image storage,
+// without buffering the request in memory. It writes the error response and returns false when
+// the upload fails.
+func receiveImage(c *gin.Context, storage services.ImageStorage, field, folder string) (*services.StoredImage, bool) {
+	if storage == nil {
+		utils.InternalServerErrorResponse(c, "Image upload service is not configured", nil)
+		return nil, false
+	}
+
+	maxBytes := int64(config.AppConfig.MaxImageSizeMB)<<20 + multipartOverhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No image file provided", err)
+		return nil, false
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "No image file provided", nil)
+			return nil, false
+		}
+		if err != nil {
+			respondImageUploadError(c, err)
+			return nil, false
+		}
+		if part.FormName() != field || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		stored, err := services.UploadImage(c.Request.Context(), storage, part, folder)
+		part.Close()
+		if err != nil {
+			respondImageUploadError(c, err)
+			return nil, false
+		}
+		return stored, true
+	}
+}
+
+// receivedImages are the files of a batch upload, with a result for each file in the order it was sent
+type receivedImages struct {
+	Results []services.ImageUploadResult
+	// Err is set when the request was cut off, e.g. for being too large. Files sent after the last
+	// result were not received.
+	Err error
+}
+
+// IncompleteMessage describes why the files after the last result were not received, or returns ""
+// when the whole request was read
+func (ri *receivedImages) IncompleteMessage() string {
+	if ri.Err == nil {
+		return ""
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(ri.Err, &tooLarge) {
+		return "Upload is too large; files after the last result were not received"
+	}
+	return "Upload was interrupted; files after the last result were not received"
+}
+
+// receiveImages streams every file in a multipart form field to image storage, processing up to
+// batchUploadConcurrency files at a time. Files after the first maxFiles are rejected unread, and the
+// request may only be as large as maxFiles images. Each file gets a result in the order it was sent; a
+// file failing validation does not stop the others. It writes the error response and returns false
+// when the request itself cannot be processed.
+func receiveImages(c *gin.Context, storage services.ImageStorage, field, folder string, maxFiles int) (*receivedImages, bool) {
+	if storage == nil {
+		utils.InternalServerErrorResponse(c, "Image upload service is not configured", nil)
+		return nil, false
+	}
+
+	maxBytes := int64(config.AppConfig.MaxImageSizeMB)<<20*int64(maxFiles) + multipartOverhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No image files provided", err)
+		return nil, false
+	}
+
+	batch := services.NewImageBatch(c.Request.Context(), storage, folder, batchUploadConcurrency)
+	files := 0
+	var readErr error
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		if part.FormName() != field || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		files++
+		if files > maxFiles {
+			batch.Fail(part.FileName(), &services.ImageValidationError{
+				Message: fmt.Sprintf("A house can have at most %d images", config.AppConfig.MaxHouseImages),
+			})
+			part.Close()
+			continue
+		}
+
+		// Parts must be read in order, so each file is spooled to disk before it is processed
+		spool, err := services.SpoolImage(part)
+		part.Close()
+		if err != nil {
+			batch.Fail(part.FileName(), err)
+			continue
+		}
+		batch.Add(part.FileName(), spool)
+	}
+
+	results := batch.Wait()
+	if readErr != nil && len(results) == 0 {
+		respondImageUploadError(c, readErr)
+		return nil, false
+	}
+	if len(results) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No image files provided", nil)
+		return nil, false
+	}
+	return &receivedImages{Results: results, Err: readErr}, true
+}
+
+// imageUploadErrorMessage describes why one file of a batch upload failed
+func imageUploadErrorMessage(err error) string {
+	var invalid *services.ImageValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Message
+	}
+	return "Failed to upload image"
+}
+
+// respondImageUploadError reports a failed image upload: rejected images and oversized requests are
+// the uploader's fault, anything else is ours
+func respondImageUploadError(c *gin.Context, err error) {
+	var invalid *services.ImageValidationError
+	if errors.As(err, &invalid) {
+		utils.ErrorResponse(c, http.StatusBadRequest, invalid.Message, nil)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Upload is too large", nil)
+		return
+	}
+	utils.InternalServerErrorResponse(c, "Failed to upload image", err)
+}
@@ -0,0 +1,679 @@
+package handlers
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bondihub/services"
+	"bondihub/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errSlotNotFound = errors.New("viewing slot not found")
+	errSlotStarted  = errors.New("this viewing slot has already started")
+	errSlotBooked   = errors.New("this viewing slot is already booked")
+	errSlotMismatch = errors.New("viewings can only be moved to another slot of the same house")
+	errViewingTaken = errors.New("you already have a viewing booked for this house")
+	errListingGone  = errors.New("the house is not listed")
+)
+
+// ViewingHandler handles viewing appointment requests
+type ViewingHandler struct{}
+
+// NewViewingHandler creates a new viewing handler
+func NewViewingHandler() *ViewingHandler {
+	return &ViewingHandler{}
+}
+
+// ViewingSlotRequest represents a single availability window
+type ViewingSlotRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+}
+
+// CreateViewingSlotsRequest represents the request structure for publishing viewing slots
+type CreateViewingSlotsRequest struct {
+	Slots []ViewingSlotRequest `json:"slots" binding:"required,min=1,max=50,dive"`
+}
+
+// BookViewingRequest represents the request structure for booking a viewing
+type BookViewingRequest struct {
+	SlotID uuid.UUID `json:"slot_id" binding:"required"`
+	Notes  string    `json:"notes" binding:"max=500"`
+}
+
+// RescheduleViewingRequest represents the request structure for moving a viewing to another slot
+type RescheduleViewingRequest struct {
+	SlotID uuid.UUID `json:"slot_id" binding:"required"`
+}
+
+// CreateViewingSlots handles publishing availability slots for a house
+// @Summary Publish viewing slots
+// @Description Publish one or more time windows in which prospective tenants can book a viewing of the house
+// @Tags Viewings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "House ID"
+// @Param request body CreateViewingSlotsRequest true "Viewing slots"
+// @Success 201 {object} map[string]interface{} "Viewing slots created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "House not found"
+// @Router /houses/{id}/viewing-slots [post]
+func (vh *ViewingHandler) CreateViewingSlots(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	if house.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only publish viewing slots for your own houses")
+		return
+	}
+
+	var req CreateViewingSlotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	slots := make([]models.ViewingSlot, 0, len(req.Slots))
+	for i, slotReq := range req.Slots {
+		if !slotReq.EndTime.After(slotReq.StartTime) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Slot %d must end after it starts", i+1), nil)
+			return
+		}
+		if !slotReq.StartTime.After(now) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Slot %d must start in the future", i+1), nil)
+			return
+		}
+
+		// Slots for the same house may not overlap each other
+		for j := 0; j < i; j++ {
+			if slotReq.StartTime.Before(req.Slots[j].EndTime) && req.Slots[j].StartTime.Before(slotReq.EndTime) {
+				utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Slots %d and %d overlap", j+1, i+1), nil)
+				return
+			}
+		}
+
+		var overlapping int64
+		config.DB.Model(&models.ViewingSlot{}).
+			Where("house_id = ? AND start_time < ? AND end_time > ?", house.ID, slotReq.EndTime, slotReq.StartTime).
+			Count(&overlapping)
+		if overlapping > 0 {
+			utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("Slot %d overlaps an existing viewing slot", i+1), nil)
+			return
+		}
+
+		slots = append(slots, models.ViewingSlot{
+			HouseID:   house.ID,
+			StartTime: slotReq.StartTime,
+			EndTime:   slotReq.EndTime,
+		})
+	}
+
+	if err := config.DB.Create(&slots).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to create viewing slots", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Viewing slots created successfully", gin.H{
+		"slots": slots,
+	})
+}
+
+// GetViewingSlots handles getting the upcoming viewing slots of a house
+func (vh *ViewingHandler) GetViewingSlots(c *gin.Context) {
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid house ID", err)
+		return
+	}
+
+	var house models.House
+	if err := config.DB.First(&house, id).Error; err != nil || !canViewListing(c, &house) {
+		utils.NotFoundResponse(c, "House not found")
+		return
+	}
+
+	bookedSlots := config.DB.Model(&models.Viewing{}).
+		Select("slot_id").
+		Where("status = ?", models.ViewingStatusBooked)
+
+	query := config.DB.Where("house_id = ? AND start_time > ?", house.ID, time.Now())
+	if c.Query("available") == "true" {
+		query = query.Where("id NOT IN (?)", bookedSlots)
+	}
+
+	var slots []models.ViewingSlot
+	if err := query.Order("start_time ASC").Find(&slots).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch viewing slots", err)
+		return
+	}
+
+	var bookedIDs []uuid.UUID
+	config.DB.Model(&models.Viewing{}).
+		Where("house_id = ? AND status = ?", house.ID, models.ViewingStatusBooked).
+		Pluck("slot_id", &bookedIDs)
+	booked := make(map[uuid.UUID]bool, len(bookedIDs))
+	for _, slotID := range bookedIDs {
+		booked[slotID] = true
+	}
+	for i := range slots {
+		slots[i].IsBooked = booked[slots[i].ID]
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Viewing slots retrieved successfully", gin.H{
+		"slots": slots,
+	})
+}
+
+// DeleteViewingSlot handles withdrawing a viewing slot, cancelling any viewing booked in it
+func (vh *ViewingHandler) DeleteViewingSlot(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	slotID, err := uuid.Parse(c.Param("slotId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid slot ID", err)
+		return
+	}
+
+	var slot models.ViewingSlot
+	if err := config.DB.Preload("House").First(&slot, slotID).Error; err != nil {
+		utils.NotFoundResponse(c, "Viewing slot not found")
+		return
+	}
+
+	if slot.House.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only remove viewing slots for your own houses")
+		return
+	}
+
+	var viewing models.Viewing
+	hasViewing := config.DB.Where("slot_id = ? AND status = ?", slot.ID, models.ViewingStatusBooked).
+		First(&viewing).Error == nil
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if hasViewing {
+			now := time.Now()
+			if err := tx.Model(&viewing).Updates(map[string]interface{}{
+				"status":          models.ViewingStatusCancelled,
+				"cancelled_at":    now,
+				"cancelled_by_id": userModel.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&slot).Error
+	})
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to remove viewing slot", err)
+		return
+	}
+
+	if hasViewing {
+		services.Notify(viewing.TenantID, "Viewing Cancelled",
+			fmt.Sprintf("Your viewing of %s at %s was cancelled by the landlord",
+				slot.House.Title, slot.StartTime.Format("2006-01-02 15:04")), "viewing")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Viewing slot removed successfully", nil)
+}
+
+// BookViewing handles a tenant booking a viewing slot
+// @Summary Book a viewing
+// @Description Book an available viewing slot. Each slot can only be booked by one tenant.
+// @Tags Viewings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BookViewingRequest true "Viewing booking"
+// @Success 201 {object} map[string]interface{} "Viewing booked successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid request data"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Viewing slot not found"
+// @Failure 409 {object} map[string]interface{} "Slot already booked"
+// @Router /viewings [post]
+func (vh *ViewingHandler) BookViewing(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+	if userModel.Role != models.RoleTenant {
+		utils.ForbiddenResponse(c, "Only tenants can book viewings")
+		return
+	}
+
+	var req BookViewingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var viewing models.Viewing
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		slot, err := reserveSlot(tx, req.SlotID)
+		if err != nil {
+			return err
+		}
+
+		// One upcoming viewing per tenant per house; reschedule to change the time
+		var existing int64
+		tx.Model(&models.Viewing{}).
+			Joins("JOIN viewing_slots ON viewing_slots.id = viewings.slot_id").
+			Where("viewings.house_id = ? AND viewings.tenant_id = ? AND viewings.status = ? AND viewing_slots.start_time > ?",
+				slot.HouseID, userModel.ID, models.ViewingStatusBooked, time.Now()).
+			Count(&existing)
+		if existing > 0 {
+			return errViewingTaken
+		}
+
+		viewing = models.Viewing{
+			SlotID:   slot.ID,
+			HouseID:  slot.HouseID,
+			TenantID: userModel.ID,
+			Status:   models.ViewingStatusBooked,
+			Notes:    req.Notes,
+		}
+		return tx.Create(&viewing).Error
+	})
+	if err != nil {
+		respondViewingError(c, "Failed to book viewing", err)
+		return
+	}
+	recordListingEvents(c, models.EventViewing, []uuid.UUID{viewing.HouseID})
+
+	// Load relationships
+	config.DB.Preload("Slot").Preload("House").First(&viewing, viewing.ID)
+
+	services.Notify(viewing.House.LandlordID, "New Viewing Booked",
+		fmt.Sprintf("%s booked a viewing of %s at %s",
+			userModel.FullName, viewing.House.Title, viewing.Slot.StartTime.Format("2006-01-02 15:04")), "viewing")
+
+	utils.SuccessResponse(c, http.StatusCreated, "Viewing booked successfully", gin.H{
+		"viewing": viewing,
+	})
+}
+
+// GetViewings handles getting the viewings of the current user
+func (vh *ViewingHandler) GetViewings(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+	upcoming := c.Query("upcoming")
+
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	// Build query
+	query := config.DB.Model(&models.Viewing{}).
+		Joins("JOIN viewing_slots ON viewing_slots.id = viewings.slot_id")
+
+	// Apply filters based on user role
+	if userModel.Role == models.RoleTenant {
+		query = query.Where("viewings.tenant_id = ?", userModel.ID)
+	} else if userModel.Role == models.RoleLandlord {
+		query = query.Joins("JOIN houses ON viewings.house_id = houses.id").
+			Where("houses.landlord_id = ?", userModel.ID)
+	}
+
+	if status != "" {
+		query = query.Where("viewings.status = ?", status)
+	}
+	if upcoming == "true" {
+		query = query.Where("viewing_slots.start_time > ?", time.Now())
+	}
+
+	// Get total count
+	var total int64
+	query.Count(&total)
+
+	// Get viewings
+	var viewings []models.Viewing
+	if err := query.Preload("Slot").Preload("House").Preload("Tenant").
+		Order("viewing_slots.start_time ASC").
+		Offset(offset).Limit(limit).
+		Find(&viewings).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch viewings", err)
+		return
+	}
+
+	// Calculate pagination info
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	utils.SuccessResponse(c, http.StatusOK, "Viewings retrieved successfully", gin.H{
+		"viewings": viewings,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// RescheduleViewing handles a tenant moving their viewing to another slot of the same house
+func (vh *ViewingHandler) RescheduleViewing(c *gin.Context) {
+	viewing, userModel, ok := vh.loadViewing(c)
+	if !ok {
+		return
+	}
+
+	if viewing.TenantID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "Only the tenant who booked the viewing can reschedule it")
+		return
+	}
+
+	var req RescheduleViewingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, "Invalid request data", map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if req.SlotID == viewing.SlotID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "The viewing is already booked in this slot", nil)
+		return
+	}
+
+	previousStart := viewing.Slot.StartTime
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		slot, err := reserveSlot(tx, req.SlotID)
+		if err != nil {
+			return err
+		}
+		if slot.HouseID != viewing.HouseID {
+			return errSlotMismatch
+		}
+
+		return tx.Model(viewing).Updates(map[string]interface{}{
+			"slot_id":          slot.ID,
+			"reminder_sent_at": nil,
+		}).Error
+	})
+	if err != nil {
+		respondViewingError(c, "Failed to reschedule viewing", err)
+		return
+	}
+
+	// Load relationships
+	config.DB.Preload("Slot").Preload("House").First(viewing, viewing.ID)
+
+	services.Notify(viewing.House.LandlordID, "Viewing Rescheduled",
+		fmt.Sprintf("%s moved their viewing of %s from %s to %s", userModel.FullName, viewing.House.Title,
+			previousStart.Format("2006-01-02 15:04"), viewing.Slot.StartTime.Format("2006-01-02 15:04")), "viewing")
+
+	utils.SuccessResponse(c, http.StatusOK, "Viewing rescheduled successfully", gin.H{
+		"viewing": viewing,
+	})
+}
+
+// CancelViewing handles the tenant or landlord cancelling a viewing
+func (vh *ViewingHandler) CancelViewing(c *gin.Context) {
+	viewing, userModel, ok := vh.loadViewing(c)
+	if !ok {
+		return
+	}
+
+	isTenant := viewing.TenantID == userModel.ID
+	if !isTenant && viewing.House.LandlordID != userModel.ID && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "You can only cancel your own viewings")
+		return
+	}
+
+	now := time.Now()
+	viewing.Status = models.ViewingStatusCancelled
+	viewing.CancelledAt = &now
+	viewing.CancelledByID = &userModel.ID
+
+	if err := config.DB.Omit(clause.Associations).Save(viewing).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to cancel viewing", err)
+		return
+	}
+
+	when := viewing.Slot.StartTime.Format("2006-01-02 15:04")
+	if isTenant {
+		services.Notify(viewing.House.LandlordID, "Viewing Cancelled",
+			fmt.Sprintf("%s cancelled their viewing of %s at %s", userModel.FullName, viewing.House.Title, when), "viewing")
+	} else {
+		services.Notify(viewing.TenantID, "Viewing Cancelled",
+			fmt.Sprintf("Your viewing of %s at %s was cancelled", viewing.House.Title, when), "viewing")
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Viewing cancelled successfully", gin.H{
+		"viewing": viewing,
+	})
+}
+
+// GetViewingCalendar handles getting a landlord's upcoming viewings across all their houses, grouped by day
+// @Summary Get viewing calendar
+// @Description Get booked viewings across all of the landlord's houses, grouped by day
+// @Tags Viewings
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day (YYYY-MM-DD), defaults to today"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to 30 days from the first day"
+// @Success 200 {object} map[string]interface{} "Viewing calendar retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid date"
+// @Router /viewings/calendar [get]
+func (vh *ViewingHandler) GetViewingCalendar(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return
+	}
+
+	userModel := user.(models.User)
+	if userModel.Role != models.RoleLandlord && userModel.Role != models.RoleAdmin {
+		utils.ForbiddenResponse(c, "Only landlords can view the viewing calendar")
+		return
+	}
+
+	from := time.Now()
+	if fromParam := c.Query("from"); fromParam != "" {
+		parsed, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid from date format", err)
+			return
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, 30)
+	if toParam := c.Query("to"); toParam != "" {
+		parsed, err := time.Parse("2006-01-02", toParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid to date format", err)
+			return
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+
+	query := config.DB.Model(&models.Viewing{}).
+		Joins("JOIN viewing_slots ON viewing_slots.id = viewings.slot_id").
+		Joins("JOIN houses ON viewings.house_id = houses.id").
+		Where("viewings.status = ?", models.ViewingStatusBooked).
+		Where("viewing_slots.start_time >= ? AND viewing_slots.start_time < ?", from, to)
+
+	if userModel.Role == models.RoleLandlord {
+		query = query.Where("houses.landlord_id = ?", userModel.ID)
+	}
+
+	var viewings []models.Viewing
+	if err := query.Preload("Slot").Preload("House").Preload("Tenant").
+		Order("viewing_slots.start_time ASC").
+		Find(&viewings).Error; err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to fetch viewing calendar", err)
+		return
+	}
+
+	byDay := map[string][]models.Viewing{}
+	for _, viewing := range viewings {
+		day := viewing.Slot.StartTime.Format("2006-01-02")
+		byDay[day] = append(byDay[day], viewing)
+	}
+
+	days := make([]gin.H, 0, len(byDay))
+	for day, dayViewings := range byDay {
+		days = append(days, gin.H{
+			"date":     day,
+			"viewings": dayViewings,
+		})
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i]["date"].(string) < days[j]["date"].(string)
+	})
+
+	utils.SuccessResponse(c, http.StatusOK, "Viewing calendar retrieved successfully", gin.H{
+		"from":  from.Format("2006-01-02"),
+		"to":    to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"total": len(viewings),
+		"days":  days,
+	})
+}
+
+// loadViewing loads a booked viewing from the route
+func (vh *ViewingHandler) loadViewing(c *gin.Context) (*models.Viewing, *models.User, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		utils.UnauthorizedResponse(c, "User not authenticated")
+		return nil, nil, false
+	}
+
+	userModel := user.(models.User)
+
+	// Parse UUID
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid viewing ID", err)
+		return nil, nil, false
+	}
+
+	var viewing models.Viewing
+	if err := config.DB.Preload("Slot").Preload("House").First(&viewing, id).Error; err != nil {
+		utils.NotFoundResponse(c, "Viewing not found")
+		return nil, nil, false
+	}
+
+	if viewing.Status != models.ViewingStatusBooked {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Only booked viewings can be changed", nil)
+		return nil, nil, false
+	}
+
+	return &viewing, &userModel, true
+}
+
+// reserveSlot locks a viewing slot and checks it is still open for booking.
+// Every booking path locks the slot row first, so concurrent bookings of the same slot are serialised;
+// the partial unique index on viewings is the last line of defence.
+func reserveSlot(tx *gorm.DB, slotID uuid.UUID) (*models.ViewingSlot, error) {
+	var slot models.ViewingSlot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&slot, slotID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errSlotNotFound
+		}
+		return nil, err
+	}
+
+	if !slot.StartTime.After(time.Now()) {
+		return nil, errSlotStarted
+	}
+
+	// Viewings are only booked for published listings, not ones under review or removed by moderators
+	var listed int64
+	if err := tx.Model(&models.House{}).
+		Where("id = ? AND listing_status = ?", slot.HouseID, models.ListingPublished).
+		Count(&listed).Error; err != nil {
+		return nil, err
+	}
+	if listed == 0 {
+		return nil, errListingGone
+	}
+
+	var booked int64
+	if err := tx.Model(&models.Viewing{}).
+		Where("slot_id = ? AND status = ?", slot.ID, models.ViewingStatusBooked).
+		Count(&booked).Error; err != nil {
+		return nil, err
+	}
+	if booked > 0 {
+		return nil, errSlotBooked
+	}
+
+	return &slot, nil
+}
+
+// respondViewingError maps booking errors to responses
+func respondViewingError(c *gin.Context, message string, err error) {
+	// 23505 is a unique violation of the one-booking-per-slot index
+	var pgErr interface{ SQLState() string }
+	switch {
+	case errors.Is(err, errSlotNotFound):
+		utils.NotFoundResponse(c, "Viewing slot not found")
+	case errors.Is(err, errListingGone):
+		utils.NotFoundResponse(c, "House not found")
+	case errors.Is(err, errSlotBooked), errors.As(err, &pgErr) && pgErr.SQLState() == "23505":
+		utils.ErrorResponse(c, http.StatusConflict, "This viewing slot is already booked", nil)
+	case errors.Is(err, errViewingTaken):
+		utils.ErrorResponse(c, http.StatusConflict, "You already have a viewing booked for this house", nil)
+	case errors.Is(err, errSlotStarted):
+		utils.ErrorResponse(c, http.StatusBadRequest, "This viewing slot has already started", nil)
+	case errors.Is(err, errSlotMismatch):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Viewings can only be moved to another slot of the same house", nil)
+	default:
+		utils.InternalServerErrorResponse(c, message, err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"bondihub/docs"
 	"bondihub/middleware"
 	"bondihub/routes"
+	"bondihub/services"
 	"log"
 
 	"github.com/gin-gonic/gin"
@@ -47,6 +48,34 @@ func main() {
 	config.InitDB()
 	config.AutoMigrate()
 
+	// Start background jobs
+	rentalService := services.NewRentalService()
+	viewingService := services.NewViewingService()
+	billingService := services.NewBillingService()
+	imageStorage, err := services.NewImageStorage()
+	if err != nil {
+		log.Printf("Queued image deletions will not be processed until image storage is configured: %v", err)
+	}
+	scheduler := services.NewScheduler()
+	scheduler.Register("scheduled_terminations", config.AppConfig.SchedulerInterval, rentalService.ProcessScheduledTerminations)
+	scheduler.Register("due_amendments", config.AppConfig.SchedulerInterval, rentalService.ProcessDueAmendments)
+	scheduler.Register("expired_agreements", config.AppConfig.SchedulerInterval, rentalService.ExpireEndedAgreements)
+	scheduler.Register("upcoming_agreements", config.AppConfig.SchedulerInterval, rentalService.ActivateUpcomingAgreements)
+	scheduler.Register("rent_escalations", config.AppConfig.SchedulerInterval, rentalService.ProcessRentEscalations)
+	scheduler.Register("monthly_charges", config.AppConfig.SchedulerInterval, billingService.GenerateMonthlyCharges)
+	scheduler.Register("viewing_reminders", config.AppConfig.SchedulerInterval, viewingService.ProcessViewingReminders)
+	scheduler.Register("listing_analytics", config.AppConfig.SchedulerInterval, services.NewAnalyticsService().RollupListingEvents)
+	scheduler.Register("search_matches", config.AppConfig.SchedulerInterval, services.NewSavedSearchService().MatchQueuedListings)
+	scheduler.Register("search_digests", config.AppConfig.SchedulerInterval, services.NewSavedSearchService().SendSearchDigests)
+	scheduler.Register("image_hashes", config.AppConfig.SchedulerInterval, services.NewImageHashBackfill().ProcessMissingImageHashes)
+	geocoder := services.NewListingGeocoder()
+	scheduler.Register("listing_geocoding", config.AppConfig.SchedulerInterval, services.NewGeocodingBackfill(geocoder).ProcessMissingCoordinates)
+	if imageStorage != nil {
+		scheduler.Register("image_deletions", config.AppConfig.SchedulerInterval, services.NewImageCleanupService(imageStorage).ProcessImageDeletions)
+	}
+	scheduler.Start()
+	defer scheduler.Stop()
+
 	// Set Gin mode
 	gin.SetMode(config.AppConfig.GinMode)
 
@@ -59,7 +88,12 @@ func main() {
 	r.Use(middleware.CORSMiddleware())
 
 	// Setup routes
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, geocoder)
+
+	// Serve uploaded images when they are stored on the local disk
+	if config.AppConfig.StorageBackend == "local" {
+		r.Static(services.LocalStorageRoute, config.AppConfig.LocalStorageDir)
+	}
 
 	// Swagger documentation
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AmendmentStatus represents the status of an agreement amendment
+type AmendmentStatus string
+
+const (
+	AmendmentStatusProposed AmendmentStatus = "proposed"
+	AmendmentStatusAccepted AmendmentStatus = "accepted"
+	AmendmentStatusRejected AmendmentStatus = "rejected"
+	AmendmentStatusApplied  AmendmentStatus = "applied"
+)
+
+// Amendable agreement fields
+const (
+	FieldRentAmount      = "rent_amount"
+	FieldDeposit         = "deposit"
+	FieldStartDate       = "start_date"
+	FieldEndDate         = "end_date"
+	FieldTenants         = "tenants"
+	FieldStatus          = "status"
+	FieldTerminationDate = "termination_date"
+	FieldEscalations     = "escalations"
+)
+
+// TenantShare is a snapshot of a tenant and their rent share used in amendment diffs
+type TenantShare struct {
+	TenantID  uuid.UUID `json:"tenant_id"`
+	RentShare float64   `json:"rent_share"`
+}
+
+// FieldChange records the old and new value of a single agreement field
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Decode unmarshals the new value of the change into target
+func (fc FieldChange) Decode(target interface{}) error {
+	raw, err := json.Marshal(fc.To)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// FieldChanges is a list of field changes stored as JSON
+type FieldChanges []FieldChange
+
+// Value implements driver.Valuer
+func (fc FieldChanges) Value() (driver.Value, error) {
+	if fc == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(fc)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner
+func (fc *FieldChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*fc = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, fc)
+	case string:
+		return json.Unmarshal([]byte(v), fc)
+	default:
+		return errors.New("unsupported type for FieldChanges")
+	}
+}
+
+// AgreementAmendment represents a version of a rental agreement's terms.
+// Version 1 holds the original terms; later versions hold proposed or applied changes.
+type AgreementAmendment struct {
+	ID            uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgreementID   uuid.UUID       `json:"agreement_id" gorm:"type:uuid;not null;uniqueIndex:idx_agreement_version"`
+	Version       int             `json:"version" gorm:"not null;uniqueIndex:idx_agreement_version"`
+	Status        AmendmentStatus `json:"status" gorm:"not null;default:'proposed'"`
+	Changes       FieldChanges    `json:"changes" gorm:"type:jsonb;not null"`
+	Reason        string          `json:"reason" gorm:"type:text"`
+	EffectiveDate time.Time       `json:"effective_date" gorm:"not null"`
+	ProposedByID  *uuid.UUID      `json:"proposed_by_id" gorm:"type:uuid"` // nil for system changes
+	AcceptedByID  *uuid.UUID      `json:"accepted_by_id" gorm:"type:uuid"`
+	AcceptedAt    *time.Time      `json:"accepted_at"`
+	RejectedByID  *uuid.UUID      `json:"rejected_by_id" gorm:"type:uuid"`
+	RejectedAt    *time.Time      `json:"rejected_at"`
+	AppliedAt     *time.Time      `json:"applied_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+
+	// Relationships
+	ProposedBy *User `json:"proposed_by,omitempty" gorm:"foreignKey:ProposedByID"`
+	AcceptedBy *User `json:"accepted_by,omitempty" gorm:"foreignKey:AcceptedByID"`
+}
+
+// BeforeCreate hook to set default values
+func (aa *AgreementAmendment) BeforeCreate(tx *gorm.DB) error {
+	if aa.ID == uuid.Nil {
+		aa.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for AgreementAmendment
+func (AgreementAmendment) TableName() string {
+	return "agreement_amendments"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Amenity represents a feature from the admin-managed catalogue that houses can offer,
+// e.g. borehole, solar backup, wall fence or DSTV
+type Amenity struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name      string    `json:"name" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"not null;uniqueIndex"` // used in search filters, e.g. solar-backup
+	Category  string    `json:"category"`                         // e.g. utilities, security, comfort
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to set default values
+func (a *Amenity) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for Amenity
+func (Amenity) TableName() string {
+	return "amenities"
+}
+
+// HouseAmenity links a house to an amenity it offers
+type HouseAmenity struct {
+	HouseID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AmenityID uuid.UUID `gorm:"type:uuid;primaryKey;index"` // indexed for amenity filters
+}
+
+// TableName returns the table name for HouseAmenity
+func (HouseAmenity) TableName() string {
+	return "house_amenities"
+}
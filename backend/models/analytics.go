@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListingEventKind represents a tenant interaction with a listing
+type ListingEventKind string
+
+const (
+	EventImpression ListingEventKind = "impression" // shown in search results
+	EventView       ListingEventKind = "view"       // listing page opened
+	EventFavorite   ListingEventKind = "favorite"   // added to favorites
+	EventViewing    ListingEventKind = "viewing"    // viewing booked
+	EventAgreement  ListingEventKind = "agreement"  // rental agreement created
+)
+
+// ListingEvent is one recorded interaction with a listing. Events are rolled up into
+// ListingDailyStat and deleted after a retention period.
+type ListingEvent struct {
+	ID         uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	HouseID    uuid.UUID        `json:"house_id" gorm:"type:uuid;not null;index"`
+	Kind       ListingEventKind `json:"kind" gorm:"not null"`
+	ViewerID   *uuid.UUID       `json:"viewer_id" gorm:"type:uuid"` // nil for anonymous visitors
+	VisitorKey string           `json:"-" gorm:"size:64"`           // identifies a visitor, signed in or not, for unique counts
+	CreatedAt  time.Time        `json:"created_at" gorm:"index"`
+}
+
+// BeforeCreate hook to set default values
+func (le *ListingEvent) BeforeCreate(tx *gorm.DB) error {
+	if le.ID == uuid.Nil {
+		le.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for ListingEvent
+func (ListingEvent) TableName() string {
+	return "listing_events"
+}
+
+// ListingDailyStat holds a listing's event counts for one day
+type ListingDailyStat struct {
+	HouseID        uuid.UUID `json:"house_id" gorm:"type:uuid;primary_key"`
+	Date           time.Time `json:"date" gorm:"type:date;primary_key;index"`
+	Impressions    int64     `json:"impressions" gorm:"not null;default:0"`
+	Views          int64     `json:"views" gorm:"not null;default:0"`
+	UniqueVisitors int64     `json:"unique_visitors" gorm:"not null;default:0"` // distinct visitors who opened the listing
+	Favorites      int64     `json:"favorites" gorm:"not null;default:0"`
+	Viewings       int64     `json:"viewings" gorm:"not null;default:0"`
+	Agreements     int64     `json:"agreements" gorm:"not null;default:0"`
+}
+
+// TableName returns the table name for ListingDailyStat
+func (ListingDailyStat) TableName() string {
+	return "listing_daily_stats"
+}
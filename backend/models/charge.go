@@ -0,0 +1,188 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ChargeBilling represents how a charge type is billed
+type ChargeBilling string
+
+const (
+	BillingRecurring ChargeBilling = "recurring" // flat amount every month, e.g. refuse collection or security
+	BillingMetered   ChargeBilling = "metered"   // priced from meter readings, e.g. water or ZESCO electricity
+)
+
+// TariffBand is one block of a tiered tariff. UpTo is the cumulative number of units the band covers;
+// nil means the band covers all remaining units.
+type TariffBand struct {
+	UpTo *float64 `json:"up_to"`
+	Rate float64  `json:"rate"`
+}
+
+// Tariff is a list of tariff bands, cheapest block first, stored as JSON
+type Tariff []TariffBand
+
+// Cost returns the price of the given number of units under the tariff
+func (t Tariff) Cost(units float64) float64 {
+	cost := 0.0
+	covered := 0.0
+	for _, band := range t {
+		if units <= covered {
+			break
+		}
+		bandUnits := units - covered
+		if band.UpTo != nil {
+			bandUnits = math.Min(bandUnits, *band.UpTo-covered)
+			covered = *band.UpTo
+		} else {
+			covered = units
+		}
+		cost += bandUnits * band.Rate
+	}
+	return math.Round(cost*100) / 100
+}
+
+// Value implements driver.Valuer
+func (t Tariff) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner
+func (t *Tariff) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return errors.New("unsupported type for Tariff")
+	}
+}
+
+// ChargeType represents a utility or service a landlord charges for on a house
+type ChargeType struct {
+	ID      uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	HouseID uuid.UUID     `json:"house_id" gorm:"type:uuid;not null;index"`
+	Name    string        `json:"name" gorm:"not null"`
+	Billing ChargeBilling `json:"billing" gorm:"not null"`
+	// Amount is the monthly amount of a recurring charge, or the monthly standing fee of a metered charge
+	Amount    float64        `json:"amount" gorm:"type:decimal(10,2);default:0"`
+	Unit      string         `json:"unit"` // e.g. kWh or m3 for metered charges
+	Tariff    Tariff         `json:"tariff" gorm:"type:jsonb"`
+	IsActive  bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	House House `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+}
+
+// BeforeCreate hook to set default values
+func (ct *ChargeType) BeforeCreate(tx *gorm.DB) error {
+	if ct.ID == uuid.Nil {
+		ct.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for ChargeType
+func (ChargeType) TableName() string {
+	return "charge_types"
+}
+
+// MeterReading represents a meter reading entered by the landlord for a metered charge
+type MeterReading struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ChargeTypeID uuid.UUID `json:"charge_type_id" gorm:"type:uuid;not null;index"`
+	Reading      float64   `json:"reading" gorm:"not null;type:decimal(12,2)"`
+	ReadingDate  time.Time `json:"reading_date" gorm:"not null"`
+	RecordedByID uuid.UUID `json:"recorded_by_id" gorm:"type:uuid;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	ChargeType ChargeType `json:"charge_type,omitempty" gorm:"foreignKey:ChargeTypeID"`
+}
+
+// BeforeCreate hook to set default values
+func (mr *MeterReading) BeforeCreate(tx *gorm.DB) error {
+	if mr.ID == uuid.Nil {
+		mr.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for MeterReading
+func (MeterReading) TableName() string {
+	return "meter_readings"
+}
+
+// ChargeKind represents what a charge is for
+type ChargeKind string
+
+const (
+	ChargeKindRent      ChargeKind = "rent"
+	ChargeKindRecurring ChargeKind = "recurring"
+	ChargeKindMetered   ChargeKind = "metered"
+)
+
+// ChargeStatus represents the payment status of a charge
+type ChargeStatus string
+
+const (
+	ChargeStatusUnpaid  ChargeStatus = "unpaid"
+	ChargeStatusPartial ChargeStatus = "partial"
+	ChargeStatusPaid    ChargeStatus = "paid"
+)
+
+// Charge represents an amount billed to an agreement: a month's rent, a service charge or metered consumption
+type Charge struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgreementID    uuid.UUID    `json:"agreement_id" gorm:"type:uuid;not null;index"`
+	ChargeTypeID   *uuid.UUID   `json:"charge_type_id" gorm:"type:uuid;index"` // nil for rent
+	MeterReadingID *uuid.UUID   `json:"meter_reading_id" gorm:"type:uuid;uniqueIndex"`
+	Kind           ChargeKind   `json:"kind" gorm:"not null"`
+	Description    string       `json:"description" gorm:"not null"`
+	PeriodStart    time.Time    `json:"period_start" gorm:"not null"`
+	PeriodEnd      time.Time    `json:"period_end" gorm:"not null"`
+	Quantity       float64      `json:"quantity" gorm:"type:decimal(12,2);default:0"` // units consumed for metered charges
+	Amount         float64      `json:"amount" gorm:"not null;type:decimal(10,2)"`
+	AmountPaid     float64      `json:"amount_paid" gorm:"type:decimal(10,2);default:0"`
+	DueDate        time.Time    `json:"due_date" gorm:"not null"`
+	Status         ChargeStatus `json:"status" gorm:"not null;default:'unpaid'"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+
+	// Relationships
+	ChargeType *ChargeType `json:"charge_type,omitempty" gorm:"foreignKey:ChargeTypeID"`
+}
+
+// BeforeCreate hook to set default values
+func (ch *Charge) BeforeCreate(tx *gorm.DB) error {
+	if ch.ID == uuid.Nil {
+		ch.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for Charge
+func (Charge) TableName() string {
+	return "charges"
+}
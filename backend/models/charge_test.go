@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestTariffCost(t *testing.T) {
+	upTo := func(units float64) *float64 { return &units }
+	// ZESCO-style residential blocks: the first 200 units are cheapest
+	blocks := Tariff{
+		{UpTo: upTo(200), Rate: 0.47},
+		{UpTo: upTo(400), Rate: 0.85},
+		{Rate: 1.94},
+	}
+
+	tests := []struct {
+		name   string
+		tariff Tariff
+		units  float64
+		want   float64
+	}{
+		{"no tariff", nil, 120, 0},
+		{"no units", blocks, 0, 0},
+		{"flat rate", Tariff{{Rate: 12.5}}, 8, 100},
+		{"within first block", blocks, 150, 70.5},
+		{"first block exactly", blocks, 200, 94},
+		{"into second block", blocks, 250, 136.5},
+		{"into open-ended block", blocks, 500, 458},
+		{"fractional units", blocks, 200.5, 94.43},
+		{"capped tariff stops at its last block", Tariff{{UpTo: upTo(10), Rate: 2}}, 25, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tariff.Cost(tt.units); got != tt.want {
+				t.Errorf("Cost(%v) = %v, want %v", tt.units, got, tt.want)
+			}
+		})
+	}
+}
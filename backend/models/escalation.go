@@ -0,0 +1,59 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EscalationType represents how a scheduled rent increase is calculated
+type EscalationType string
+
+const (
+	EscalationFixed      EscalationType = "fixed"
+	EscalationPercentage EscalationType = "percentage"
+)
+
+// EscalationTerms describes an agreement's rent escalation clause. An empty Type means no escalation.
+type EscalationTerms struct {
+	Type           EscalationType `json:"type"`
+	Value          float64        `json:"value" gorm:"type:decimal(10,2)"`
+	IntervalMonths int            `json:"interval_months"`
+}
+
+// RentEscalation represents a scheduled rent increase on an agreement's anniversary date
+type RentEscalation struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgreementID   uuid.UUID      `json:"agreement_id" gorm:"type:uuid;not null;index"`
+	EffectiveDate time.Time      `json:"effective_date" gorm:"not null;index"`
+	Type          EscalationType `json:"type" gorm:"not null"`
+	Value         float64        `json:"value" gorm:"not null;type:decimal(10,2)"`  // amount in ZMW or percentage
+	NewRentAmount *float64       `json:"new_rent_amount" gorm:"type:decimal(10,2)"` // set when applied
+	NoticeSentAt  *time.Time     `json:"notice_sent_at"`
+	AppliedAt     *time.Time     `json:"applied_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Apply returns the rent after this increase is applied to the given rent
+func (re *RentEscalation) Apply(rent float64) float64 {
+	if re.Type == EscalationPercentage {
+		return math.Round(rent*(1+re.Value/100)*100) / 100
+	}
+	return math.Round((rent+re.Value)*100) / 100
+}
+
+// BeforeCreate hook to set default values
+func (re *RentEscalation) BeforeCreate(tx *gorm.DB) error {
+	if re.ID == uuid.Nil {
+		re.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for RentEscalation
+func (RentEscalation) TableName() string {
+	return "rent_escalations"
+}
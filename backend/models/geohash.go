@@ -0,0 +1,55 @@
+package models
+
+import "strings"
+
+// GeohashPrecision is the number of characters stored for a house's geohash (cells of roughly 5m x 5m)
+const GeohashPrecision = 9
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of a coordinate with the given number of characters.
+// Houses close to each other share a geohash prefix, which lets map searches use a prefix index.
+func EncodeGeohash(latitude, longitude float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+
+	var hash strings.Builder
+	bit, ch := 0, 0
+	evenBit := true // geohash bits alternate between longitude and latitude, starting with longitude
+	for hash.Len() < precision {
+		if evenBit {
+			mid := (minLng + maxLng) / 2
+			if longitude >= mid {
+				ch = ch<<1 | 1
+				minLng = mid
+			} else {
+				ch = ch << 1
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if latitude >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch = ch << 1
+				maxLat = mid
+			}
+		}
+		evenBit = !evenBit
+
+		if bit++; bit == 5 {
+			hash.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// GeohashCellSize returns the height and width in degrees of a geohash cell with the given number of characters
+func GeohashCellSize(precision int) (latDegrees, lngDegrees float64) {
+	bits := precision * 5
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / float64(uint64(1)<<latBits), 360 / float64(uint64(1)<<lngBits)
+}
@@ -16,6 +16,17 @@ const (
 	StatusMaintenance HouseStatus = "maintenance"
 )
 
+// ListingStatus represents where a listing is in moderation, separately from the occupancy status of the house
+type ListingStatus string
+
+const (
+	ListingDraft         ListingStatus = "draft"          // not yet submitted by the landlord
+	ListingPendingReview ListingStatus = "pending_review" // waiting for a moderator
+	ListingPublished     ListingStatus = "published"      // shown to the public
+	ListingRejected      ListingStatus = "rejected"       // sent back to the landlord with a reason
+	ListingArchived      ListingStatus = "archived"       // withdrawn by the landlord
+)
+
 // HouseType represents the type of house
 type HouseType string
 
@@ -31,30 +42,67 @@ const (
 type House struct {
 	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	LandlordID    uuid.UUID      `json:"landlord_id" gorm:"type:uuid;not null"`
+	PropertyID    *uuid.UUID     `json:"property_id" gorm:"type:uuid;index"` // set when the house is a unit of a property
+	UnitLabel     string         `json:"unit_label"`                         // e.g. "Flat 3B", for units of a property
 	Title         string         `json:"title" gorm:"not null"`
 	Description   string         `json:"description" gorm:"type:text"`
 	Address       string         `json:"address" gorm:"not null"`
+	LocationID    *uuid.UUID     `json:"location_id" gorm:"type:uuid;index"` // the most specific location node, usually an area
 	MonthlyRent   float64        `json:"monthly_rent" gorm:"not null;type:decimal(10,2)"`
 	Status        HouseStatus    `json:"status" gorm:"not null;default:'available'"`
 	HouseType     HouseType      `json:"house_type" gorm:"not null"`
 	Latitude      float64        `json:"latitude" gorm:"type:decimal(10,8)"`
 	Longitude     float64        `json:"longitude" gorm:"type:decimal(11,8)"`
+	Geohash       string         `json:"-" gorm:"size:12;index:idx_houses_geohash,expression:geohash text_pattern_ops"`
+	SearchVector  string         `json:"-" gorm:"->:false;<-:false;type:tsvector;index:idx_houses_search_vector,type:gin"` // maintained by a trigger
 	Bedrooms      int            `json:"bedrooms" gorm:"default:0"`
 	Bathrooms     int            `json:"bathrooms" gorm:"default:0"`
 	Area          float64        `json:"area" gorm:"type:decimal(8,2)"` // in square meters
 	IsFeatured    bool           `json:"is_featured" gorm:"default:false"`
 	FeaturedUntil *time.Time     `json:"featured_until"`
+	AvailableFrom *time.Time     `json:"available_from"` // nil when the house is free now
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Moderation; houses listed before moderation was introduced stay published
+	ListingStatus ListingStatus `json:"listing_status" gorm:"not null;default:'published';index"`
+	SubmittedAt   *time.Time    `json:"submitted_at"`                              // when the listing last entered review
+	RejectedFor   string        `json:"rejected_for,omitempty"`                    // rejection reason while rejected
+	ModeratorNote string        `json:"moderator_note,omitempty" gorm:"type:text"` // moderator's message while rejected
+
+	// When the geocoding job last tried to place a house saved without coordinates
+	GeocodeAttemptedAt *time.Time `json:"-"`
+
+	// When the listing was published and queued to be matched against saved searches; cleared once
+	// it has been matched
+	SearchMatchQueuedAt *time.Time `json:"-" gorm:"index"`
+
+	// Where geocoded coordinates came from and what they matched, e.g. gazetteer and area; both are
+	// empty when the landlord placed the pin
+	GeocodeSource    string `json:"geocode_source,omitempty"`
+	GeocodePrecision string `json:"geocode_precision,omitempty"`
+
+	// Only set by location and text searches, and recommendations
+	DistanceKm    *float64 `json:"distance_km,omitempty" gorm:"->;-:migration"`
+	SearchRank    *float64 `json:"search_rank,omitempty" gorm:"->;-:migration"`
+	SearchSnippet *string  `json:"search_snippet,omitempty" gorm:"->;-:migration"`
+	MatchScore    *float64 `json:"match_score,omitempty" gorm:"->;-:migration"` // 0 to 1
+	// Set with distance_km; true when the pin is only the centre of the house's area or town
+	ApproximatePin *bool `json:"approximate_pin,omitempty" gorm:"->;-:migration"`
+
 	// Relationships
 	Landlord            User                 `json:"landlord,omitempty" gorm:"foreignKey:LandlordID"`
+	Property            *Property            `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+	Location            *Location            `json:"location,omitempty" gorm:"foreignKey:LocationID"`
 	Images              []HouseImage         `json:"images,omitempty" gorm:"foreignKey:HouseID"`
+	Amenities           []Amenity            `json:"amenities,omitempty" gorm:"many2many:house_amenities"`
 	RentalAgreements    []RentalAgreement    `json:"rental_agreements,omitempty" gorm:"foreignKey:HouseID"`
 	Reviews             []Review             `json:"reviews,omitempty" gorm:"foreignKey:HouseID"`
 	MaintenanceRequests []MaintenanceRequest `json:"maintenance_requests,omitempty" gorm:"foreignKey:HouseID"`
 	Favorites           []Favorite           `json:"favorites,omitempty" gorm:"foreignKey:HouseID"`
+	Moderations         []ListingModeration  `json:"moderations,omitempty" gorm:"foreignKey:HouseID"`
+	Risk                *ListingRisk         `json:"risk,omitempty" gorm:"foreignKey:HouseID"` // only loaded for moderators
 }
 
 // BeforeCreate hook to set default values
@@ -65,6 +113,16 @@ func (h *House) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave hook to keep the geohash in step with the coordinates
+func (h *House) BeforeSave(tx *gorm.DB) error {
+	if h.Latitude == 0 && h.Longitude == 0 {
+		h.Geohash = ""
+	} else {
+		h.Geohash = EncodeGeohash(h.Latitude, h.Longitude, GeohashPrecision)
+	}
+	return nil
+}
+
 // TableName returns the table name for House
 func (House) TableName() string {
 	return "houses"
@@ -72,11 +130,16 @@ func (House) TableName() string {
 
 // HouseImage represents images associated with a house
 type HouseImage struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	HouseID   uuid.UUID `json:"house_id" gorm:"type:uuid;not null"`
-	ImageURL  string    `json:"image_url" gorm:"not null"`
-	IsPrimary bool      `json:"is_primary" gorm:"default:false"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	HouseID      uuid.UUID `json:"house_id" gorm:"type:uuid;not null;index"`
+	ImageURL     string    `json:"image_url" gorm:"not null"`
+	PublicID     string    `json:"-"`                                    // storage key, used to delete the stored image
+	ImageHash    *int64    `json:"-"`                                    // perceptual hash, to spot photos reused across listings
+	MediumURL    string    `json:"medium_url"`                           // about 800px wide, for listing pages
+	ThumbnailURL string    `json:"thumbnail_url"`                        // 300x200, for search results and galleries
+	IsPrimary    bool      `json:"is_primary" gorm:"default:false"`      // at most one image per house
+	SortOrder    int       `json:"sort_order" gorm:"not null;default:0"` // gallery position, primary image first
+	CreatedAt    time.Time `json:"created_at"`
 
 	// Relationships
 	House House `json:"house,omitempty" gorm:"foreignKey:HouseID"`
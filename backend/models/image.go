@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImageHashBand is a run of bits of an image's perceptual hash. Each band has an expression index on
+// house_images, so images sharing a band with a hash are found without comparing every image.
+type ImageHashBand struct {
+	Shift uint // position of the band's lowest bit
+	Bits  uint
+}
+
+// ImageHashBands splits the 64 bits of a hash into 9 bands, one of 8 bits and eight of 7. Two hashes
+// differing in at most 8 bits leave at least one band untouched, so they always share a band.
+var ImageHashBands = func() []ImageHashBand {
+	bands := []ImageHashBand{{Shift: 0, Bits: 8}}
+	for shift := uint(8); shift < 64; shift += 7 {
+		bands = append(bands, ImageHashBand{Shift: shift, Bits: 7})
+	}
+	return bands
+}()
+
+// Value returns the band of a hash
+func (b ImageHashBand) Value(hash int64) int64 {
+	return int64(uint64(hash) >> b.Shift & (1<<b.Bits - 1))
+}
+
+// SQL returns the expression computing the band of house_images.image_hash, as indexed
+func (b ImageHashBand) SQL() string {
+	return fmt.Sprintf("((image_hash >> %d) & %d)", b.Shift, int64(1)<<b.Bits-1)
+}
+
+// ImageDeletion is a stored image whose record has been deleted and which still has to be removed
+// from image storage. Deletions are queued in the same transaction as the record, so an image is
+// never orphaned when the storage backend is unreachable; a scheduled job retries failed deletions.
+type ImageDeletion struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PublicID      string    `json:"public_id" gorm:"not null"`
+	Attempts      int       `json:"attempts" gorm:"not null;default:0"`
+	LastError     string    `json:"last_error" gorm:"type:text"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"not null;index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set default values
+func (d *ImageDeletion) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = time.Now()
+	}
+	return nil
+}
+
+// TableName returns the table name for ImageDeletion
+func (ImageDeletion) TableName() string {
+	return "image_deletions"
+}
+
+// UploadClaim records that a direct upload is being confirmed. Its public ID is unique, so only one
+// request can confirm an upload even when the confirmation is sent twice at once.
+type UploadClaim struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PublicID    string    `json:"public_id" gorm:"not null;uniqueIndex"`
+	ClaimedByID uuid.UUID `json:"claimed_by_id" gorm:"type:uuid;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set default values
+func (uc *UploadClaim) BeforeCreate(tx *gorm.DB) error {
+	if uc.ID == uuid.Nil {
+		uc.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for UploadClaim
+func (UploadClaim) TableName() string {
+	return "upload_claims"
+}
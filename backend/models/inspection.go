@@ -0,0 +1,139 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InspectionType represents when an inspection was carried out
+type InspectionType string
+
+const (
+	InspectionTypeMoveIn  InspectionType = "move_in"
+	InspectionTypeMoveOut InspectionType = "move_out"
+)
+
+// ConditionRating represents the condition of an inspected item
+type ConditionRating string
+
+const (
+	ConditionExcellent ConditionRating = "excellent"
+	ConditionGood      ConditionRating = "good"
+	ConditionFair      ConditionRating = "fair"
+	ConditionPoor      ConditionRating = "poor"
+	ConditionDamaged   ConditionRating = "damaged"
+)
+
+// Score returns a numeric score for the rating, higher is better
+func (cr ConditionRating) Score() int {
+	switch cr {
+	case ConditionExcellent:
+		return 5
+	case ConditionGood:
+		return 4
+	case ConditionFair:
+		return 3
+	case ConditionPoor:
+		return 2
+	case ConditionDamaged:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// InspectionReport represents a move-in or move-out inspection of a rented house
+type InspectionReport struct {
+	ID                 uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgreementID        uuid.UUID      `json:"agreement_id" gorm:"type:uuid;not null;index"`
+	Type               InspectionType `json:"type" gorm:"not null"`
+	InspectedAt        time.Time      `json:"inspected_at" gorm:"not null"`
+	Notes              string         `json:"notes" gorm:"type:text"`
+	CreatedByID        uuid.UUID      `json:"created_by_id" gorm:"type:uuid;not null"`
+	LandlordSignedAt   *time.Time     `json:"landlord_signed_at"`
+	LandlordSignedByID *uuid.UUID     `json:"landlord_signed_by_id" gorm:"type:uuid"`
+	TenantSignedAt     *time.Time     `json:"tenant_signed_at"`
+	TenantSignedByID   *uuid.UUID     `json:"tenant_signed_by_id" gorm:"type:uuid"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Agreement RentalAgreement  `json:"agreement,omitempty" gorm:"foreignKey:AgreementID"`
+	Items     []InspectionItem `json:"items,omitempty" gorm:"foreignKey:ReportID"`
+}
+
+// IsLocked reports whether either party has signed, after which the report can no longer be edited
+func (ir *InspectionReport) IsLocked() bool {
+	return ir.LandlordSignedAt != nil || ir.TenantSignedAt != nil
+}
+
+// IsComplete reports whether both parties have signed off on the report
+func (ir *InspectionReport) IsComplete() bool {
+	return ir.LandlordSignedAt != nil && ir.TenantSignedAt != nil
+}
+
+// BeforeCreate hook to set default values
+func (ir *InspectionReport) BeforeCreate(tx *gorm.DB) error {
+	if ir.ID == uuid.Nil {
+		ir.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for InspectionReport
+func (InspectionReport) TableName() string {
+	return "inspection_reports"
+}
+
+// InspectionItem represents a single checklist item in a room
+type InspectionItem struct {
+	ID        uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ReportID  uuid.UUID       `json:"report_id" gorm:"type:uuid;not null;index"`
+	Room      string          `json:"room" gorm:"not null"`
+	Item      string          `json:"item" gorm:"not null"`
+	Condition ConditionRating `json:"condition" gorm:"not null"`
+	Notes     string          `json:"notes" gorm:"type:text"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+
+	// Relationships
+	Photos []InspectionPhoto `json:"photos,omitempty" gorm:"foreignKey:ItemID"`
+}
+
+// BeforeCreate hook to set default values
+func (ii *InspectionItem) BeforeCreate(tx *gorm.DB) error {
+	if ii.ID == uuid.Nil {
+		ii.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for InspectionItem
+func (InspectionItem) TableName() string {
+	return "inspection_items"
+}
+
+// InspectionPhoto represents a photo attached to an inspection item
+type InspectionPhoto struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ItemID    uuid.UUID `json:"item_id" gorm:"type:uuid;not null;index"`
+	ImageURL  string    `json:"image_url" gorm:"not null"`
+	PublicID  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to set default values
+func (ip *InspectionPhoto) BeforeCreate(tx *gorm.DB) error {
+	if ip.ID == uuid.Nil {
+		ip.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for InspectionPhoto
+func (InspectionPhoto) TableName() string {
+	return "inspection_photos"
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LocationLevel represents a level of the location hierarchy
+type LocationLevel string
+
+const (
+	LevelProvince LocationLevel = "province"
+	LevelDistrict LocationLevel = "district"
+	LevelTown     LocationLevel = "town"
+	LevelArea     LocationLevel = "area" // a suburb, area or compound of a town
+)
+
+// LocationLevels lists the levels from the top of the hierarchy down
+var LocationLevels = []LocationLevel{LevelProvince, LevelDistrict, LevelTown, LevelArea}
+
+// ChildLevel returns the level below a level, or "" for areas
+func (ll LocationLevel) ChildLevel() LocationLevel {
+	for i, level := range LocationLevels[:len(LocationLevels)-1] {
+		if level == ll {
+			return LocationLevels[i+1]
+		}
+	}
+	return ""
+}
+
+var nonLocationSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// LocationSlug turns a place name into the slug used in location paths, e.g. "North-Western" to "north-western"
+func LocationSlug(name string) string {
+	return strings.Trim(nonLocationSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// Location is a node of the province, district, town and area hierarchy houses are listed in.
+// Path holds the slugs of the node and its ancestors from the province down, e.g.
+// "lusaka/lusaka/lusaka/kabulonga", so a node's descendants are the nodes whose path starts with its own.
+type Location struct {
+	ID        uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ParentID  *uuid.UUID    `json:"parent_id" gorm:"type:uuid;index"`
+	Level     LocationLevel `json:"level" gorm:"not null"`
+	Name      string        `json:"name" gorm:"not null"`
+	Slug      string        `json:"slug" gorm:"not null"`
+	Path      string        `json:"path" gorm:"not null;uniqueIndex;index:idx_locations_path_prefix,expression:path text_pattern_ops"`
+	Latitude  float64       `json:"latitude" gorm:"type:decimal(10,8)"` // centre of the area, or the capital of a province or district
+	Longitude float64       `json:"longitude" gorm:"type:decimal(11,8)"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+
+	// Relationships
+	Parent   *Location  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	Children []Location `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// BeforeCreate hook to set default values
+func (l *Location) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for Location
+func (Location) TableName() string {
+	return "locations"
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RejectionReason is why a moderator turned a listing down
+type RejectionReason string
+
+const (
+	RejectionSuspectedScam RejectionReason = "suspected_scam"
+	RejectionMisleading    RejectionReason = "misleading"
+	RejectionDuplicate     RejectionReason = "duplicate"
+	RejectionPoorPhotos    RejectionReason = "poor_photos"
+	RejectionIncomplete    RejectionReason = "incomplete"
+	RejectionProhibited    RejectionReason = "prohibited"
+	RejectionOther         RejectionReason = "other"
+)
+
+// ListingModeration records one change of a listing's moderation state, so moderators can see a
+// listing's history and why it came back for review
+type ListingModeration struct {
+	ID         uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	HouseID    uuid.UUID     `json:"house_id" gorm:"type:uuid;not null;index"`
+	ActorID    *uuid.UUID    `json:"actor_id" gorm:"type:uuid"` // nil for changes made by the system
+	FromStatus ListingStatus `json:"from_status"`
+	ToStatus   ListingStatus `json:"to_status" gorm:"not null"`
+	Reason     string        `json:"reason"`                // rejection reason, or what changed on a published listing
+	Note       string        `json:"note" gorm:"type:text"` // moderator's message to the landlord
+	CreatedAt  time.Time     `json:"created_at"`
+
+	// Relationships
+	House House `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
+
+// BeforeCreate hook to set default values
+func (lm *ListingModeration) BeforeCreate(tx *gorm.DB) error {
+	if lm.ID == uuid.Nil {
+		lm.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for ListingModeration
+func (ListingModeration) TableName() string {
+	return "listing_moderations"
+}
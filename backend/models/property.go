@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Property represents a building or compound whose flats or houses are let as separate units.
+// Units are House rows with PropertyID set; they take their address, location and coordinates from the property,
+// and share its images and amenities. Rent, status and agreements stay on each unit.
+type Property struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	LandlordID  uuid.UUID      `json:"landlord_id" gorm:"type:uuid;not null;index"`
+	Name        string         `json:"name" gorm:"not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	Address     string         `json:"address" gorm:"not null"`
+	LocationID  *uuid.UUID     `json:"location_id" gorm:"type:uuid;index"`
+	Latitude    float64        `json:"latitude" gorm:"type:decimal(10,8)"`
+	Longitude   float64        `json:"longitude" gorm:"type:decimal(11,8)"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// When the geocoding job last tried to place a property saved without coordinates
+	GeocodeAttemptedAt *time.Time `json:"-"`
+
+	// Where geocoded coordinates came from and what they matched, e.g. gazetteer and area; both are
+	// empty when the landlord placed the pin
+	GeocodeSource    string `json:"geocode_source,omitempty"`
+	GeocodePrecision string `json:"geocode_precision,omitempty"`
+
+	// Relationships
+	Landlord  User            `json:"landlord,omitempty" gorm:"foreignKey:LandlordID"`
+	Location  *Location       `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+	Units     []House         `json:"units,omitempty" gorm:"foreignKey:PropertyID"`
+	Images    []PropertyImage `json:"images,omitempty" gorm:"foreignKey:PropertyID"`
+	Amenities []Amenity       `json:"amenities,omitempty" gorm:"many2many:property_amenities"`
+}
+
+// BeforeCreate hook to set default values
+func (p *Property) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for Property
+func (Property) TableName() string {
+	return "properties"
+}
+
+// PropertyImage represents images shared by all units of a property
+type PropertyImage struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PropertyID   uuid.UUID `json:"property_id" gorm:"type:uuid;not null;index"`
+	ImageURL     string    `json:"image_url" gorm:"not null"`
+	PublicID     string    `json:"-"`          // storage key, used to delete the stored image
+	MediumURL    string    `json:"medium_url"` // as for house images
+	ThumbnailURL string    `json:"thumbnail_url"`
+	IsPrimary    bool      `json:"is_primary" gorm:"default:false"`      // at most one image per property
+	SortOrder    int       `json:"sort_order" gorm:"not null;default:0"` // gallery position, oldest first
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	Property Property `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+}
+
+// BeforeCreate hook to set default values
+func (pi *PropertyImage) BeforeCreate(tx *gorm.DB) error {
+	if pi.ID == uuid.Nil {
+		pi.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for PropertyImage
+func (PropertyImage) TableName() string {
+	return "property_images"
+}
+
+// PropertyAmenity links a property to an amenity shared by all its units
+type PropertyAmenity struct {
+	PropertyID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	AmenityID  uuid.UUID `gorm:"type:uuid;primaryKey;index"` // indexed for amenity filters
+}
+
+// TableName returns the table name for PropertyAmenity
+func (PropertyAmenity) TableName() string {
+	return "property_amenities"
+}
@@ -11,6 +11,7 @@ import (
 type AgreementStatus string
 
 const (
+	AgreementStatusUpcoming   AgreementStatus = "upcoming"
 	AgreementStatusActive     AgreementStatus = "active"
 	AgreementStatusTerminated AgreementStatus = "terminated"
 	AgreementStatusExpired    AgreementStatus = "expired"
@@ -26,14 +27,23 @@ type RentalAgreement struct {
 	RentAmount float64         `json:"rent_amount" gorm:"not null;type:decimal(10,2)"`
 	Deposit    float64         `json:"deposit" gorm:"not null;type:decimal(10,2)"`
 	Status     AgreementStatus `json:"status" gorm:"not null;default:'active'"`
+	// NoticePeriodDays is the minimum notice a tenant must give before moving out
+	NoticePeriodDays int `json:"notice_period_days" gorm:"not null;default:30"`
+	// TerminationDate is set when a termination has been scheduled for a future date
+	TerminationDate *time.Time `json:"termination_date" gorm:"index"`
+	// Escalation is the clause used to schedule rent increases on the agreement's anniversaries
+	Escalation EscalationTerms `json:"escalation" gorm:"embedded;embeddedPrefix:escalation_"`
 	CreatedAt  time.Time       `json:"created_at"`
 	UpdatedAt  time.Time       `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt  `json:"-" gorm:"index"`
 
 	// Relationships
-	House    House     `json:"house,omitempty" gorm:"foreignKey:HouseID"`
-	Tenant   User      `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
-	Payments []Payment `json:"payments,omitempty" gorm:"foreignKey:AgreementID"`
+	House          House             `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+	Tenant         User              `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+	Payments       []Payment         `json:"payments,omitempty" gorm:"foreignKey:AgreementID"`
+	MoveOutNotices []MoveOutNotice   `json:"move_out_notices,omitempty" gorm:"foreignKey:AgreementID"`
+	Tenants        []AgreementTenant `json:"tenants,omitempty" gorm:"foreignKey:AgreementID"`
+	Escalations    []RentEscalation  `json:"escalations,omitempty" gorm:"foreignKey:AgreementID"`
 }
 
 // BeforeCreate hook to set default values
@@ -44,11 +54,93 @@ func (ra *RentalAgreement) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// OccupiedUntil returns the date the house is handed back: the scheduled termination date if it
+// comes before the end date, otherwise the end date
+func (ra *RentalAgreement) OccupiedUntil() time.Time {
+	if ra.TerminationDate != nil && ra.TerminationDate.Before(ra.EndDate) {
+		return *ra.TerminationDate
+	}
+	return ra.EndDate
+}
+
 // TableName returns the table name for RentalAgreement
 func (RentalAgreement) TableName() string {
 	return "rental_agreements"
 }
 
+// AgreementTenant represents one of the tenants sharing a rental agreement.
+// The primary tenant is also stored on RentalAgreement.TenantID.
+type AgreementTenant struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgreementID uuid.UUID `json:"agreement_id" gorm:"type:uuid;not null;uniqueIndex:idx_agreement_tenant"`
+	TenantID    uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_agreement_tenant;index"`
+	RentShare   float64   `json:"rent_share" gorm:"not null;type:decimal(10,2);default:0"`
+	IsPrimary   bool      `json:"is_primary" gorm:"default:false"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Tenant User `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+}
+
+// BeforeCreate hook to set default values
+func (at *AgreementTenant) BeforeCreate(tx *gorm.DB) error {
+	if at.ID == uuid.Nil {
+		at.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for AgreementTenant
+func (AgreementTenant) TableName() string {
+	return "agreement_tenants"
+}
+
+// NoticeStatus represents the status of a move-out notice
+type NoticeStatus string
+
+const (
+	NoticeStatusPending      NoticeStatus = "pending"
+	NoticeStatusAcknowledged NoticeStatus = "acknowledged"
+	NoticeStatusWithdrawn    NoticeStatus = "withdrawn"
+	NoticeStatusCompleted    NoticeStatus = "completed"
+)
+
+// MoveOutNotice represents a tenant's notice to vacate a rented house
+type MoveOutNotice struct {
+	ID               uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AgreementID      uuid.UUID    `json:"agreement_id" gorm:"type:uuid;not null;index"`
+	TenantID         uuid.UUID    `json:"tenant_id" gorm:"type:uuid;not null"`
+	NoticeDate       time.Time    `json:"notice_date" gorm:"not null"`
+	MoveOutDate      time.Time    `json:"move_out_date" gorm:"not null"`
+	Reason           string       `json:"reason" gorm:"type:text"`
+	Status           NoticeStatus `json:"status" gorm:"not null;default:'pending'"`
+	AcknowledgedAt   *time.Time   `json:"acknowledged_at"`
+	AcknowledgedByID *uuid.UUID   `json:"acknowledged_by_id" gorm:"type:uuid"`
+	// PreviousTerminationDate is the agreement's termination date before the notice was acknowledged,
+	// restored if the notice is withdrawn
+	PreviousTerminationDate *time.Time `json:"previous_termination_date"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+
+	// Relationships
+	Agreement RentalAgreement `json:"agreement,omitempty" gorm:"foreignKey:AgreementID"`
+	Tenant    User            `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+}
+
+// BeforeCreate hook to set default values
+func (mn *MoveOutNotice) BeforeCreate(tx *gorm.DB) error {
+	if mn.ID == uuid.Nil {
+		mn.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for MoveOutNotice
+func (MoveOutNotice) TableName() string {
+	return "move_out_notices"
+}
+
 // PaymentMethod represents the payment method used
 type PaymentMethod string
 
@@ -73,6 +165,8 @@ const (
 type Payment struct {
 	ID          uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	AgreementID uuid.UUID     `json:"agreement_id" gorm:"type:uuid;not null"`
+	TenantID    *uuid.UUID    `json:"tenant_id" gorm:"type:uuid;index"` // tenant the payment is attributed to
+	ChargeID    *uuid.UUID    `json:"charge_id" gorm:"type:uuid;index"` // charge the payment settles, if any
 	Amount      float64       `json:"amount" gorm:"not null;type:decimal(10,2)"`
 	PaymentDate time.Time     `json:"payment_date" gorm:"not null"`
 	Method      PaymentMethod `json:"method" gorm:"not null"`
@@ -84,6 +178,8 @@ type Payment struct {
 
 	// Relationships
 	Agreement RentalAgreement `json:"agreement,omitempty" gorm:"foreignKey:AgreementID"`
+	Tenant    *User           `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+	Charge    *Charge         `json:"charge,omitempty" gorm:"foreignKey:ChargeID"`
 }
 
 // BeforeCreate hook to set default values
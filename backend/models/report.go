@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportReason represents why a user reported a listing
+type ReportReason string
+
+const (
+	ReportFraudulent  ReportReason = "fraudulent"
+	ReportDuplicate   ReportReason = "duplicate"
+	ReportMisleading  ReportReason = "misleading"
+	ReportUnavailable ReportReason = "unavailable" // already let, or not for rent
+	ReportOffensive   ReportReason = "offensive"
+	ReportOther       ReportReason = "other"
+)
+
+// ReportStatus represents the status of a listing report
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "open"
+	ReportStatusResolved ReportStatus = "resolved"
+)
+
+// ReportResolution represents what a moderator did about a listing's reports
+type ReportResolution string
+
+const (
+	ResolutionDismissed       ReportResolution = "dismissed"
+	ResolutionLandlordWarned  ReportResolution = "landlord_warned"
+	ResolutionListingRemoved  ReportResolution = "listing_removed"
+	ResolutionUserDeactivated ReportResolution = "user_deactivated"
+)
+
+// ListingReport is a user's report of a suspicious listing. Reports are handled per listing: resolving
+// a listing resolves all of its open reports.
+type ListingReport struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	HouseID        uuid.UUID        `json:"house_id" gorm:"type:uuid;not null;index"`
+	ReporterID     uuid.UUID        `json:"reporter_id" gorm:"type:uuid;not null;index"`
+	Reason         ReportReason     `json:"reason" gorm:"not null"`
+	Details        string           `json:"details" gorm:"type:text"`
+	Status         ReportStatus     `json:"status" gorm:"not null;default:'open';index"`
+	Resolution     ReportResolution `json:"resolution,omitempty"`
+	ResolutionNote string           `json:"resolution_note,omitempty" gorm:"type:text"`
+	ResolvedByID   *uuid.UUID       `json:"resolved_by_id,omitempty" gorm:"type:uuid"`
+	ResolvedAt     *time.Time       `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+
+	// Relationships
+	House      House `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+	Reporter   User  `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
+	ResolvedBy *User `json:"resolved_by,omitempty" gorm:"foreignKey:ResolvedByID"`
+}
+
+// BeforeCreate hook to set default values
+func (lr *ListingReport) BeforeCreate(tx *gorm.DB) error {
+	if lr.ID == uuid.Nil {
+		lr.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for ListingReport
+func (ListingReport) TableName() string {
+	return "listing_reports"
+}
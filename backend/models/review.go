@@ -51,6 +51,7 @@ type MaintenanceRequest struct {
 	ID          uuid.UUID                `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	TenantID    uuid.UUID                `json:"tenant_id" gorm:"type:uuid;not null"`
 	HouseID     uuid.UUID                `json:"house_id" gorm:"type:uuid;not null"`
+	AgreementID *uuid.UUID               `json:"agreement_id" gorm:"type:uuid;index"`
 	Title       string                   `json:"title" gorm:"not null"`
 	Description string                   `json:"description" gorm:"type:text;not null"`
 	Status      MaintenanceRequestStatus `json:"status" gorm:"not null;default:'pending'"`
@@ -111,7 +112,7 @@ type Notification struct {
 	Title     string    `json:"title" gorm:"not null"`
 	Message   string    `json:"message" gorm:"type:text;not null"`
 	IsRead    bool      `json:"is_read" gorm:"default:false"`
-	Type      string    `json:"type" gorm:"not null"` // payment, maintenance, agreement, general
+	Type      string    `json:"type" gorm:"not null"` // payment, maintenance, agreement, listing, search, general
 	CreatedAt time.Time `json:"created_at"`
 
 	// Relationships
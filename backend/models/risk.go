@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RiskSignalKind represents what made a listing look like a duplicate or scam
+type RiskSignalKind string
+
+const (
+	SignalImageMatch         RiskSignalKind = "image_match" // a photo matches one on another landlord's listing
+	SignalSimilarTitle       RiskSignalKind = "similar_title"
+	SignalSimilarDescription RiskSignalKind = "similar_description"
+	SignalSimilarAddress     RiskSignalKind = "similar_address"
+)
+
+// RiskSignal is one piece of evidence that a listing copies another landlord's listing
+type RiskSignal struct {
+	Kind           RiskSignalKind `json:"kind"`
+	HouseID        uuid.UUID      `json:"house_id"`                   // the other landlord's listing
+	ImageID        *uuid.UUID     `json:"image_id,omitempty"`         // this listing's image, for image matches
+	MatchedImageID *uuid.UUID     `json:"matched_image_id,omitempty"` // the other listing's image, for image matches
+	Similarity     float64        `json:"similarity"`                 // 0 to 1; for images, the share of matching hash bits
+	Points         int            `json:"points"`                     // contribution to the risk score
+}
+
+// RiskSignals is a list of risk signals stored as JSON
+type RiskSignals []RiskSignal
+
+// Value implements driver.Valuer
+func (rs RiskSignals) Value() (driver.Value, error) {
+	if rs == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(rs)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner
+func (rs *RiskSignals) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*rs = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, rs)
+	case string:
+		return json.Unmarshal([]byte(v), rs)
+	default:
+		return errors.New("unsupported type for RiskSignals")
+	}
+}
+
+// ListingRisk is the duplicate and scam risk assessment of a listing, shown to moderators only
+type ListingRisk struct {
+	HouseID    uuid.UUID   `json:"house_id" gorm:"type:uuid;primary_key"`
+	Score      int         `json:"score" gorm:"not null;default:0;index"` // 0 to 100
+	Signals    RiskSignals `json:"signals" gorm:"type:jsonb;not null"`
+	AssessedAt time.Time   `json:"assessed_at" gorm:"not null"`
+}
+
+// TableName returns the table name for ListingRisk
+func (ListingRisk) TableName() string {
+	return "listing_risks"
+}
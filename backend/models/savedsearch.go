@@ -0,0 +1,114 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AlertFrequency represents how often a user is alerted about new listings matching a saved search
+type AlertFrequency string
+
+const (
+	AlertInstant AlertFrequency = "instant" // as soon as a matching listing is published
+	AlertDaily   AlertFrequency = "daily"   // one digest a day
+	AlertOff     AlertFrequency = "off"
+)
+
+// StringList is a list of strings stored as JSON
+type StringList []string
+
+// Value implements driver.Valuer
+func (sl StringList) Value() (driver.Value, error) {
+	if sl == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(sl)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan implements sql.Scanner
+func (sl *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*sl = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, sl)
+	case string:
+		return json.Unmarshal([]byte(v), sl)
+	default:
+		return errors.New("unsupported type for StringList")
+	}
+}
+
+// SavedSearch represents house search filters a user saved to be alerted about new listings.
+// Empty and zero filters match every listing.
+type SavedSearch struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID         uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name           string         `json:"name" gorm:"not null"`
+	HouseType      HouseType      `json:"house_type"`
+	MinRent        float64        `json:"min_rent" gorm:"not null;default:0;type:decimal(10,2)"`
+	MaxRent        float64        `json:"max_rent" gorm:"not null;default:0;type:decimal(10,2)"`
+	Bedrooms       int            `json:"bedrooms" gorm:"not null;default:0"` // minimum
+	Latitude       *float64       `json:"latitude" gorm:"type:decimal(10,8)"` // centre of the search area, if any
+	Longitude      *float64       `json:"longitude" gorm:"type:decimal(11,8)"`
+	RadiusKm       float64        `json:"radius_km" gorm:"not null;default:0"`
+	Amenities      StringList     `json:"amenities" gorm:"type:jsonb;not null;default:'[]'"` // amenity slugs, all required
+	Search         string         `json:"search"`
+	AlertFrequency AlertFrequency `json:"alert_frequency" gorm:"not null;default:'instant';index"`
+	LastDigestAt   *time.Time     `json:"last_digest_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// BeforeCreate hook to set default values
+func (ss *SavedSearch) BeforeCreate(tx *gorm.DB) error {
+	if ss.ID == uuid.Nil {
+		ss.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for SavedSearch
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
+// SavedSearchMatch records a published listing matching a saved search, so each listing is alerted once
+type SavedSearchMatch struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SavedSearchID uuid.UUID  `json:"saved_search_id" gorm:"type:uuid;not null;uniqueIndex:idx_saved_search_house"`
+	HouseID       uuid.UUID  `json:"house_id" gorm:"type:uuid;not null;uniqueIndex:idx_saved_search_house"`
+	NotifiedAt    *time.Time `json:"notified_at" gorm:"index"` // nil while waiting for a daily digest
+	CreatedAt     time.Time  `json:"created_at"`
+
+	// Relationships
+	SavedSearch SavedSearch `json:"saved_search,omitempty" gorm:"foreignKey:SavedSearchID"`
+	House       House       `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+}
+
+// BeforeCreate hook to set default values
+func (ssm *SavedSearchMatch) BeforeCreate(tx *gorm.DB) error {
+	if ssm.ID == uuid.Nil {
+		ssm.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for SavedSearchMatch
+func (SavedSearchMatch) TableName() string {
+	return "saved_search_matches"
+}
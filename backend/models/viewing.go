@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ViewingSlot represents a time window in which a landlord is available to show a house
+type ViewingSlot struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	HouseID   uuid.UUID      `json:"house_id" gorm:"type:uuid;not null;index"`
+	StartTime time.Time      `json:"start_time" gorm:"not null;index"`
+	EndTime   time.Time      `json:"end_time" gorm:"not null"`
+	IsBooked  bool           `json:"is_booked" gorm:"-"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	House House `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+}
+
+// BeforeCreate hook to set default values
+func (vs *ViewingSlot) BeforeCreate(tx *gorm.DB) error {
+	if vs.ID == uuid.Nil {
+		vs.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for ViewingSlot
+func (ViewingSlot) TableName() string {
+	return "viewing_slots"
+}
+
+// ViewingStatus represents the status of a viewing appointment
+type ViewingStatus string
+
+const (
+	ViewingStatusBooked    ViewingStatus = "booked"
+	ViewingStatusCancelled ViewingStatus = "cancelled"
+)
+
+// Viewing represents a prospective tenant's booking of a viewing slot.
+// The partial unique index allows only one booked viewing per slot.
+type Viewing struct {
+	ID             uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SlotID         uuid.UUID     `json:"slot_id" gorm:"type:uuid;not null;uniqueIndex:idx_viewing_slot_booked,where:status = 'booked'"`
+	HouseID        uuid.UUID     `json:"house_id" gorm:"type:uuid;not null;index"`
+	TenantID       uuid.UUID     `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Status         ViewingStatus `json:"status" gorm:"not null;default:'booked'"`
+	Notes          string        `json:"notes" gorm:"type:text"`
+	ReminderSentAt *time.Time    `json:"reminder_sent_at"`
+	CancelledAt    *time.Time    `json:"cancelled_at"`
+	CancelledByID  *uuid.UUID    `json:"cancelled_by_id" gorm:"type:uuid"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+
+	// Relationships
+	Slot   ViewingSlot `json:"slot,omitempty" gorm:"foreignKey:SlotID"`
+	House  House       `json:"house,omitempty" gorm:"foreignKey:HouseID"`
+	Tenant User        `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+}
+
+// BeforeCreate hook to set default values
+func (v *Viewing) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName returns the table name for Viewing
+func (Viewing) TableName() string {
+	return "viewings"
+}
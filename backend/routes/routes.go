@@ -3,15 +3,17 @@ package routes
 import (
 	"bondihub/handlers"
 	"bondihub/middleware"
+	"bondihub/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes configures all the routes for the application
-func SetupRoutes(r *gin.Engine) {
+// SetupRoutes configures all the routes for the application. The geocoder is shared with the
+// background geocoding job, so they keep to one rate limit and cache.
+func SetupRoutes(r *gin.Engine, geocoder services.Geocoder) {
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler()
-	houseHandler := handlers.NewHouseHandler()
+	houseHandler := handlers.NewHouseHandler(geocoder)
 	paymentHandler := handlers.NewPaymentHandler()
 	rentalHandler := handlers.NewRentalHandler()
 	reviewHandler := handlers.NewReviewHandler()
@@ -19,6 +21,19 @@ func SetupRoutes(r *gin.Engine) {
 	favoriteHandler := handlers.NewFavoriteHandler()
 	notificationHandler := handlers.NewNotificationHandler()
 	adminHandler := handlers.NewAdminHandler()
+	inspectionHandler := handlers.NewInspectionHandler()
+	viewingHandler := handlers.NewViewingHandler()
+	chargeHandler := handlers.NewChargeHandler()
+	amenityHandler := handlers.NewAmenityHandler()
+	propertyHandler := handlers.NewPropertyHandler(geocoder)
+	uploadHandler := handlers.NewUploadHandler()
+	moderationHandler := handlers.NewModerationHandler()
+	reportHandler := handlers.NewReportHandler()
+	analyticsHandler := handlers.NewAnalyticsHandler()
+	savedSearchHandler := handlers.NewSavedSearchHandler()
+	recommendationHandler := handlers.NewRecommendationHandler()
+	locationHandler := handlers.NewLocationHandler()
+	geocodingHandler := handlers.NewGeocodingHandler(geocoder)
 
 	// API version 1
 	v1 := r.Group("/api/v1")
@@ -32,9 +47,19 @@ func SetupRoutes(r *gin.Engine) {
 		public.POST("/auth/logout", authHandler.Logout)
 
 		// Public house routes (browse houses)
-		public.GET("/houses", houseHandler.GetHouses)
-		public.GET("/houses/:id", houseHandler.GetHouse)
+		public.GET("/houses", middleware.OptionalAuthMiddleware(), houseHandler.GetHouses)
+		public.GET("/houses/:id", middleware.OptionalAuthMiddleware(), houseHandler.GetHouse)
+		public.GET("/houses/:id/similar", middleware.OptionalAuthMiddleware(), recommendationHandler.GetSimilarHouses)
 		public.GET("/houses/:id/reviews", reviewHandler.GetReviews)
+		public.GET("/houses/:id/viewing-slots", middleware.OptionalAuthMiddleware(), viewingHandler.GetViewingSlots)
+		public.GET("/houses/:id/charge-types", middleware.OptionalAuthMiddleware(), chargeHandler.GetChargeTypes)
+		public.GET("/amenities", amenityHandler.GetAmenities)
+		public.GET("/locations", locationHandler.GetLocations)
+		public.GET("/locations/:id", locationHandler.GetLocation)
+		public.GET("/properties/:id", middleware.OptionalAuthMiddleware(), propertyHandler.GetProperty)
+
+		// Direct uploads to the local image storage backend (authorised by the signed upload fields)
+		public.POST("/uploads/direct", uploadHandler.ReceiveDirectUpload)
 	}
 
 	// Protected routes (require authentication)
@@ -51,10 +76,54 @@ func SetupRoutes(r *gin.Engine) {
 		houses.Use(middleware.LandlordOrAdminMiddleware())
 		{
 			houses.POST("", houseHandler.CreateHouse)
+			houses.GET("/mine", houseHandler.GetMyHouses)
+			houses.GET("/mine/analytics", analyticsHandler.GetPortfolioAnalytics)
+			houses.GET("/:id/analytics", analyticsHandler.GetHouseAnalytics)
 			houses.PUT("/:id", houseHandler.UpdateHouse)
 			houses.DELETE("/:id", houseHandler.DeleteHouse)
+			houses.PUT("/:id/submit", houseHandler.SubmitHouse)
+			houses.PUT("/:id/archive", houseHandler.ArchiveHouse)
 			houses.POST("/:id/images", houseHandler.UploadHouseImage)
+			houses.POST("/:id/images/batch", houseHandler.BatchUploadHouseImages)
+			houses.POST("/:id/images/direct-upload", houseHandler.CreateHouseImageUpload)
+			houses.POST("/:id/images/direct-upload/confirm", houseHandler.ConfirmHouseImageUpload)
+			houses.PUT("/:id/images/order", houseHandler.ReorderHouseImages)
 			houses.DELETE("/images/:imageId", houseHandler.DeleteHouseImage)
+			houses.PUT("/images/:imageId/primary", houseHandler.SetPrimaryHouseImage)
+			houses.POST("/:id/viewing-slots", viewingHandler.CreateViewingSlots)
+			houses.DELETE("/viewing-slots/:slotId", viewingHandler.DeleteViewingSlot)
+			houses.POST("/:id/charge-types", chargeHandler.CreateChargeType)
+			houses.PUT("/charge-types/:chargeTypeId", chargeHandler.UpdateChargeType)
+			houses.POST("/charge-types/:chargeTypeId/readings", chargeHandler.RecordMeterReading)
+			houses.GET("/charge-types/:chargeTypeId/readings", chargeHandler.GetMeterReadings)
+		}
+
+		// Listing reports (any signed-in user)
+		protected.POST("/houses/:id/reports", reportHandler.ReportHouse)
+
+		// Personalised home feed
+		protected.GET("/houses/recommended", recommendationHandler.GetRecommendedHouses)
+
+		// Address and map pin lookups for listing forms (landlords and admins)
+		geocode := protected.Group("/geocode")
+		geocode.Use(middleware.LandlordOrAdminMiddleware())
+		{
+			geocode.GET("", geocodingHandler.Geocode)
+			geocode.GET("/reverse", geocodingHandler.ReverseGeocode)
+		}
+
+		// Property routes (landlords and admins)
+		properties := protected.Group("/properties")
+		properties.Use(middleware.LandlordOrAdminMiddleware())
+		{
+			properties.POST("", propertyHandler.CreateProperty)
+			properties.GET("", propertyHandler.GetProperties)
+			properties.PUT("/:id", propertyHandler.UpdateProperty)
+			properties.DELETE("/:id", propertyHandler.DeleteProperty)
+			properties.GET("/:id/occupancy", propertyHandler.GetPropertyOccupancy)
+			properties.POST("/:id/images", propertyHandler.UploadPropertyImage)
+			properties.DELETE("/images/:imageId", propertyHandler.DeletePropertyImage)
+			properties.PUT("/images/:imageId/primary", propertyHandler.SetPrimaryPropertyImage)
 		}
 
 		// Payment routes
@@ -74,6 +143,43 @@ func SetupRoutes(r *gin.Engine) {
 			rentals.GET("/:id", rentalHandler.GetRentalAgreement)
 			rentals.PUT("/:id", rentalHandler.UpdateRentalAgreement)
 			rentals.PUT("/:id/terminate", rentalHandler.TerminateRentalAgreement)
+			rentals.POST("/:id/notices", rentalHandler.GiveMoveOutNotice)
+			rentals.GET("/:id/notices", rentalHandler.GetMoveOutNotices)
+			rentals.PUT("/:id/notices/:noticeId/acknowledge", rentalHandler.AcknowledgeMoveOutNotice)
+			rentals.PUT("/:id/notices/:noticeId/withdraw", rentalHandler.WithdrawMoveOutNotice)
+			rentals.POST("/:id/tenants", rentalHandler.AddCoTenant)
+			rentals.DELETE("/:id/tenants/:tenantId", rentalHandler.RemoveCoTenant)
+			rentals.PUT("/:id/tenants/shares", rentalHandler.UpdateRentShares)
+			rentals.POST("/:id/amendments", rentalHandler.ProposeAmendment)
+			rentals.GET("/:id/amendments", rentalHandler.GetAmendments)
+			rentals.PUT("/:id/amendments/:amendmentId/accept", rentalHandler.AcceptAmendment)
+			rentals.PUT("/:id/amendments/:amendmentId/reject", rentalHandler.RejectAmendment)
+			rentals.GET("/:id/timeline", rentalHandler.GetAgreementTimeline)
+			rentals.GET("/:id/charges", chargeHandler.GetAgreementCharges)
+			rentals.GET("/:id/balance", chargeHandler.GetAgreementBalance)
+			rentals.POST("/:id/inspections", inspectionHandler.CreateInspectionReport)
+			rentals.GET("/:id/inspections", inspectionHandler.GetInspectionReports)
+			rentals.GET("/:id/inspections/compare", inspectionHandler.CompareInspectionReports)
+		}
+
+		// Inspection report routes
+		inspections := protected.Group("/inspections")
+		{
+			inspections.GET("/:id", inspectionHandler.GetInspectionReport)
+			inspections.POST("/:id/items", inspectionHandler.AddInspectionItem)
+			inspections.PUT("/:id/items/:itemId", inspectionHandler.UpdateInspectionItem)
+			inspections.POST("/:id/items/:itemId/photos", inspectionHandler.UploadInspectionPhoto)
+			inspections.PUT("/:id/sign", inspectionHandler.SignInspectionReport)
+		}
+
+		// Viewing appointment routes
+		viewings := protected.Group("/viewings")
+		{
+			viewings.POST("", viewingHandler.BookViewing)
+			viewings.GET("", viewingHandler.GetViewings)
+			viewings.GET("/calendar", viewingHandler.GetViewingCalendar)
+			viewings.PUT("/:id/reschedule", viewingHandler.RescheduleViewing)
+			viewings.PUT("/:id/cancel", viewingHandler.CancelViewing)
 		}
 
 		// Review routes
@@ -105,6 +211,16 @@ func SetupRoutes(r *gin.Engine) {
 			favorites.GET("/:id/check", favoriteHandler.CheckFavorite)
 		}
 
+		// Saved search routes
+		savedSearches := protected.Group("/saved-searches")
+		{
+			savedSearches.POST("", savedSearchHandler.CreateSavedSearch)
+			savedSearches.GET("", savedSearchHandler.GetSavedSearches)
+			savedSearches.PUT("/:id", savedSearchHandler.UpdateSavedSearch)
+			savedSearches.DELETE("/:id", savedSearchHandler.DeleteSavedSearch)
+			savedSearches.GET("/:id/matches", savedSearchHandler.GetSavedSearchMatches)
+		}
+
 		// Notification routes
 		notifications := protected.Group("/notifications")
 		{
@@ -125,6 +241,18 @@ func SetupRoutes(r *gin.Engine) {
 		admin.GET("/users", adminHandler.GetUsers)
 		admin.PUT("/users/:id/status", adminHandler.UpdateUserStatus)
 		admin.GET("/reports", adminHandler.GetReports)
+		admin.GET("/image-deletions", adminHandler.GetFailedImageDeletions)
+		admin.PUT("/image-deletions/:id/retry", adminHandler.RetryImageDeletion)
+		admin.GET("/listings", moderationHandler.GetListingQueue)
+		admin.PUT("/listings/:id/approve", moderationHandler.ApproveListing)
+		admin.PUT("/listings/:id/reject", moderationHandler.RejectListing)
+		admin.GET("/listings/:id/risk", moderationHandler.GetListingRisk)
+		admin.GET("/listing-reports", reportHandler.GetReportQueue)
+		admin.PUT("/listing-reports/:id/resolve", reportHandler.ResolveReports)
+		admin.POST("/amenities", amenityHandler.CreateAmenity)
+		admin.PUT("/amenities/:id", amenityHandler.UpdateAmenity)
+		admin.DELETE("/amenities/:id", amenityHandler.DeleteAmenity)
+		admin.POST("/locations", locationHandler.CreateLocation)
 	}
 
 	// Health check route
@@ -41,6 +41,31 @@ func main() {
 		landlordID = landlord.ID
 	}
 
+	// Create the amenity catalogue
+	amenities := []models.Amenity{
+		{Name: "Borehole", Slug: "borehole", Category: "utilities"},
+		{Name: "Solar Backup", Slug: "solar-backup", Category: "utilities"},
+		{Name: "Generator", Slug: "generator", Category: "utilities"},
+		{Name: "Water Tank", Slug: "water-tank", Category: "utilities"},
+		{Name: "Wall Fence", Slug: "wall-fence", Category: "security"},
+		{Name: "Electric Fence", Slug: "electric-fence", Category: "security"},
+		{Name: "Security Guard", Slug: "security-guard", Category: "security"},
+		{Name: "Parking", Slug: "parking", Category: "features"},
+		{Name: "Furnished", Slug: "furnished", Category: "features"},
+		{Name: "Servants Quarters", Slug: "servants-quarters", Category: "features"},
+		{Name: "Swimming Pool", Slug: "swimming-pool", Category: "features"},
+		{Name: "Pets Allowed", Slug: "pets-allowed", Category: "rules"},
+		{Name: "DSTV", Slug: "dstv", Category: "connectivity"},
+		{Name: "Wi-Fi", Slug: "wi-fi", Category: "connectivity"},
+	}
+
+	for _, amenity := range amenities {
+		amenity.IsActive = true
+		if err := config.DB.FirstOrCreate(&amenity, models.Amenity{Slug: amenity.Slug}).Error; err != nil {
+			log.Printf("Error creating amenity '%s': %v", amenity.Name, err)
+		}
+	}
+
 	// Create sample houses
 	houses := []models.House{
 		{
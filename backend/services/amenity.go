@@ -0,0 +1,79 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownAmenity is returned when a house is given an amenity that is not in the active catalogue
+var ErrUnknownAmenity = errors.New("one or more amenities do not exist or are inactive")
+
+// AmenityFacet is the number of houses in a search result offering an amenity
+type AmenityFacet struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Slug     string    `json:"slug"`
+	Category string    `json:"category"`
+	Count    int64     `json:"count"`
+}
+
+// LoadAmenities returns the active catalogue amenities with the given IDs
+func LoadAmenities(ids []uuid.UUID) ([]models.Amenity, error) {
+	amenities := []models.Amenity{}
+	if len(ids) == 0 {
+		return amenities, nil
+	}
+
+	if err := config.DB.Where("id IN ? AND is_active = ?", ids, true).Find(&amenities).Error; err != nil {
+		return nil, err
+	}
+
+	unique := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	if len(amenities) != len(unique) {
+		return nil, ErrUnknownAmenity
+	}
+	return amenities, nil
+}
+
+// houseAmenityLinks selects the amenities each house offers: its own, plus those of its property
+func houseAmenityLinks() *gorm.DB {
+	return config.DB.Raw(`SELECT house_id, amenity_id FROM house_amenities
+		UNION
+		SELECT houses.id AS house_id, property_amenities.amenity_id FROM houses
+		JOIN property_amenities ON property_amenities.property_id = houses.property_id`)
+}
+
+// FilterByAmenities limits a house query to houses offering all of the amenities with the given slugs,
+// or any of them when matchAll is false. Units offer the amenities of their property too.
+func FilterByAmenities(query *gorm.DB, slugs []string, matchAll bool) *gorm.DB {
+	offering := config.DB.Table("(?) AS links", houseAmenityLinks()).
+		Select("links.house_id").
+		Joins("JOIN amenities ON amenities.id = links.amenity_id").
+		Where("amenities.slug IN ? AND amenities.is_active = ?", slugs, true)
+	if matchAll {
+		offering = offering.Group("links.house_id").
+			Having("COUNT(DISTINCT links.amenity_id) = ?", len(slugs))
+	}
+	return query.Where("houses.id IN (?)", offering)
+}
+
+// AmenityFacets counts, for every active amenity, how many of the houses selected by the given
+// house ID query offer it
+func AmenityFacets(houseIDs *gorm.DB) ([]AmenityFacet, error) {
+	facets := []AmenityFacet{}
+	err := config.DB.Model(&models.Amenity{}).
+		Select("amenities.id, amenities.name, amenities.slug, amenities.category, COUNT(links.house_id) AS count").
+		Joins("LEFT JOIN (?) AS links ON links.amenity_id = amenities.id AND links.house_id IN (?)", houseAmenityLinks(), houseIDs).
+		Where("amenities.is_active = ?", true).
+		Group("amenities.id").
+		Order("amenities.category ASC, amenities.name ASC").
+		Scan(&facets).Error
+	return facets, err
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// listingEventRetention is how long raw listing events are kept after they are rolled up
+const listingEventRetention = 90 * 24 * time.Hour
+
+// Similar listings are published houses of the same type, with a bedroom count within
+// similarBedroomRange, less than similarListingRadiusKm away
+const (
+	similarListingRadiusKm = 5.0
+	similarBedroomRange    = 1
+)
+
+// ListingMetrics holds a listing's event counts over a period
+type ListingMetrics struct {
+	Impressions    int64 `json:"impressions"`
+	Views          int64 `json:"views"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+	Favorites      int64 `json:"favorites"`
+	Viewings       int64 `json:"viewings"`
+	Agreements     int64 `json:"agreements"`
+}
+
+// ConversionRates are the shares of tenants moving from one step to the next, from 0 to 1
+type ConversionRates struct {
+	ViewRate      float64 `json:"view_rate"`      // views per impression
+	FavoriteRate  float64 `json:"favorite_rate"`  // favorites per view
+	ViewingRate   float64 `json:"viewing_rate"`   // viewings booked per view
+	AgreementRate float64 `json:"agreement_rate"` // agreements per viewing
+}
+
+// Rates returns the conversion rates of the metrics
+func (lm ListingMetrics) Rates() ConversionRates {
+	return ConversionRates{
+		ViewRate:      ratio(float64(lm.Views), float64(lm.Impressions)),
+		FavoriteRate:  ratio(float64(lm.Favorites), float64(lm.Views)),
+		ViewingRate:   ratio(float64(lm.Viewings), float64(lm.Views)),
+		AgreementRate: ratio(float64(lm.Agreements), float64(lm.Viewings)),
+	}
+}
+
+// Changes returns the percentage change of each metric from a previous period; nil when the
+// previous period had none
+func (lm ListingMetrics) Changes(previous ListingMetrics) map[string]*float64 {
+	return map[string]*float64{
+		"impressions":     percentChange(lm.Impressions, previous.Impressions),
+		"views":           percentChange(lm.Views, previous.Views),
+		"unique_visitors": percentChange(lm.UniqueVisitors, previous.UniqueVisitors),
+		"favorites":       percentChange(lm.Favorites, previous.Favorites),
+		"viewings":        percentChange(lm.Viewings, previous.Viewings),
+		"agreements":      percentChange(lm.Agreements, previous.Agreements),
+	}
+}
+
+// DailyListingMetrics holds event counts for one day
+type DailyListingMetrics struct {
+	Date string `json:"date"`
+	ListingMetrics
+}
+
+// metricSums is the select list summing daily stats into ListingMetrics
+const metricSums = "COALESCE(SUM(impressions), 0) AS impressions, COALESCE(SUM(views), 0) AS views, " +
+	"COALESCE(SUM(unique_visitors), 0) AS unique_visitors, COALESCE(SUM(favorites), 0) AS favorites, " +
+	"COALESCE(SUM(viewings), 0) AS viewings, COALESCE(SUM(agreements), 0) AS agreements"
+
+// RecordListingEvents records an event for each listing. Events are buffered and written in the
+// background; analytics never fail or slow down a request, so errors are only logged.
+func RecordListingEvents(kind models.ListingEventKind, houseIDs []uuid.UUID, viewerID *uuid.UUID, visitorKey string) {
+	if len(houseIDs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	events := make([]models.ListingEvent, 0, len(houseIDs))
+	for _, houseID := range houseIDs {
+		events = append(events, models.ListingEvent{
+			ID:         uuid.New(),
+			HouseID:    houseID,
+			Kind:       kind,
+			ViewerID:   viewerID,
+			VisitorKey: visitorKey,
+			CreatedAt:  now,
+		})
+	}
+	listingEventWriter.add(events)
+}
+
+// VisitorKey identifies an anonymous visitor for a day by their IP address and browser. The key is an
+// HMAC under the server secret over the date as well, so it cannot be reversed by trying addresses
+// and the same visitor cannot be followed from one day to the next.
+func VisitorKey(ip, userAgent string, day time.Time) string {
+	mac := hmac.New(sha256.New, []byte("listing-visitor|"+config.AppConfig.JWTSecret))
+	mac.Write([]byte(day.Format("2006-01-02") + "|" + ip + "|" + userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// SumListingMetrics adds up the daily stats of listings between two dates, inclusive
+func SumListingMetrics(houseIDs []uuid.UUID, from, to time.Time) (ListingMetrics, error) {
+	var metrics ListingMetrics
+	if len(houseIDs) == 0 {
+		return metrics, nil
+	}
+	err := config.DB.Model(&models.ListingDailyStat{}).Select(metricSums).
+		Where("house_id IN ? AND date BETWEEN ? AND ?", houseIDs, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Scan(&metrics).Error
+	return metrics, err
+}
+
+// ListingMetricsByHouse adds up the daily stats of each listing between two dates, inclusive.
+// Listings without stats are left out.
+func ListingMetricsByHouse(houseIDs []uuid.UUID, from, to time.Time) (map[uuid.UUID]ListingMetrics, error) {
+	byHouse := map[uuid.UUID]ListingMetrics{}
+	if len(houseIDs) == 0 {
+		return byHouse, nil
+	}
+
+	var rows []struct {
+		HouseID uuid.UUID
+		ListingMetrics
+	}
+	if err := config.DB.Model(&models.ListingDailyStat{}).Select("house_id, "+metricSums).
+		Where("house_id IN ? AND date BETWEEN ? AND ?", houseIDs, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Group("house_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		byHouse[row.HouseID] = row.ListingMetrics
+	}
+	return byHouse, nil
+}
+
+// DailyMetrics returns the combined event counts of listings for every day between two dates,
+// inclusive, with zeros for days without events
+func DailyMetrics(houseIDs []uuid.UUID, from, to time.Time) ([]DailyListingMetrics, error) {
+	var rows []struct {
+		Date time.Time
+		ListingMetrics
+	}
+	if len(houseIDs) > 0 {
+		if err := config.DB.Model(&models.ListingDailyStat{}).Select("date, "+metricSums).
+			Where("house_id IN ? AND date BETWEEN ? AND ?", houseIDs, from.Format("2006-01-02"), to.Format("2006-01-02")).
+			Group("date").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	byDate := make(map[string]ListingMetrics, len(rows))
+	for _, row := range rows {
+		byDate[row.Date.Format("2006-01-02")] = row.ListingMetrics
+	}
+
+	var days []DailyListingMetrics
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		days = append(days, DailyListingMetrics{Date: date, ListingMetrics: byDate[date]})
+	}
+	return days, nil
+}
+
+// SimilarListingIDs returns the published listings of the same type and about the same size near a
+// listing, for comparing its performance. Listings without coordinates have none.
+func SimilarListingIDs(house *models.House) ([]uuid.UUID, error) {
+	if house.Latitude == 0 && house.Longitude == 0 {
+		return nil, nil
+	}
+
+	distanceSQL, distanceArgs := DistanceSQL(house.Latitude, house.Longitude)
+	query := config.DB.Model(&models.House{}).
+		Where("houses.id <> ? AND listing_status = ? AND house_type = ? AND bedrooms BETWEEN ? AND ?",
+			house.ID, models.ListingPublished, house.HouseType,
+			house.Bedrooms-similarBedroomRange, house.Bedrooms+similarBedroomRange)
+	query = WithinBoundingBox(query, RadiusBoundingBox(house.Latitude, house.Longitude, similarListingRadiusKm))
+	query = query.Where(distanceSQL+" <= ?", append(distanceArgs, similarListingRadiusKm)...)
+
+	var ids []uuid.UUID
+	err := query.Pluck("houses.id", &ids).Error
+	return ids, err
+}
+
+// ListingComparison compares a listing's metrics with similar listings nearby
+type ListingComparison struct {
+	SimilarListings int             `json:"similar_listings"`
+	RadiusKm        float64         `json:"radius_km"`
+	Average         ListingMetrics  `json:"average"`          // per similar listing, rounded down
+	AverageRates    ConversionRates `json:"average_rates"`    // of the similar listings combined
+	ViewsPercentile *float64        `json:"views_percentile"` // share of similar listings with fewer views; nil without any
+}
+
+// CompareListingMetrics compares a listing's metrics over a period with those of similar listings nearby
+func CompareListingMetrics(house *models.House, metrics ListingMetrics, from, to time.Time) (*ListingComparison, error) {
+	comparison := &ListingComparison{RadiusKm: similarListingRadiusKm}
+
+	similarIDs, err := SimilarListingIDs(house)
+	if err != nil || len(similarIDs) == 0 {
+		return comparison, err
+	}
+	comparison.SimilarListings = len(similarIDs)
+
+	byHouse, err := ListingMetricsByHouse(similarIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var total ListingMetrics
+	views := make([]int64, 0, len(similarIDs))
+	for _, id := range similarIDs {
+		m := byHouse[id]
+		total.Impressions += m.Impressions
+		total.Views += m.Views
+		total.UniqueVisitors += m.UniqueVisitors
+		total.Favorites += m.Favorites
+		total.Viewings += m.Viewings
+		total.Agreements += m.Agreements
+		views = append(views, m.Views)
+	}
+
+	n := int64(len(similarIDs))
+	comparison.Average = ListingMetrics{
+		Impressions:    total.Impressions / n,
+		Views:          total.Views / n,
+		UniqueVisitors: total.UniqueVisitors / n,
+		Favorites:      total.Favorites / n,
+		Viewings:       total.Viewings / n,
+		Agreements:     total.Agreements / n,
+	}
+	comparison.AverageRates = total.Rates()
+
+	sort.Slice(views, func(i, j int) bool { return views[i] < views[j] })
+	fewer := sort.Search(len(views), func(i int) bool { return views[i] >= metrics.Views })
+	percentile := float64(fewer) / float64(len(views))
+	comparison.ViewsPercentile = &percentile
+	return comparison, nil
+}
+
+// AnalyticsService rolls listing events up into daily stats
+type AnalyticsService struct{}
+
+// NewAnalyticsService creates a new analytics service
+func NewAnalyticsService() *AnalyticsService {
+	return &AnalyticsService{}
+}
+
+// RollupListingEvents recounts the daily stats of the latest rolled up day and every day since, so
+// events recorded after a day was last rolled up are included, then deletes expired events
+func (as *AnalyticsService) RollupListingEvents() error {
+	FlushListingEvents()
+
+	var latest *time.Time
+	if err := config.DB.Model(&models.ListingDailyStat{}).Select("MAX(date)").Scan(&latest).Error; err != nil {
+		return err
+	}
+	since := time.Now().Add(-listingEventRetention)
+	if latest != nil && latest.After(since) {
+		since = *latest
+	}
+
+	result := config.DB.Exec(`INSERT INTO listing_daily_stats
+			(house_id, date, impressions, views, unique_visitors, favorites, viewings, agreements)
+		SELECT house_id, created_at::date,
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(DISTINCT visitor_key) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE kind = ?)
+		FROM listing_events
+		WHERE created_at >= ?
+		GROUP BY house_id, created_at::date
+		ON CONFLICT (house_id, date) DO UPDATE SET
+			impressions = EXCLUDED.impressions,
+			views = EXCLUDED.views,
+			unique_visitors = EXCLUDED.unique_visitors,
+			favorites = EXCLUDED.favorites,
+			viewings = EXCLUDED.viewings,
+			agreements = EXCLUDED.agreements`,
+		models.EventImpression, models.EventView, models.EventView,
+		models.EventFavorite, models.EventViewing, models.EventAgreement,
+		since.Format("2006-01-02"))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if err := config.DB.Where("created_at < ?", time.Now().Add(-listingEventRetention)).
+		Delete(&models.ListingEvent{}).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Rolled up listing events into %d daily stats", result.RowsAffected)
+	return nil
+}
+
+// ratio divides two numbers, returning 0 when the denominator is 0
+func ratio(numerator, denominator float64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// percentChange returns the percentage change from previous to current, or nil when previous is 0
+func percentChange(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := float64(current-previous) / float64(previous) * 100
+	return &change
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"bondihub/config"
+	"testing"
+	"time"
+)
+
+func TestVisitorKey(t *testing.T) {
+	useTestConfig(t)
+	day := time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC)
+	key := VisitorKey("102.145.1.7", "Mozilla/5.0", day)
+
+	if again := VisitorKey("102.145.1.7", "Mozilla/5.0", day.Add(10*time.Hour)); again != key {
+		t.Errorf("key changed within a day: %s, then %s", key, again)
+	}
+	if next := VisitorKey("102.145.1.7", "Mozilla/5.0", day.AddDate(0, 0, 1)); next == key {
+		t.Error("key did not change on the next day")
+	}
+	if other := VisitorKey("102.145.1.8", "Mozilla/5.0", day); other == key {
+		t.Error("different visitors have the same key")
+	}
+
+	config.AppConfig.JWTSecret = "another-secret"
+	if rekeyed := VisitorKey("102.145.1.7", "Mozilla/5.0", day); rekeyed == key {
+		t.Error("key does not depend on the server secret")
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BookedAgreementStatuses are the agreement statuses that hold a house for their date range
+var BookedAgreementStatuses = []models.AgreementStatus{models.AgreementStatusUpcoming, models.AgreementStatusActive}
+
+// FindOverlappingAgreement returns an upcoming or active agreement for the house whose dates overlap
+// the given range, or nil if the house is free. A new agreement may start on the day the previous one ends.
+// excludeID skips the agreement being changed.
+func FindOverlappingAgreement(tx *gorm.DB, houseID uuid.UUID, start, end time.Time, excludeID *uuid.UUID) (*models.RentalAgreement, error) {
+	query := tx.Where("house_id = ? AND status IN ?", houseID, BookedAgreementStatuses).
+		Where("start_date < ? AND LEAST(termination_date, end_date) > ?", end, start)
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+
+	var agreements []models.RentalAgreement
+	if err := query.Order("start_date ASC").Limit(1).Find(&agreements).Error; err != nil {
+		return nil, err
+	}
+	if len(agreements) == 0 {
+		return nil, nil
+	}
+	return &agreements[0], nil
+}
+
+// RefreshHouseAvailability recomputes a house's status and available-from date from its agreements.
+// Houses under maintenance keep their status.
+func RefreshHouseAvailability(tx *gorm.DB, houseID uuid.UUID) error {
+	var house models.House
+	if err := tx.First(&house, houseID).Error; err != nil {
+		return err
+	}
+
+	var agreements []models.RentalAgreement
+	if err := tx.Where("house_id = ? AND status IN ?", houseID, BookedAgreementStatuses).
+		Order("start_date ASC").
+		Find(&agreements).Error; err != nil {
+		return err
+	}
+
+	// Follow back-to-back agreements from today to find when the house is next free
+	today := time.Now().Truncate(24 * time.Hour)
+	freeFrom := today
+	occupied := false
+	for i := range agreements {
+		agreement := &agreements[i]
+		if agreement.Status == models.AgreementStatusActive {
+			occupied = true
+		}
+		if !agreement.StartDate.After(freeFrom) && agreement.OccupiedUntil().After(freeFrom) {
+			freeFrom = agreement.OccupiedUntil()
+		}
+	}
+
+	updates := map[string]interface{}{"available_from": nil}
+	if freeFrom.After(today) {
+		updates["available_from"] = freeFrom
+	}
+	if house.Status != models.StatusMaintenance {
+		if occupied {
+			updates["status"] = models.StatusOccupied
+		} else {
+			updates["status"] = models.StatusAvailable
+		}
+	}
+
+	return tx.Model(&house).Updates(updates).Error
+}
+
+// LockHouse locks a house row for the rest of the transaction so agreement date checks cannot race
+func LockHouse(tx *gorm.DB, houseID uuid.UUID) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.House{}, houseID).Error
+}
+
+// ActivateUpcomingAgreements starts pre-leased agreements whose start date has arrived
+func (rs *RentalService) ActivateUpcomingAgreements() error {
+	var agreements []models.RentalAgreement
+	if err := config.DB.Preload("House").
+		Where("status = ? AND start_date <= ?", models.AgreementStatusUpcoming, time.Now()).
+		Find(&agreements).Error; err != nil {
+		return fmt.Errorf("failed to load upcoming agreements: %w", err)
+	}
+
+	for i := range agreements {
+		agreement := &agreements[i]
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(agreement).Update("status", models.AgreementStatusActive).Error; err != nil {
+				return err
+			}
+			if err := RecordAgreementChange(tx, agreement.ID, nil, models.FieldChanges{
+				{Field: models.FieldStatus, From: models.AgreementStatusUpcoming, To: models.AgreementStatusActive},
+			}, "Agreement started"); err != nil {
+				return err
+			}
+			return RefreshHouseAvailability(tx, agreement.HouseID)
+		})
+		if err != nil {
+			log.Printf("Failed to activate agreement %s: %v", agreement.ID, err)
+			continue
+		}
+
+		message := fmt.Sprintf("Your rental agreement for %s starts today", agreement.House.Title)
+		NotifyAll(AgreementTenantIDs(agreement), "Rental Agreement Started", message, "agreement")
+		Notify(agreement.House.LandlordID, "Rental Agreement Started",
+			fmt.Sprintf("The rental agreement for %s has started", agreement.House.Title), "agreement")
+	}
+
+	return nil
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMeterReadingDecreased is returned when a meter reading is lower than the previous reading
+var ErrMeterReadingDecreased = errors.New("meter reading is lower than the previous reading")
+
+// ErrPaymentExceedsBalance is returned when a payment is larger than what is owed on the charges it settles
+var ErrPaymentExceedsBalance = errors.New("payment exceeds the outstanding balance")
+
+// BillingService handles rent and utility charges on agreements
+type BillingService struct{}
+
+// NewBillingService creates a new billing service instance
+func NewBillingService() *BillingService {
+	return &BillingService{}
+}
+
+// AgreementBalance summarises what has been billed to and paid on an agreement
+type AgreementBalance struct {
+	TotalCharged float64 `json:"total_charged"`
+	TotalPaid    float64 `json:"total_paid"`
+	Balance      float64 `json:"balance"` // positive when the tenants owe money
+}
+
+// GenerateMonthlyCharges bills rent and recurring service charges for each active agreement's current
+// billing month. Billing months run from each monthly anniversary of the agreement's start date.
+// Months missed since the last rent charge are caught up; agreements that have never been billed
+// start from the current month. A month is billed once even when runs overlap, as the charges of a
+// billing month are unique.
+func (bs *BillingService) GenerateMonthlyCharges() error {
+	var agreements []models.RentalAgreement
+	if err := config.DB.Preload("House").
+		Where("status = ?", models.AgreementStatusActive).
+		Find(&agreements).Error; err != nil {
+		return fmt.Errorf("failed to load agreements for billing: %w", err)
+	}
+
+	now := time.Now()
+	for i := range agreements {
+		agreement := &agreements[i]
+
+		var lastBilled *time.Time
+		var lastRent models.Charge
+		if err := config.DB.
+			Where("agreement_id = ? AND kind = ?", agreement.ID, models.ChargeKindRent).
+			Order("period_start DESC").
+			First(&lastRent).Error; err == nil {
+			lastBilled = &lastRent.PeriodStart
+		}
+
+		for _, period := range billingPeriods(agreement.StartDate, agreement.OccupiedUntil(), now, lastBilled) {
+			if err := bs.billMonth(agreement, period.Start, period.End); err != nil {
+				log.Printf("Failed to bill agreement %s for %s: %v", agreement.ID, period.Start.Format("2006-01-02"), err)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// billingPeriod is one billing month of an agreement
+type billingPeriod struct {
+	Start time.Time
+	End   time.Time
+}
+
+// billingPeriods returns the billing months of an agreement that are due by now: the months after the
+// last billed month, or only the current month when the agreement has never been billed. Months
+// starting on or after the agreement ends are not billed.
+func billingPeriods(start, occupiedUntil, now time.Time, lastBilled *time.Time) []billingPeriod {
+	var periods []billingPeriod
+	for month := 0; ; month++ {
+		periodStart := start.AddDate(0, month, 0)
+		periodEnd := start.AddDate(0, month+1, 0)
+		if periodStart.After(now) || !periodStart.Before(occupiedUntil) {
+			break
+		}
+		if lastBilled != nil && !periodStart.After(*lastBilled) {
+			continue
+		}
+		if lastBilled == nil && !periodEnd.After(now) {
+			continue
+		}
+		periods = append(periods, billingPeriod{Start: periodStart, End: periodEnd})
+	}
+	return periods
+}
+
+// billMonth creates the rent charge and recurring charges of one billing month and notifies the tenants.
+// Nothing is created when the month has already been billed.
+func (bs *BillingService) billMonth(agreement *models.RentalAgreement, periodStart, periodEnd time.Time) error {
+	var chargeTypes []models.ChargeType
+	if err := config.DB.Where("house_id = ? AND is_active = ?", agreement.HouseID, true).
+		Find(&chargeTypes).Error; err != nil {
+		return err
+	}
+
+	charges := []models.Charge{{
+		AgreementID: agreement.ID,
+		Kind:        models.ChargeKindRent,
+		Description: fmt.Sprintf("Rent %s to %s", periodStart.Format("2006-01-02"), periodEnd.AddDate(0, 0, -1).Format("2006-01-02")),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Amount:      RentOn(config.DB, agreement, periodStart),
+		DueDate:     periodStart,
+		Status:      models.ChargeStatusUnpaid,
+	}}
+
+	// Recurring charges, and the standing fee of metered charges
+	for i := range chargeTypes {
+		chargeType := &chargeTypes[i]
+		if chargeType.Amount <= 0 {
+			continue
+		}
+		description := chargeType.Name
+		if chargeType.Billing == models.BillingMetered {
+			description += " standing charge"
+		}
+		charges = append(charges, models.Charge{
+			AgreementID:  agreement.ID,
+			ChargeTypeID: &chargeType.ID,
+			Kind:         models.ChargeKindRecurring,
+			Description:  description,
+			PeriodStart:  periodStart,
+			PeriodEnd:    periodEnd,
+			Amount:       chargeType.Amount,
+			DueDate:      periodStart,
+			Status:       models.ChargeStatusUnpaid,
+		})
+	}
+
+	billed := false
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// The rent charge claims the month; another run that got there first leaves nothing to do
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&charges[0])
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		billed = true
+		if len(charges) == 1 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(charges[1:]).Error
+	}); err != nil || !billed {
+		return err
+	}
+
+	total := 0.0
+	for _, charge := range charges {
+		total += charge.Amount
+	}
+	NotifyAll(AgreementTenantIDs(agreement), "New Charges",
+		fmt.Sprintf("ZMW %.2f in rent and charges for %s is due on %s",
+			total, agreement.House.Title, periodStart.Format("2006-01-02")), "payment")
+
+	return nil
+}
+
+// ChargeMeterReading bills the consumption since the previous reading of a metered charge to the
+// agreement occupying the house on the reading date. The first reading of a meter only sets the baseline.
+// Returns nil when there is nothing to bill.
+func (bs *BillingService) ChargeMeterReading(tx *gorm.DB, chargeType *models.ChargeType, reading *models.MeterReading) (*models.Charge, error) {
+	var previous models.MeterReading
+	if err := tx.Where("charge_type_id = ? AND reading_date <= ? AND id <> ?", chargeType.ID, reading.ReadingDate, reading.ID).
+		Order("reading_date DESC, created_at DESC").
+		First(&previous).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	units := math.Round((reading.Reading-previous.Reading)*100) / 100
+	if units < 0 {
+		return nil, ErrMeterReadingDecreased
+	}
+
+	// The reading is billed to the agreement occupying the house on the reading date; should
+	// agreements overlap on that date, the one that started last is the current occupant.
+	var agreements []models.RentalAgreement
+	if err := tx.Where("house_id = ? AND status = ? AND start_date <= ?",
+		chargeType.HouseID, models.AgreementStatusActive, reading.ReadingDate).
+		Where("LEAST(termination_date, end_date) >= ?", reading.ReadingDate).
+		Order("start_date DESC, created_at DESC, id").
+		Limit(1).
+		Find(&agreements).Error; err != nil {
+		return nil, err
+	}
+	if len(agreements) == 0 {
+		return nil, nil
+	}
+
+	charge := models.Charge{
+		AgreementID:    agreements[0].ID,
+		ChargeTypeID:   &chargeType.ID,
+		MeterReadingID: &reading.ID,
+		Kind:           models.ChargeKindMetered,
+		Description: fmt.Sprintf("%s %.2f %s (%s to %s)", chargeType.Name, units, chargeType.Unit,
+			previous.ReadingDate.Format("2006-01-02"), reading.ReadingDate.Format("2006-01-02")),
+		PeriodStart: previous.ReadingDate,
+		PeriodEnd:   reading.ReadingDate,
+		Quantity:    units,
+		Amount:      chargeType.Tariff.Cost(units),
+		DueDate:     reading.ReadingDate.AddDate(0, 0, 14),
+		Status:      models.ChargeStatusUnpaid,
+	}
+	if err := tx.Create(&charge).Error; err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+// ApplyChargePayment adds a completed payment to the charge it was made against. tx must be a
+// transaction, as the charge is locked until it commits so concurrent payments add up.
+func (bs *BillingService) ApplyChargePayment(tx *gorm.DB, chargeID uuid.UUID, amount float64) error {
+	var charge models.Charge
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&charge, chargeID).Error; err != nil {
+		return err
+	}
+	return settleCharge(tx, &charge, amount)
+}
+
+// AllocatePayment settles the oldest open charges of an agreement with a completed payment that was not
+// made against a charge. Whatever is left once every open charge is paid stays on the agreement's
+// balance. tx must be a transaction, as the open charges are locked until it commits.
+func (bs *BillingService) AllocatePayment(tx *gorm.DB, agreementID uuid.UUID, amount float64) error {
+	var charges []models.Charge
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("agreement_id = ? AND status <> ?", agreementID, models.ChargeStatusPaid).
+		Order("due_date ASC, period_start ASC, created_at ASC").
+		Find(&charges).Error; err != nil {
+		return err
+	}
+
+	owed := make([]float64, len(charges))
+	for i, charge := range charges {
+		owed[i] = charge.Amount - charge.AmountPaid
+	}
+	for i, share := range allocatePayment(amount, owed) {
+		if share <= 0 {
+			continue
+		}
+		if err := settleCharge(tx, &charges[i], share); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocatePayment splits a payment over amounts owed, oldest first, rounding each share to cents
+func allocatePayment(amount float64, owed []float64) []float64 {
+	shares := make([]float64, len(owed))
+	remaining := math.Round(amount*100) / 100
+	for i, due := range owed {
+		if remaining <= 0 {
+			break
+		}
+		share := math.Round(math.Min(remaining, due)*100) / 100
+		if share <= 0 {
+			continue
+		}
+		shares[i] = share
+		remaining = math.Round((remaining-share)*100) / 100
+	}
+	return shares
+}
+
+// settleCharge adds a payment to a locked charge and updates its status
+func settleCharge(tx *gorm.DB, charge *models.Charge, amount float64) error {
+	charge.AmountPaid = math.Round((charge.AmountPaid+amount)*100) / 100
+	switch {
+	case charge.AmountPaid >= charge.Amount:
+		charge.Status = models.ChargeStatusPaid
+	case charge.AmountPaid > 0:
+		charge.Status = models.ChargeStatusPartial
+	}
+
+	return tx.Model(charge).Updates(map[string]interface{}{
+		"amount_paid": charge.AmountPaid,
+		"status":      charge.Status,
+	}).Error
+}
+
+// CheckPaymentAmount returns ErrPaymentExceedsBalance when a payment is larger than what is still owed
+// on the charge it is made against, or on all open charges of the agreement when it names no charge.
+// Agreements that have not been billed yet take payments such as the deposit without a limit.
+func (bs *BillingService) CheckPaymentAmount(agreementID uuid.UUID, chargeID *uuid.UUID, amount float64) error {
+	query := config.DB.Model(&models.Charge{}).Where("agreement_id = ?", agreementID)
+	if chargeID != nil {
+		query = query.Where("id = ?", *chargeID)
+	} else {
+		var billed int64
+		if err := config.DB.Model(&models.Charge{}).Where("agreement_id = ?", agreementID).Count(&billed).Error; err != nil {
+			return err
+		}
+		if billed == 0 {
+			return nil
+		}
+	}
+
+	var owed float64
+	if err := query.Where("status <> ?", models.ChargeStatusPaid).
+		Select("COALESCE(SUM(amount - amount_paid), 0)").
+		Scan(&owed).Error; err != nil {
+		return err
+	}
+	if math.Round(amount*100) > math.Round(owed*100) {
+		return fmt.Errorf("%w of %.2f", ErrPaymentExceedsBalance, owed)
+	}
+	return nil
+}
+
+// Balance returns the total charged, total paid and outstanding balance of an agreement. Payments made
+// before billing started, such as the deposit or rent paid up front, only count when they were made
+// against a charge.
+func (bs *BillingService) Balance(agreementID uuid.UUID) AgreementBalance {
+	var balance AgreementBalance
+	config.DB.Model(&models.Charge{}).
+		Where("agreement_id = ?", agreementID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&balance.TotalCharged)
+	config.DB.Model(&models.Payment{}).
+		Where("agreement_id = ? AND status = ?", agreementID, models.PaymentStatusCompleted).
+		Where("charge_id IS NOT NULL OR payment_date >= (SELECT MIN(period_start) FROM charges WHERE agreement_id = ?)", agreementID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&balance.TotalPaid)
+
+	balance.Balance = math.Round((balance.TotalCharged-balance.TotalPaid)*100) / 100
+	return balance
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBillingPeriods(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	start := date(2026, time.January, 15)
+	end := date(2027, time.January, 15)
+
+	tests := []struct {
+		name          string
+		start         time.Time
+		occupiedUntil time.Time
+		now           time.Time
+		lastBilled    *time.Time
+		want          []time.Time // period starts
+	}{
+		{
+			name:          "never billed starts from the current month",
+			start:         start,
+			occupiedUntil: end,
+			now:           date(2026, time.April, 20),
+			want:          []time.Time{date(2026, time.April, 15)},
+		},
+		{
+			name:          "never billed before the first anniversary",
+			start:         start,
+			occupiedUntil: end,
+			now:           date(2026, time.February, 1),
+			want:          []time.Time{start},
+		},
+		{
+			name:          "current month already billed",
+			start:         start,
+			occupiedUntil: end,
+			now:           date(2026, time.April, 20),
+			lastBilled:    timePtr(date(2026, time.April, 15)),
+		},
+		{
+			name:          "missed months are caught up",
+			start:         start,
+			occupiedUntil: end,
+			now:           date(2026, time.June, 15),
+			lastBilled:    timePtr(date(2026, time.March, 15)),
+			want:          []time.Time{date(2026, time.April, 15), date(2026, time.May, 15), date(2026, time.June, 15)},
+		},
+		{
+			name:          "no months after the agreement ends",
+			start:         start,
+			occupiedUntil: date(2026, time.May, 15),
+			now:           date(2026, time.July, 1),
+			lastBilled:    timePtr(date(2026, time.February, 15)),
+			want:          []time.Time{date(2026, time.March, 15), date(2026, time.April, 15)},
+		},
+		{
+			name:          "not started yet",
+			start:         date(2026, time.August, 1),
+			occupiedUntil: date(2027, time.August, 1),
+			now:           date(2026, time.July, 1),
+		},
+		{
+			name:          "month-end start dates roll over like time.AddDate",
+			start:         date(2026, time.January, 31),
+			occupiedUntil: end,
+			now:           date(2026, time.March, 31),
+			lastBilled:    timePtr(date(2026, time.January, 31)),
+			want:          []time.Time{date(2026, time.March, 3), date(2026, time.March, 31)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods := billingPeriods(tt.start, tt.occupiedUntil, tt.now, tt.lastBilled)
+			if len(periods) != len(tt.want) {
+				t.Fatalf("got %d periods %v, want %d", len(periods), periods, len(tt.want))
+			}
+			for i, period := range periods {
+				if !period.Start.Equal(tt.want[i]) {
+					t.Errorf("period %d starts %s, want %s", i, period.Start.Format("2006-01-02"), tt.want[i].Format("2006-01-02"))
+				}
+				if !period.End.After(period.Start) {
+					t.Errorf("period %d ends %s, not after its start", i, period.End.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+func TestAllocatePayment(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		owed   []float64
+		want   []float64
+	}{
+		{
+			name:   "settles the oldest charge first",
+			amount: 3000,
+			owed:   []float64{2500, 2500, 400},
+			want:   []float64{2500, 500, 0},
+		},
+		{
+			name:   "exact balance settles everything",
+			amount: 5400,
+			owed:   []float64{2500, 2500, 400},
+			want:   []float64{2500, 2500, 400},
+		},
+		{
+			name:   "overpayment leaves the rest unallocated",
+			amount: 1000,
+			owed:   []float64{250.5},
+			want:   []float64{250.5},
+		},
+		{
+			name:   "cents are carried without drift",
+			amount: 0.3,
+			owed:   []float64{0.1, 0.1, 0.1},
+			want:   []float64{0.1, 0.1, 0.1},
+		},
+		{
+			name:   "settled charges are skipped",
+			amount: 100,
+			owed:   []float64{0, 150},
+			want:   []float64{0, 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := allocatePayment(tt.amount, tt.owed)
+			for i, share := range shares {
+				if share != tt.want[i] {
+					t.Errorf("shares = %v, want %v", shares, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
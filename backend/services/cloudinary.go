@@ -6,15 +6,25 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/google/uuid"
 )
 
 // CloudinaryService handles image uploads to Cloudinary
 type CloudinaryService struct {
-	cld *cloudinary.Cloudinary
+	cld    *cloudinary.Cloudinary
+	client *http.Client // fetches delivered images to hash them
 }
 
 // NewCloudinaryService creates a new Cloudinary service instance
@@ -42,24 +52,14 @@ func NewCloudinaryService() (*CloudinaryService, error) {
 		}
 	}
 
-	return &CloudinaryService{cld: cld}, nil
+	return &CloudinaryService{cld: cld, client: &http.Client{Timeout: 30 * time.Second}}, nil
 }
 
-// UploadImage uploads an image file to Cloudinary
+// UploadImage uploads an image file to Cloudinary, streaming it rather than reading it into memory
 func (cs *CloudinaryService) UploadImage(ctx context.Context, file multipart.File, folder string) (*uploader.UploadResult, error) {
-	// Read file content
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Convert bytes to Reader for Cloudinary
-	fileReader := bytes.NewReader(fileBytes)
-
-	// Upload to Cloudinary
 	result, err := cs.cld.Upload.Upload(
 		ctx,
-		fileReader,
+		file,
 		uploader.UploadParams{
 			Folder:         folder,
 			ResourceType:   "image",
@@ -112,7 +112,7 @@ func (cs *CloudinaryService) DeleteImage(ctx context.Context, publicID string) (
 // GetImageURL generates a Cloudinary URL for an image
 func (cs *CloudinaryService) GetImageURL(publicID string, transformations string) string {
 	return fmt.Sprintf("https://res.cloudinary.com/%s/image/upload/%s/%s",
-		config.AppConfig.CloudinaryCloud, transformations, publicID)
+		cs.cld.Config.Cloud.CloudName, transformations, publicID)
 }
 
 // GetOptimizedImageURL generates an optimized image URL
@@ -120,3 +120,131 @@ func (cs *CloudinaryService) GetOptimizedImageURL(publicID string, width, height
 	transformations := fmt.Sprintf("f_auto,q_auto,w_%d,h_%d,c_fill", width, height)
 	return cs.GetImageURL(publicID, transformations)
 }
+
+// cloudinaryTransformations maps image sizes to Cloudinary transformations
+var cloudinaryTransformations = map[ImageSize]string{
+	ImageSizeOriginal:  "f_auto,q_auto",
+	ImageSizeMedium:    "f_auto,q_auto,w_800,h_600,c_limit",
+	ImageSizeThumbnail: "f_auto,q_auto,w_300,h_200,c_fill",
+}
+
+// Upload uploads an image to Cloudinary as part of the ImageStorage interface
+func (cs *CloudinaryService) Upload(ctx context.Context, image io.Reader, folder string) (*StoredImage, error) {
+	result, err := cs.cld.Upload.Upload(
+		ctx,
+		image,
+		uploader.UploadParams{
+			Folder:         folder,
+			ResourceType:   "image",
+			Transformation: "f_auto,q_auto",
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+	if result.Error.Message != "" {
+		return nil, fmt.Errorf("failed to upload image: %s", result.Error.Message)
+	}
+
+	return &StoredImage{PublicID: result.PublicID, URL: result.SecureURL}, nil
+}
+
+// Delete removes an image from Cloudinary as part of the ImageStorage interface
+func (cs *CloudinaryService) Delete(ctx context.Context, publicID string) error {
+	_, err := cs.DeleteImage(ctx, publicID)
+	return err
+}
+
+// URL returns the Cloudinary delivery URL of an image at the given size
+func (cs *CloudinaryService) URL(publicID string, size ImageSize) string {
+	transformations, ok := cloudinaryTransformations[size]
+	if !ok {
+		transformations = cloudinaryTransformations[ImageSizeOriginal]
+	}
+	return cs.GetImageURL(publicID, transformations)
+}
+
+// cloudinaryUploadFormats are the formats direct uploads to Cloudinary are restricted to
+var cloudinaryUploadFormats = map[string]bool{"jpg": true, "jpeg": true, "png": true, "webp": true}
+
+// cloudinaryIncomingTransformation is applied to direct uploads before Cloudinary stores them.
+// Re-encoding the image drops its EXIF data, including the GPS position the photo was taken at, which
+// would otherwise stay on the original.
+const cloudinaryIncomingTransformation = "q_auto:best"
+
+// SignUpload returns signed parameters for uploading one image straight to Cloudinary. The public ID
+// and incoming transformation are fixed by the signature, so the client cannot choose where the image
+// is stored or keep its metadata.
+func (cs *CloudinaryService) SignUpload(folder string, expiresAt time.Time) (*DirectUpload, error) {
+	cloud := cs.cld.Config.Cloud
+	publicID := folder + "/" + uuid.New().String()
+
+	// Cloudinary accepts signed parameters for an hour after their timestamp; the upload token
+	// returned alongside limits how long the upload can be confirmed
+	params := url.Values{}
+	params.Set("public_id", publicID)
+	params.Set("allowed_formats", "jpg,png,webp")
+	params.Set("transformation", cloudinaryIncomingTransformation)
+	params.Set("timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	signature, err := api.SignParameters(params, cloud.APISecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload: %w", err)
+	}
+
+	return &DirectUpload{
+		URL:    fmt.Sprintf("https://api.cloudinary.com/v1_1/%s/image/upload", cloud.CloudName),
+		Method: "POST",
+		Fields: map[string]string{
+			"api_key":         cloud.APIKey,
+			"public_id":       publicID,
+			"allowed_formats": params.Get("allowed_formats"),
+			"transformation":  params.Get("transformation"),
+			"timestamp":       params.Get("timestamp"),
+			"signature":       signature,
+		},
+		FileField: "file",
+		PublicID:  publicID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ConfirmUpload checks an image uploaded straight to Cloudinary against the upload limits. Rejected
+// images are deleted. Metadata was stripped by the incoming transformation signed into the upload.
+func (cs *CloudinaryService) ConfirmUpload(ctx context.Context, publicID, folder string) (*StoredImage, error) {
+	if !strings.HasPrefix(publicID, folder+"/") {
+		return nil, ErrUploadNotFound
+	}
+
+	asset, err := cs.cld.Admin.Asset(ctx, admin.AssetParams{PublicID: publicID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploaded image: %w", err)
+	}
+	if asset.Error.Message != "" || asset.PublicID == "" {
+		return nil, ErrUploadNotFound
+	}
+
+	maxDimension := config.AppConfig.MaxImageDimension
+	var invalid *ImageValidationError
+	switch {
+	case !cloudinaryUploadFormats[asset.Format]:
+		invalid = &ImageValidationError{Message: "Only JPEG, PNG and WebP images are accepted"}
+	case int64(asset.Bytes) > int64(config.AppConfig.MaxImageSizeMB)<<20:
+		invalid = &ImageValidationError{Message: fmt.Sprintf("Image is larger than %d MB", config.AppConfig.MaxImageSizeMB)}
+	case asset.Width < minImageDimension || asset.Height < minImageDimension:
+		invalid = &ImageValidationError{Message: fmt.Sprintf("Image must be at least %dx%d pixels", minImageDimension, minImageDimension)}
+	case asset.Width > maxDimension || asset.Height > maxDimension:
+		invalid = &ImageValidationError{Message: fmt.Sprintf("Image must be at most %dx%d pixels", maxDimension, maxDimension)}
+	}
+	if invalid != nil {
+		cs.Delete(ctx, publicID)
+		return nil, invalid
+	}
+
+	stored := &StoredImage{PublicID: asset.PublicID, URL: cs.URL(asset.PublicID, ImageSizeOriginal)}
+	if hash, err := FetchImageHash(ctx, cs.client, cs.URL(asset.PublicID, ImageSizeMedium)); err == nil {
+		stored.Hash = &hash
+	} else {
+		log.Printf("Failed to hash uploaded image %s: %v", asset.PublicID, err)
+	}
+	return stored, nil
+}
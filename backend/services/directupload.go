@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DirectUploadTTL is how long signed upload parameters stay valid
+const DirectUploadTTL = 15 * time.Minute
+
+// stagingFolder holds direct uploads until they are confirmed. Storages that cannot process images on
+// delivery run confirmed uploads through the same validation and clean-up as uploads through the API.
+const stagingFolder = "staging"
+
+// LocalDirectUploadRoute is the API path the local backend accepts direct uploads on
+const LocalDirectUploadRoute = "/uploads/direct"
+
+// ErrUploadNotFound is returned when a direct upload is confirmed but nothing was uploaded
+var ErrUploadNotFound = errors.New("uploaded image not found")
+
+// DirectUpload tells a client how to upload one image straight to image storage
+type DirectUpload struct {
+	URL       string            `json:"url"`        // where to send the file
+	Method    string            `json:"method"`     // always POST, as multipart form data
+	Fields    map[string]string `json:"fields"`     // form fields to send before the file, unchanged
+	FileField string            `json:"file_field"` // form field to send the file in
+	PublicID  string            `json:"public_id"`  // identifies the upload when it is confirmed
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// DirectUploadStorage is implemented by image storages that clients can upload to directly, so that
+// images do not pass through the API server
+type DirectUploadStorage interface {
+	// SignUpload returns parameters that let a client upload one image until expiresAt
+	SignUpload(folder string, expiresAt time.Time) (*DirectUpload, error)
+	// ConfirmUpload checks a direct upload and makes it a stored image in the folder. It returns
+	// ErrUploadNotFound when nothing was uploaded and an ImageValidationError when the image is rejected.
+	ConfirmUpload(ctx context.Context, publicID, folder string) (*StoredImage, error)
+}
+
+// SignUploadToken binds a direct upload to a scope, such as a house, until it expires. The client
+// returns the token when confirming the upload, so an upload cannot be claimed for another scope.
+func SignUploadToken(scope, publicID string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + uploadSignature("token", scope, publicID, expires)
+}
+
+// VerifyUploadToken checks an upload token issued by SignUploadToken
+func VerifyUploadToken(token, scope, publicID string) error {
+	expires, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(uploadSignature("token", scope, publicID, expires))) {
+		return errors.New("invalid upload token")
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return errors.New("upload token has expired")
+	}
+	return nil
+}
+
+// uploadSignature signs the parts of a direct upload with the server secret
+func uploadSignature(parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	mac.Write([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// QueueUnconfirmedUpload queues a direct upload for deletion once it can no longer be confirmed, so
+// files uploaded but never confirmed do not stay in any image storage. Confirming the upload cancels
+// the deletion (see CancelUnconfirmedUpload).
+func QueueUnconfirmedUpload(publicID string, expiresAt time.Time) error {
+	return config.DB.Create(&models.ImageDeletion{
+		PublicID:      publicID,
+		NextAttemptAt: expiresAt.Add(DirectUploadTTL),
+	}).Error
+}
+
+// CancelUnconfirmedUpload keeps a confirmed direct upload from being deleted as unconfirmed
+func CancelUnconfirmedUpload(publicID string) error {
+	return config.DB.Where("public_id = ? AND attempts = 0", publicID).Delete(&models.ImageDeletion{}).Error
+}
+
+// newStagingKey returns a unique key for a direct upload awaiting confirmation
+func newStagingKey() string {
+	return stagingFolder + "/" + uuid.New().String()
+}
+
+// isStagingKey reports whether a key names a direct upload awaiting confirmation
+func isStagingKey(key string) bool {
+	rest, found := strings.CutPrefix(key, stagingFolder+"/")
+	return found && rest != "" && !strings.Contains(rest, "/") && !strings.Contains(rest, "..")
+}
+
+// SignUpload returns parameters for uploading an image to the local stand-in upload endpoint.
+// The local backend lets development and tests exercise direct uploads without any external service.
+func (ls *LocalStorage) SignUpload(folder string, expiresAt time.Time) (*DirectUpload, error) {
+	key := newStagingKey()
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return &DirectUpload{
+		URL:    strings.TrimRight(config.AppConfig.APIBaseURL, "/") + LocalDirectUploadRoute,
+		Method: "POST",
+		Fields: map[string]string{
+			"key":       key,
+			"expires":   expires,
+			"signature": uploadSignature("local", key, expires),
+		},
+		FileField: "file",
+		PublicID:  key,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// SaveDirectUpload stores a file sent to the local stand-in upload endpoint, after checking the
+// parameters were signed by SignUpload and have not expired
+func (ls *LocalStorage) SaveDirectUpload(key, expires, signature string, file io.Reader) error {
+	if !isStagingKey(key) || !hmac.Equal([]byte(signature), []byte(uploadSignature("local", key, expires))) {
+		return errors.New("invalid upload signature")
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return errors.New("upload signature has expired")
+	}
+
+	filePath, err := ls.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(ls.dir, stagingFolder), 0o755); err != nil {
+		return fmt.Errorf("failed to create upload folder: %w", err)
+	}
+
+	// Reject oversized files like a storage service would
+	maxBytes := int64(config.AppConfig.MaxImageSizeMB) << 20
+	if err := writeFile(filePath, io.LimitReader(file, maxBytes+1)); err != nil {
+		return err
+	}
+	if info, err := os.Stat(filePath); err == nil && info.Size() > maxBytes {
+		os.Remove(filePath)
+		return &ImageValidationError{Message: fmt.Sprintf("Image is larger than %d MB", config.AppConfig.MaxImageSizeMB)}
+	}
+	return nil
+}
+
+// ConfirmUpload runs a direct upload through image validation into the folder and removes the staged file
+func (ls *LocalStorage) ConfirmUpload(ctx context.Context, publicID, folder string) (*StoredImage, error) {
+	if !isStagingKey(publicID) {
+		return nil, ErrUploadNotFound
+	}
+	filePath, err := ls.filePath(publicID)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filePath)
+	defer file.Close()
+
+	return UploadImage(ctx, ls, file, folder)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bondihub/config"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestConfig loads the default configuration with local storage in a temporary directory
+func useTestConfig(t *testing.T) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = config.Load()
+	config.AppConfig.JWTSecret = "test-secret"
+	config.AppConfig.LocalStorageDir = t.TempDir()
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+func TestUploadToken(t *testing.T) {
+	useTestConfig(t)
+	valid := SignUploadToken("house:1", "staging/a", time.Now().Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		token    string
+		scope    string
+		publicID string
+		wantErr  bool
+	}{
+		{"valid", valid, "house:1", "staging/a", false},
+		{"other scope", valid, "house:2", "staging/a", true},
+		{"other upload", valid, "house:1", "staging/b", true},
+		{"expired", SignUploadToken("house:1", "staging/a", time.Now().Add(-time.Minute)), "house:1", "staging/a", true},
+		{"extended expiry", strings.Replace(valid, strings.Split(valid, ".")[0], "9999999999", 1), "house:1", "staging/a", true},
+		{"no signature", strings.Split(valid, ".")[0], "house:1", "staging/a", true},
+		{"empty", "", "house:1", "staging/a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyUploadToken(tt.token, tt.scope, tt.publicID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyUploadToken() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	config.AppConfig.JWTSecret = "rotated-secret"
+	if err := VerifyUploadToken(valid, "house:1", "staging/a"); err == nil {
+		t.Error("token signed with another secret was accepted")
+	}
+}
+
+func TestLocalDirectUpload(t *testing.T) {
+	useTestConfig(t)
+	storage, err := NewLocalStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	photo := image.NewRGBA(image.Rect(0, 0, minImageDimension, minImageDimension))
+	for x := 0; x < minImageDimension; x++ {
+		for y := 0; y < minImageDimension; y++ {
+			photo.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, photo); err != nil {
+		t.Fatal(err)
+	}
+
+	upload, err := storage.SignUpload("bondihub/houses", time.Now().Add(DirectUploadTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := upload.Fields
+	if !isStagingKey(upload.PublicID) || fields["key"] != upload.PublicID {
+		t.Fatalf("upload is not staged: public ID %q, key %q", upload.PublicID, fields["key"])
+	}
+
+	t.Run("rejects a tampered signature", func(t *testing.T) {
+		err := storage.SaveDirectUpload(fields["key"], fields["expires"], fields["signature"]+"0", bytes.NewReader(encoded.Bytes()))
+		if err == nil {
+			t.Fatal("upload with a tampered signature was saved")
+		}
+	})
+
+	t.Run("rejects another key", func(t *testing.T) {
+		err := storage.SaveDirectUpload(newStagingKey(), fields["expires"], fields["signature"], bytes.NewReader(encoded.Bytes()))
+		if err == nil {
+			t.Fatal("upload to a key it was not signed for was saved")
+		}
+	})
+
+	t.Run("rejects keys outside staging", func(t *testing.T) {
+		for _, key := range []string{"bondihub/houses/x", "staging/../x", "staging/", "staging/a/b"} {
+			if isStagingKey(key) {
+				t.Errorf("isStagingKey(%q) = true", key)
+			}
+		}
+	})
+
+	t.Run("rejects an extended expiry", func(t *testing.T) {
+		err := storage.SaveDirectUpload(fields["key"], "9999999999", fields["signature"], bytes.NewReader(encoded.Bytes()))
+		if err == nil {
+			t.Fatal("upload with an extended expiry was saved")
+		}
+	})
+
+	t.Run("confirming before uploading finds nothing", func(t *testing.T) {
+		if _, err := storage.ConfirmUpload(context.Background(), upload.PublicID, "bondihub/houses"); !errors.Is(err, ErrUploadNotFound) {
+			t.Fatalf("ConfirmUpload() error = %v, want ErrUploadNotFound", err)
+		}
+	})
+
+	t.Run("uploads and confirms once", func(t *testing.T) {
+		if err := storage.SaveDirectUpload(fields["key"], fields["expires"], fields["signature"], bytes.NewReader(encoded.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := storage.ConfirmUpload(context.Background(), upload.PublicID, "bondihub/houses")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(stored.PublicID, "bondihub/houses/") || stored.Hash == nil {
+			t.Errorf("stored image %q with hash %v, want a hashed image in bondihub/houses", stored.PublicID, stored.Hash)
+		}
+		if _, err := os.Stat(filepath.Join(storage.Dir(), filepath.FromSlash(stored.PublicID))); err != nil {
+			t.Errorf("confirmed image was not stored: %v", err)
+		}
+
+		if _, err := storage.ConfirmUpload(context.Background(), upload.PublicID, "bondihub/houses"); !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("second ConfirmUpload() error = %v, want ErrUploadNotFound", err)
+		}
+	})
+
+	t.Run("rejects files that are not images", func(t *testing.T) {
+		other, err := storage.SignUpload("bondihub/houses", time.Now().Add(DirectUploadTTL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.SaveDirectUpload(other.Fields["key"], other.Fields["expires"], other.Fields["signature"], strings.NewReader("not an image")); err != nil {
+			t.Fatal(err)
+		}
+		var invalid *ImageValidationError
+		if _, err := storage.ConfirmUpload(context.Background(), other.PublicID, "bondihub/houses"); !errors.As(err, &invalid) {
+			t.Fatalf("ConfirmUpload() error = %v, want an ImageValidationError", err)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BuildEscalationSchedule returns an increase on every interval anniversary of the start date before the end date
+func BuildEscalationSchedule(start, end time.Time, escalationType models.EscalationType, value float64, intervalMonths int) []models.RentEscalation {
+	var schedule []models.RentEscalation
+	for step := 1; ; step++ {
+		effectiveDate := start.AddDate(0, step*intervalMonths, 0)
+		if !effectiveDate.Before(end) {
+			break
+		}
+		schedule = append(schedule, models.RentEscalation{
+			EffectiveDate: effectiveDate,
+			Type:          escalationType,
+			Value:         value,
+		})
+	}
+	return schedule
+}
+
+// SyncEscalationSchedule replaces the pending increases of an agreement that fall after the given date
+// with the anniversaries of its escalation clause after that date. Increases on or before the date
+// are kept, as are notices already sent for an unchanged date.
+func SyncEscalationSchedule(tx *gorm.DB, agreement *models.RentalAgreement, after time.Time) error {
+	pending := PendingEscalations(tx, agreement.ID)
+
+	if err := tx.Where("agreement_id = ? AND applied_at IS NULL AND effective_date > ?", agreement.ID, after).
+		Delete(&models.RentEscalation{}).Error; err != nil {
+		return err
+	}
+
+	schedule := escalationsAfter(agreement, after, pending)
+	if len(schedule) == 0 {
+		return nil
+	}
+	return tx.Create(&schedule).Error
+}
+
+// escalationsAfter returns the increases of an agreement's escalation clause after the given date,
+// carrying over when notice was sent from the pending increase on the same date
+func escalationsAfter(agreement *models.RentalAgreement, after time.Time, pending []models.RentEscalation) []models.RentEscalation {
+	terms := agreement.Escalation
+	if terms.Type == "" {
+		return nil
+	}
+
+	noticeSent := make(map[string]*time.Time, len(pending))
+	for _, escalation := range pending {
+		noticeSent[escalation.EffectiveDate.Format("2006-01-02")] = escalation.NoticeSentAt
+	}
+
+	var schedule []models.RentEscalation
+	for _, escalation := range BuildEscalationSchedule(agreement.StartDate, agreement.EndDate, terms.Type, terms.Value, terms.IntervalMonths) {
+		if !escalation.EffectiveDate.After(after) {
+			continue
+		}
+		escalation.AgreementID = agreement.ID
+		escalation.NoticeSentAt = noticeSent[escalation.EffectiveDate.Format("2006-01-02")]
+		schedule = append(schedule, escalation)
+	}
+	return schedule
+}
+
+// PendingEscalations returns the increases of an agreement that have not been applied yet, in date order
+func PendingEscalations(tx *gorm.DB, agreementID uuid.UUID) []models.RentEscalation {
+	var escalations []models.RentEscalation
+	tx.Where("agreement_id = ? AND applied_at IS NULL", agreementID).
+		Order("effective_date ASC").
+		Find(&escalations)
+	return escalations
+}
+
+// RentOn returns the rent due on an agreement on the given date, including scheduled increases
+// that have taken effect but have not been applied by the background job yet
+func RentOn(tx *gorm.DB, agreement *models.RentalAgreement, date time.Time) float64 {
+	rent := agreement.RentAmount
+	for _, escalation := range PendingEscalations(tx, agreement.ID) {
+		if escalation.EffectiveDate.After(date) {
+			break
+		}
+		rent = escalation.Apply(rent)
+	}
+	return rent
+}
+
+// ScaleTenantShares scales each tenant's rent share in proportion to a new rent amount.
+// Rounding differences go to the first (primary) tenant so the shares add up to the new rent.
+func ScaleTenantShares(shares []models.TenantShare, oldRent, newRent float64) []models.TenantShare {
+	scaled := make([]models.TenantShare, 0, len(shares))
+	total := 0.0
+	for _, share := range shares {
+		rentShare := newRent
+		if oldRent > 0 {
+			rentShare = math.Round(share.RentShare*newRent/oldRent*100) / 100
+		}
+		total += rentShare
+		scaled = append(scaled, models.TenantShare{TenantID: share.TenantID, RentShare: rentShare})
+	}
+	if len(scaled) > 0 {
+		scaled[0].RentShare = math.Round((scaled[0].RentShare+newRent-total)*100) / 100
+	}
+	return scaled
+}
+
+// ProcessRentEscalations notifies tenants of upcoming rent increases and applies the increases that are due
+func (rs *RentalService) ProcessRentEscalations() error {
+	now := time.Now()
+
+	// Advance notice of increases coming up within the notice window
+	var upcoming []models.RentEscalation
+	if err := config.DB.
+		Joins("JOIN rental_agreements ON rental_agreements.id = rent_escalations.agreement_id").
+		Where("rental_agreements.status IN ? AND rental_agreements.deleted_at IS NULL", BookedAgreementStatuses).
+		Where("rent_escalations.applied_at IS NULL AND rent_escalations.notice_sent_at IS NULL").
+		Where("rent_escalations.effective_date <= ?", now.AddDate(0, 0, config.AppConfig.EscalationLeadDays)).
+		Find(&upcoming).Error; err != nil {
+		return fmt.Errorf("failed to load upcoming rent escalations: %w", err)
+	}
+
+	for i := range upcoming {
+		escalation := &upcoming[i]
+
+		var agreement models.RentalAgreement
+		if err := config.DB.Preload("House").First(&agreement, escalation.AgreementID).Error; err != nil {
+			continue
+		}
+
+		message := fmt.Sprintf("The rent for %s will change from ZMW %.2f to ZMW %.2f on %s",
+			agreement.House.Title,
+			RentOn(config.DB, &agreement, escalation.EffectiveDate.AddDate(0, 0, -1)),
+			RentOn(config.DB, &agreement, escalation.EffectiveDate),
+			escalation.EffectiveDate.Format("2006-01-02"))
+		NotifyAll(AgreementTenantIDs(&agreement), "Upcoming Rent Increase", message, "agreement")
+		Notify(agreement.House.LandlordID, "Upcoming Rent Increase", message, "agreement")
+
+		if err := config.DB.Model(escalation).Update("notice_sent_at", now).Error; err != nil {
+			log.Printf("Failed to mark rent escalation %s as notified: %v", escalation.ID, err)
+		}
+	}
+
+	// Apply increases that have taken effect on active agreements, oldest first
+	var due []models.RentEscalation
+	if err := config.DB.
+		Joins("JOIN rental_agreements ON rental_agreements.id = rent_escalations.agreement_id").
+		Where("rental_agreements.status = ? AND rental_agreements.deleted_at IS NULL", models.AgreementStatusActive).
+		Where("rent_escalations.applied_at IS NULL AND rent_escalations.effective_date <= ?", now).
+		Order("rent_escalations.effective_date ASC").
+		Find(&due).Error; err != nil {
+		return fmt.Errorf("failed to load due rent escalations: %w", err)
+	}
+
+	for i := range due {
+		escalation := &due[i]
+		if err := rs.applyEscalation(escalation); err != nil {
+			log.Printf("Failed to apply rent escalation %s: %v", escalation.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// applyEscalation raises the agreement's rent and tenant shares, recording the increase as a new version
+func (rs *RentalService) applyEscalation(escalation *models.RentEscalation) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var agreement models.RentalAgreement
+		if err := tx.First(&agreement, escalation.AgreementID).Error; err != nil {
+			return err
+		}
+
+		newRent := escalation.Apply(agreement.RentAmount)
+		shares := AgreementTenantShares(tx, &agreement)
+		now := time.Now()
+
+		amendment := models.AgreementAmendment{
+			AgreementID: agreement.ID,
+			Status:      models.AmendmentStatusAccepted,
+			Changes: models.FieldChanges{
+				{Field: models.FieldRentAmount, From: agreement.RentAmount, To: newRent},
+				{Field: models.FieldTenants, From: shares, To: ScaleTenantShares(shares, agreement.RentAmount, newRent)},
+			},
+			Reason:        "Scheduled rent escalation",
+			EffectiveDate: escalation.EffectiveDate,
+			AcceptedAt:    &now,
+		}
+		if err := CreateAgreementVersion(tx, &amendment); err != nil {
+			return err
+		}
+		if err := rs.ApplyAmendment(tx, &amendment); err != nil {
+			return err
+		}
+
+		return tx.Model(escalation).Updates(map[string]interface{}{
+			"new_rent_amount": newRent,
+			"applied_at":      now,
+		}).Error
+	})
+}
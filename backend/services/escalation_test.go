@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bondihub/models"
+	"testing"
+	"time"
+)
+
+func TestBuildEscalationSchedule(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name           string
+		start, end     time.Time
+		intervalMonths int
+		want           []time.Time
+	}{
+		{
+			name:           "yearly over three years",
+			start:          date(2026, time.March, 1),
+			end:            date(2029, time.March, 1),
+			intervalMonths: 12,
+			want:           []time.Time{date(2027, time.March, 1), date(2028, time.March, 1)},
+		},
+		{
+			name:           "no increase on the end date",
+			start:          date(2026, time.March, 1),
+			end:            date(2027, time.March, 1),
+			intervalMonths: 12,
+		},
+		{
+			name:           "every six months",
+			start:          date(2026, time.January, 10),
+			end:            date(2027, time.June, 1),
+			intervalMonths: 6,
+			want:           []time.Time{date(2026, time.July, 10), date(2027, time.January, 10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := BuildEscalationSchedule(tt.start, tt.end, models.EscalationPercentage, 10, tt.intervalMonths)
+			if len(schedule) != len(tt.want) {
+				t.Fatalf("got %d increases, want %d", len(schedule), len(tt.want))
+			}
+			for i, escalation := range schedule {
+				if !escalation.EffectiveDate.Equal(tt.want[i]) {
+					t.Errorf("increase %d on %s, want %s", i, escalation.EffectiveDate.Format("2006-01-02"), tt.want[i].Format("2006-01-02"))
+				}
+				if escalation.Type != models.EscalationPercentage || escalation.Value != 10 {
+					t.Errorf("increase %d is %s %v, want percentage 10", i, escalation.Type, escalation.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestEscalationsAfter(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	agreement := &models.RentalAgreement{
+		StartDate: date(2026, time.January, 1),
+		EndDate:   date(2030, time.January, 1),
+		Escalation: models.EscalationTerms{
+			Type:           models.EscalationFixed,
+			Value:          500,
+			IntervalMonths: 12,
+		},
+	}
+	notified := date(2026, time.December, 1)
+	pending := []models.RentEscalation{
+		{EffectiveDate: date(2027, time.January, 1), NoticeSentAt: &notified},
+		{EffectiveDate: date(2028, time.January, 1)},
+	}
+
+	tests := []struct {
+		name         string
+		escalation   models.EscalationTerms
+		after        time.Time
+		want         []time.Time
+		wantNotified []bool
+	}{
+		{
+			name:         "whole schedule keeps sent notices",
+			escalation:   agreement.Escalation,
+			after:        agreement.StartDate,
+			want:         []time.Time{date(2027, time.January, 1), date(2028, time.January, 1), date(2029, time.January, 1)},
+			wantNotified: []bool{true, false, false},
+		},
+		{
+			name:         "increases on or before the date are left alone",
+			escalation:   agreement.Escalation,
+			after:        date(2027, time.January, 1),
+			want:         []time.Time{date(2028, time.January, 1), date(2029, time.January, 1)},
+			wantNotified: []bool{false, false},
+		},
+		{
+			name:         "changed interval drops the old dates",
+			escalation:   models.EscalationTerms{Type: models.EscalationFixed, Value: 500, IntervalMonths: 18},
+			after:        date(2026, time.June, 1),
+			want:         []time.Time{date(2027, time.July, 1), date(2029, time.January, 1)},
+			wantNotified: []bool{false, false},
+		},
+		{
+			name:  "escalation clause removed",
+			after: date(2026, time.June, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := *agreement
+			changed.Escalation = tt.escalation
+			schedule := escalationsAfter(&changed, tt.after, pending)
+			if len(schedule) != len(tt.want) {
+				t.Fatalf("got %d increases, want %d", len(schedule), len(tt.want))
+			}
+			for i, escalation := range schedule {
+				if !escalation.EffectiveDate.Equal(tt.want[i]) {
+					t.Errorf("increase %d on %s, want %s", i, escalation.EffectiveDate.Format("2006-01-02"), tt.want[i].Format("2006-01-02"))
+				}
+				if (escalation.NoticeSentAt != nil) != tt.wantNotified[i] {
+					t.Errorf("increase %d notice sent = %v, want %v", i, escalation.NoticeSentAt != nil, tt.wantNotified[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// Listing events are buffered in memory and written in bulk by a background writer, so recording the
+// impressions of a page of search results never holds up the response. When the buffer is full, new
+// events are dropped rather than blocking requests.
+const (
+	listingEventBufferSize    = 10000
+	listingEventBatchSize     = 500
+	listingEventFlushInterval = 2 * time.Second
+)
+
+// listingEventWriter is the buffer every listing event goes through
+var listingEventWriter = &eventWriter{
+	events: make(chan models.ListingEvent, listingEventBufferSize),
+	flush:  make(chan chan struct{}),
+}
+
+// eventWriter writes buffered listing events in batches from a single goroutine, started with the
+// first event
+type eventWriter struct {
+	events chan models.ListingEvent
+	flush  chan chan struct{}
+	start  sync.Once
+}
+
+// add buffers events without blocking
+func (w *eventWriter) add(events []models.ListingEvent) {
+	w.start.Do(func() { go w.run() })
+	for i, event := range events {
+		select {
+		case w.events <- event:
+		default:
+			log.Printf("Listing event buffer is full, dropped %d %s events", len(events)-i, event.Kind)
+			return
+		}
+	}
+}
+
+// run writes buffered events whenever a batch fills up, on each flush interval and when asked to flush
+func (w *eventWriter) run() {
+	ticker := time.NewTicker(listingEventFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.ListingEvent, 0, listingEventBatchSize)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := config.DB.CreateInBatches(batch, listingEventBatchSize).Error; err != nil {
+			log.Printf("Failed to record %d listing events: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event := <-w.events:
+			batch = append(batch, event)
+			if len(batch) >= listingEventBatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case done := <-w.flush:
+			for drained := false; !drained; {
+				select {
+				case event := <-w.events:
+					batch = append(batch, event)
+				default:
+					drained = true
+				}
+			}
+			write()
+			close(done)
+		}
+	}
+}
+
+// FlushListingEvents writes every buffered listing event and waits until they are stored
+func FlushListingEvents() {
+	listingEventWriter.start.Do(func() { go listingEventWriter.run() })
+	done := make(chan struct{})
+	listingEventWriter.flush <- done
+	<-done
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reverse geocoding snaps coordinates to the nearest area within areaSnapKm, otherwise to the
+// nearest town within townSnapKm
+const (
+	areaSnapKm = 3.0
+	townSnapKm = 25.0
+)
+
+// gazetteerCacheTTL is how long the location hierarchy is cached. Locations are seed data that admins
+// rarely add to; other API instances pick up additions within this time.
+const gazetteerCacheTTL = time.Hour
+
+// gazetteerCache holds the location hierarchy keyed by path, shared by all gazetteer geocoders
+var gazetteerCache struct {
+	sync.Mutex
+	locations map[string]*models.Location
+	loadedAt  time.Time
+}
+
+// InvalidateGazetteer makes the gazetteer reload the location hierarchy, after a location is added
+func InvalidateGazetteer() {
+	gazetteerCache.Lock()
+	defer gazetteerCache.Unlock()
+	gazetteerCache.locations = nil
+}
+
+// GazetteerGeocoder geocodes offline against the location hierarchy. Addresses are placed at the
+// centre of the most specific location they name, so its coordinates are approximate.
+type GazetteerGeocoder struct{}
+
+// NewGazetteerGeocoder creates a gazetteer geocoder
+func NewGazetteerGeocoder() *GazetteerGeocoder {
+	return &GazetteerGeocoder{}
+}
+
+// Geocode finds the location an address names. Addresses that only name a province are too vague
+// to place.
+func (gg *GazetteerGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	locations, err := gg.locations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	location := matchAddress(address, locations)
+	if location == nil || location.Level == models.LevelProvince {
+		return nil, ErrNoGeocodeMatch
+	}
+	return gazetteerResult(location, locations, location.Latitude, location.Longitude), nil
+}
+
+// matchAddress returns the location an address names, or nil. A location counts for more the more of
+// its ancestors the address names too, so "Ndeke, Kitwe" is the Kitwe Ndeke and not the Ndola one,
+// and deeper locations win ties. When equally good matches remain, the address is placed at the
+// deepest location containing all of them.
+func matchAddress(address string, locations map[string]*models.Location) *models.Location {
+	// Pad the words of the address with spaces, so names only match whole words
+	words := " " + strings.Join(strings.Fields(strings.ReplaceAll(models.LocationSlug(address), "-", " ")), " ") + " "
+	spans := map[string][][2]int{}
+	for path, location := range locations {
+		name := " " + strings.ReplaceAll(location.Slug, "-", " ") + " "
+		for offset := 0; ; {
+			i := strings.Index(words[offset:], name)
+			if i < 0 {
+				break
+			}
+			spans[path] = append(spans[path], [2]int{offset + i, offset + i + len(name)})
+			offset += i + 1
+		}
+	}
+
+	// A name only counts where it is not part of a longer name, so "Woodlands Extension" does not
+	// name Woodlands too
+	named := map[string]bool{}
+	for path, occurrences := range spans {
+		for _, span := range occurrences {
+			if !insideLongerName(span, spans) {
+				named[path] = true
+				break
+			}
+		}
+	}
+
+	var best []string
+	bestScore, bestDepth := 0, -1
+	for path := range named {
+		score := 0
+		for _, ancestor := range pathPrefixes(path) {
+			if named[ancestor] {
+				score++
+			}
+		}
+		depth := strings.Count(path, "/")
+		switch {
+		case score > bestScore || (score == bestScore && depth > bestDepth):
+			best, bestScore, bestDepth = []string{path}, score, depth
+		case score == bestScore && depth == bestDepth:
+			best = append(best, path)
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+
+	match := best[0]
+	for _, path := range best[1:] {
+		match = commonPath(match, path)
+	}
+	return locations[match]
+}
+
+// insideLongerName reports whether a span of an address lies within a longer location name found in it
+func insideLongerName(span [2]int, spans map[string][][2]int) bool {
+	for _, occurrences := range spans {
+		for _, other := range occurrences {
+			if other[1]-other[0] > span[1]-span[0] && other[0] <= span[0] && span[1] <= other[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReverseGeocode suggests the address of the nearest area, or of the nearest town when no area is close
+func (gg *GazetteerGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error) {
+	locations, err := gg.locations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nearest := map[models.LocationLevel]*models.Location{}
+	distances := map[models.LocationLevel]float64{}
+	for _, location := range locations {
+		if location.Level != models.LevelArea && location.Level != models.LevelTown {
+			continue
+		}
+		distance := haversineKm(latitude, longitude, location.Latitude, location.Longitude)
+		if nearest[location.Level] == nil || distance < distances[location.Level] {
+			nearest[location.Level] = location
+			distances[location.Level] = distance
+		}
+	}
+
+	switch {
+	case nearest[models.LevelArea] != nil && distances[models.LevelArea] <= areaSnapKm:
+		return gazetteerResult(nearest[models.LevelArea], locations, latitude, longitude), nil
+	case nearest[models.LevelTown] != nil && distances[models.LevelTown] <= townSnapKm:
+		return gazetteerResult(nearest[models.LevelTown], locations, latitude, longitude), nil
+	}
+	return nil, ErrNoGeocodeMatch
+}
+
+// locations returns the location hierarchy keyed by path, loading it when it is not cached. The
+// locations are shared and must not be modified.
+func (gg *GazetteerGeocoder) locations(ctx context.Context) (map[string]*models.Location, error) {
+	gazetteerCache.Lock()
+	defer gazetteerCache.Unlock()
+	if gazetteerCache.locations != nil && time.Since(gazetteerCache.loadedAt) < gazetteerCacheTTL {
+		return gazetteerCache.locations, nil
+	}
+
+	var loaded []models.Location
+	if err := config.DB.WithContext(ctx).Find(&loaded).Error; err != nil {
+		return nil, err
+	}
+	locations := make(map[string]*models.Location, len(loaded))
+	for i := range loaded {
+		locations[loaded[i].Path] = &loaded[i]
+	}
+	gazetteerCache.locations, gazetteerCache.loadedAt = locations, time.Now()
+	return locations, nil
+}
+
+// gazetteerResult builds the result for a location, formatting its address from the location up to
+// its province, e.g. "Kabulonga, Lusaka, Lusaka Province". A town and the district named after it
+// appear once.
+func gazetteerResult(location *models.Location, locations map[string]*models.Location, latitude, longitude float64) *GeocodeResult {
+	var names []string
+	prefixes := pathPrefixes(location.Path)
+	for i := len(prefixes) - 1; i >= 0; i-- {
+		node, ok := locations[prefixes[i]]
+		if !ok {
+			continue
+		}
+		name := node.Name
+		if node.Level == models.LevelProvince {
+			name += " Province"
+		}
+		if len(names) == 0 || names[len(names)-1] != name {
+			names = append(names, name)
+		}
+	}
+
+	locationID := location.ID
+	return &GeocodeResult{
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Address:    strings.Join(names, ", "),
+		LocationID: &locationID,
+		Precision:  string(location.Level),
+		Source:     "gazetteer",
+	}
+}
+
+// pathPrefixes returns the paths of a location's ancestors and itself, province first
+func pathPrefixes(path string) []string {
+	segments := strings.Split(path, "/")
+	prefixes := make([]string, len(segments))
+	for i := range segments {
+		prefixes[i] = strings.Join(segments[:i+1], "/")
+	}
+	return prefixes
+}
+
+// commonPath returns the path of the deepest location containing both locations, or "" when they
+// are in different provinces
+func commonPath(a, b string) string {
+	aSegments, bSegments := strings.Split(a, "/"), strings.Split(b, "/")
+	n := 0
+	for n < len(aSegments) && n < len(bSegments) && aSegments[n] == bSegments[n] {
+		n++
+	}
+	return strings.Join(aSegments[:n], "/")
+}
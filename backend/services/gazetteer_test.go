@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bondihub/models"
+	"strings"
+	"testing"
+)
+
+// testLocations builds a small location hierarchy keyed by path from the paths of its nodes
+func testLocations(paths ...string) map[string]*models.Location {
+	levels := []models.LocationLevel{models.LevelProvince, models.LevelDistrict, models.LevelTown, models.LevelArea}
+	locations := map[string]*models.Location{}
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		slug := segments[len(segments)-1]
+		locations[path] = &models.Location{
+			Level: levels[len(segments)-1],
+			Name:  slug,
+			Slug:  slug,
+			Path:  path,
+		}
+	}
+	return locations
+}
+
+func TestMatchAddress(t *testing.T) {
+	locations := testLocations(
+		"lusaka",
+		"lusaka/lusaka",
+		"lusaka/lusaka/lusaka",
+		"lusaka/lusaka/lusaka/kabulonga",
+		"lusaka/lusaka/lusaka/woodlands",
+		"lusaka/lusaka/lusaka/woodlands-extension",
+		"copperbelt",
+		"copperbelt/kitwe",
+		"copperbelt/kitwe/kitwe",
+		"copperbelt/kitwe/kitwe/ndeke",
+		"copperbelt/kitwe/kitwe/riverside",
+		"copperbelt/ndola",
+		"copperbelt/ndola/ndola",
+		"copperbelt/ndola/ndola/ndeke",
+		"copperbelt/ndola/ndola/riverside",
+	)
+
+	tests := []struct {
+		name    string
+		address string
+		want    string // path of the match, "" for none
+	}{
+		{"area and town", "12 Kabulonga Road, Lusaka", "lusaka/lusaka/lusaka/kabulonga"},
+		{"area alone", "Plot 5, Kabulonga", "lusaka/lusaka/lusaka/kabulonga"},
+		{"town named in the address picks the area", "House 3, Ndeke, Kitwe", "copperbelt/kitwe/kitwe/ndeke"},
+		{"other town", "Ndeke Township, Ndola", "copperbelt/ndola/ndola/ndeke"},
+		{"ambiguous area falls back to the common location", "House 3 Ndeke", "copperbelt"},
+		{"ambiguous area in a named province", "Riverside, Copperbelt", "copperbelt"},
+		{"whole words only", "Kabulongatown", ""},
+		{"longer name wins", "Woodlands Extension, Lusaka", "lusaka/lusaka/lusaka/woodlands-extension"},
+		{"case and punctuation", "KABULONGA; lusaka!", "lusaka/lusaka/lusaka/kabulonga"},
+		{"town only", "Cairo Road, Lusaka", "lusaka/lusaka/lusaka"},
+		{"nothing known", "1 Main Street, Chipata", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := matchAddress(tt.address, locations)
+			got := ""
+			if match != nil {
+				got = match.Path
+			}
+			if got != tt.want {
+				t.Errorf("matchAddress(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonPath(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"copperbelt/kitwe/kitwe/ndeke", "copperbelt/ndola/ndola/ndeke", "copperbelt"},
+		{"lusaka/lusaka/lusaka/kabulonga", "lusaka/lusaka/lusaka/woodlands", "lusaka/lusaka/lusaka"},
+		{"lusaka/lusaka/lusaka", "lusaka/lusaka/lusaka/woodlands", "lusaka/lusaka/lusaka"},
+		{"lusaka/lusaka", "lusaka/lusaka", "lusaka/lusaka"},
+		{"lusaka/lusaka", "copperbelt/kitwe", ""},
+		// Segments are compared whole, not as text prefixes
+		{"lusaka/lusaka/lusaka/woodlands", "lusaka/lusaka/lusaka/woodlands-extension", "lusaka/lusaka/lusaka"},
+	}
+
+	for _, tt := range tests {
+		if got := commonPath(tt.a, tt.b); got != tt.want {
+			t.Errorf("commonPath(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+		if got := commonPath(tt.b, tt.a); got != tt.want {
+			t.Errorf("commonPath(%q, %q) = %q, want %q", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	got := pathPrefixes("lusaka/lusaka/lusaka/kabulonga")
+	want := []string{"lusaka", "lusaka/lusaka", "lusaka/lusaka/lusaka", "lusaka/lusaka/lusaka/kabulonga"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("pathPrefixes() = %v, want %v", got, want)
+	}
+}
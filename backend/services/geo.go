@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bondihub/models"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// EarthRadiusKm is the mean radius of the earth used for distance calculations
+const EarthRadiusKm = 6371.0
+
+// maxGeohashCells caps the number of geohash prefixes a location search scans
+const maxGeohashCells = 32
+
+// BoundingBox is an area between two latitudes and two longitudes
+type BoundingBox struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// ParsePoint parses a "lat,lng" pair
+func ParsePoint(value string) (latitude, longitude float64, err error) {
+	coords, err := parseCoordinates(value, 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !validLatitude(coords[0]) || !validLongitude(coords[1]) {
+		return 0, 0, errors.New("coordinates are out of range")
+	}
+	return coords[0], coords[1], nil
+}
+
+// ParseBoundingBox parses a "min_lat,min_lng,max_lat,max_lng" (south-west corner, then north-east corner) box
+func ParseBoundingBox(value string) (BoundingBox, error) {
+	coords, err := parseCoordinates(value, 4)
+	if err != nil {
+		return BoundingBox{}, err
+	}
+	box := BoundingBox{MinLat: coords[0], MinLng: coords[1], MaxLat: coords[2], MaxLng: coords[3]}
+	if !validLatitude(box.MinLat) || !validLatitude(box.MaxLat) || !validLongitude(box.MinLng) || !validLongitude(box.MaxLng) {
+		return BoundingBox{}, errors.New("coordinates are out of range")
+	}
+	if box.MinLat > box.MaxLat || box.MinLng > box.MaxLng {
+		return BoundingBox{}, errors.New("the first corner must be south-west of the second")
+	}
+	return box, nil
+}
+
+// RadiusBoundingBox returns the smallest box containing every point within the radius of a coordinate
+func RadiusBoundingBox(latitude, longitude, radiusKm float64) BoundingBox {
+	latDelta := radiusKm / EarthRadiusKm * 180 / math.Pi
+	box := BoundingBox{
+		MinLat: math.Max(latitude-latDelta, -90),
+		MaxLat: math.Min(latitude+latDelta, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+
+	// Longitude degrees shrink towards the poles; near a pole or the antimeridian search every longitude
+	cosLat := math.Cos(latitude * math.Pi / 180)
+	if cosLat > 0.01 {
+		lngDelta := latDelta / cosLat
+		if longitude-lngDelta >= -180 && longitude+lngDelta <= 180 {
+			box.MinLng = longitude - lngDelta
+			box.MaxLng = longitude + lngDelta
+		}
+	}
+	return box
+}
+
+// GeohashCells returns the geohash prefixes of the cells covering a box, using the longest prefix that
+// keeps the number of cells small. Every house inside the box has a geohash starting with one of them.
+func GeohashCells(box BoundingBox) []string {
+	for precision := models.GeohashPrecision; precision > 0; precision-- {
+		latSize, lngSize := models.GeohashCellSize(precision)
+		rows := math.Floor((box.MaxLat+90)/latSize) - math.Floor((box.MinLat+90)/latSize) + 1
+		cols := math.Floor((box.MaxLng+180)/lngSize) - math.Floor((box.MinLng+180)/lngSize) + 1
+		if rows*cols > maxGeohashCells {
+			continue
+		}
+
+		cells := make([]string, 0, int(rows*cols))
+		seen := make(map[string]bool, int(rows*cols))
+		for row := 0.0; row < rows; row++ {
+			lat := math.Min((math.Floor((box.MinLat+90)/latSize)+row+0.5)*latSize-90, 90)
+			for col := 0.0; col < cols; col++ {
+				lng := math.Min((math.Floor((box.MinLng+180)/lngSize)+col+0.5)*lngSize-180, 180)
+				cell := models.EncodeGeohash(lat, lng, precision)
+				if !seen[cell] {
+					seen[cell] = true
+					cells = append(cells, cell)
+				}
+			}
+		}
+		return cells
+	}
+	return nil // the box spans most of the globe, so prefixes would not narrow the search
+}
+
+// WithinBoundingBox limits a house query to houses inside a box. The geohash prefixes use the
+// geohash index to narrow the scan; the coordinate check then trims houses outside the box.
+func WithinBoundingBox(query *gorm.DB, box BoundingBox) *gorm.DB {
+	if cells := GeohashCells(box); len(cells) > 0 {
+		conditions := make([]string, len(cells))
+		args := make([]interface{}, len(cells))
+		for i, cell := range cells {
+			conditions[i] = "houses.geohash LIKE ?"
+			args[i] = cell + "%"
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	} else {
+		query = query.Where("houses.geohash <> ''")
+	}
+
+	return query.Where("houses.latitude BETWEEN ? AND ? AND houses.longitude BETWEEN ? AND ?",
+		box.MinLat, box.MaxLat, box.MinLng, box.MaxLng)
+}
+
+// DistanceSQL returns a haversine expression giving the distance in km from a house to a coordinate,
+// along with its arguments
+func DistanceSQL(latitude, longitude float64) (string, []interface{}) {
+	sql := "(? * 2 * ASIN(LEAST(1, SQRT(" +
+		"POWER(SIN(RADIANS(houses.latitude - ?) / 2), 2) + " +
+		"COS(RADIANS(?)) * COS(RADIANS(houses.latitude)) * POWER(SIN(RADIANS(houses.longitude - ?) / 2), 2)))))"
+	return sql, []interface{}{EarthRadiusKm, latitude, latitude, longitude}
+}
+
+// ColumnDistanceSQL returns a haversine expression giving the distance in km from a house to the
+// coordinate held in two other columns
+func ColumnDistanceSQL(latitudeColumn, longitudeColumn string) string {
+	return fmt.Sprintf("(%g * 2 * ASIN(LEAST(1, SQRT("+
+		"POWER(SIN(RADIANS(houses.latitude - %[2]s) / 2), 2) + "+
+		"COS(RADIANS(%[2]s)) * COS(RADIANS(houses.latitude)) * POWER(SIN(RADIANS(houses.longitude - %[3]s) / 2), 2)))))",
+		EarthRadiusKm, latitudeColumn, longitudeColumn)
+}
+
+// parseCoordinates parses a comma separated list of exactly n numbers
+func parseCoordinates(value string, n int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != n {
+		return nil, errors.New("expected " + strconv.Itoa(n) + " comma separated coordinates")
+	}
+	coords := make([]float64, n)
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(coord) || math.IsInf(coord, 0) {
+			return nil, errors.New("coordinates must be numbers")
+		}
+		coords[i] = coord
+	}
+	return coords, nil
+}
+
+func validLatitude(latitude float64) bool {
+	return latitude >= -90 && latitude <= 90
+}
+
+func validLongitude(longitude float64) bool {
+	return longitude >= -180 && longitude <= 180
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Geocoding results are cached so repeated lookups, such as a landlord nudging a map pin back and forth
+// or the same address typed into several forms, do not use up the online geocoder's rate limit
+const (
+	geocodeCacheTTL     = 24 * time.Hour
+	geocodeCacheEntries = 10000
+)
+
+// cachingGeocoder remembers the results of another geocoder, including addresses it could not place
+type cachingGeocoder struct {
+	geocoder Geocoder
+
+	mu      sync.Mutex
+	entries map[string]geocodeCacheEntry
+}
+
+// geocodeCacheEntry is a cached result; result is nil when the lookup found no match
+type geocodeCacheEntry struct {
+	result  *GeocodeResult
+	expires time.Time
+}
+
+// NewCachingGeocoder wraps a geocoder with a cache of its results
+func NewCachingGeocoder(geocoder Geocoder) Geocoder {
+	return &cachingGeocoder{
+		geocoder: geocoder,
+		entries:  map[string]geocodeCacheEntry{},
+	}
+}
+
+// Geocode finds the coordinates of an address
+func (cg *cachingGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	key := "address|" + strings.ToLower(strings.Join(strings.Fields(address), " "))
+	return cg.lookup(key, func() (*GeocodeResult, error) {
+		return cg.geocoder.Geocode(ctx, address)
+	})
+}
+
+// ReverseGeocode suggests an address for coordinates. Coordinates are cached to about a metre.
+func (cg *cachingGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error) {
+	key := fmt.Sprintf("point|%.5f,%.5f", latitude, longitude)
+	return cg.lookup(key, func() (*GeocodeResult, error) {
+		return cg.geocoder.ReverseGeocode(ctx, latitude, longitude)
+	})
+}
+
+// lookup returns the cached result for a key, or looks it up and caches it. Errors other than
+// ErrNoGeocodeMatch are not cached.
+func (cg *cachingGeocoder) lookup(key string, find func() (*GeocodeResult, error)) (*GeocodeResult, error) {
+	now := time.Now()
+	cg.mu.Lock()
+	entry, ok := cg.entries[key]
+	cg.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		if entry.result == nil {
+			return nil, ErrNoGeocodeMatch
+		}
+		result := *entry.result
+		return &result, nil
+	}
+
+	result, err := find()
+	if err != nil && !errors.Is(err, ErrNoGeocodeMatch) {
+		return nil, err
+	}
+
+	entry = geocodeCacheEntry{expires: now.Add(geocodeCacheTTL)}
+	if result != nil {
+		cached := *result
+		entry.result = &cached
+	}
+	cg.mu.Lock()
+	if len(cg.entries) >= geocodeCacheEntries {
+		cg.evict(now)
+	}
+	cg.entries[key] = entry
+	cg.mu.Unlock()
+	return result, err
+}
+
+// evict drops expired entries, or when none have expired, an arbitrary tenth of the cache. The caller
+// must hold the lock.
+func (cg *cachingGeocoder) evict(now time.Time) {
+	for key, entry := range cg.entries {
+		if !now.Before(entry.expires) {
+			delete(cg.entries, key)
+		}
+	}
+	for key := range cg.entries {
+		if len(cg.entries) < geocodeCacheEntries*9/10 {
+			break
+		}
+		delete(cg.entries, key)
+	}
+}
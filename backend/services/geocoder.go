@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoGeocodeMatch is returned when a geocoder cannot place an address or coordinates
+var ErrNoGeocodeMatch = errors.New("no matching place found")
+
+// GeocodeResult is a place found by a Geocoder
+type GeocodeResult struct {
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	Address    string     `json:"address"`     // formatted address of the place
+	LocationID *uuid.UUID `json:"location_id"` // the location the place is in, when known
+	Precision  string     `json:"precision"`   // what was matched, e.g. area or town for the gazetteer
+	Source     string     `json:"source"`      // gazetteer, nominatim or location
+}
+
+// Geocoder turns addresses into coordinates and back. The offline gazetteer covers the seeded Zambian
+// towns and areas; NewGeocoder can put an online service in front of it.
+type Geocoder interface {
+	// Geocode finds the coordinates of an address, or returns ErrNoGeocodeMatch
+	Geocode(ctx context.Context, address string) (*GeocodeResult, error)
+	// ReverseGeocode suggests an address for coordinates, or returns ErrNoGeocodeMatch
+	ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error)
+}
+
+// NewGeocoder creates the geocoder selected by GEOCODER_BACKEND
+func NewGeocoder() (Geocoder, error) {
+	gazetteer := NewGazetteerGeocoder()
+	switch config.AppConfig.GeocoderBackend {
+	case "offline":
+		return gazetteer, nil
+	case "nominatim":
+		nominatim, err := NewNominatimGeocoder()
+		if err != nil {
+			return nil, err
+		}
+		return &fallbackGeocoder{primary: nominatim, gazetteer: gazetteer}, nil
+	default:
+		return nil, fmt.Errorf("unknown geocoder backend %q", config.AppConfig.GeocoderBackend)
+	}
+}
+
+// NewListingGeocoder creates the geocoder shared by listing forms and the geocoding job: the configured
+// geocoder behind a cache, or the offline gazetteer when the configured one cannot be set up
+func NewListingGeocoder() Geocoder {
+	geocoder, err := NewGeocoder()
+	if err != nil {
+		log.Printf("Failed to initialize %s geocoder, using the offline gazetteer: %v", config.AppConfig.GeocoderBackend, err)
+		geocoder = NewGazetteerGeocoder()
+	}
+	return NewCachingGeocoder(geocoder)
+}
+
+// fallbackGeocoder asks an online geocoder first and the gazetteer when it fails. Places found online
+// are filed under the gazetteer location they fall in.
+type fallbackGeocoder struct {
+	primary   Geocoder
+	gazetteer *GazetteerGeocoder
+}
+
+// Geocode finds the coordinates of an address
+func (fg *fallbackGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	result, err := fg.primary.Geocode(ctx, address)
+	if err != nil {
+		if !errors.Is(err, ErrNoGeocodeMatch) {
+			log.Printf("Online geocoding failed, using the gazetteer: %v", err)
+		}
+		return fg.gazetteer.Geocode(ctx, address)
+	}
+	fg.fileUnderLocation(ctx, result)
+	return result, nil
+}
+
+// ReverseGeocode suggests an address for coordinates
+func (fg *fallbackGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error) {
+	result, err := fg.primary.ReverseGeocode(ctx, latitude, longitude)
+	if err != nil {
+		if !errors.Is(err, ErrNoGeocodeMatch) {
+			log.Printf("Online reverse geocoding failed, using the gazetteer: %v", err)
+		}
+		return fg.gazetteer.ReverseGeocode(ctx, latitude, longitude)
+	}
+	fg.fileUnderLocation(ctx, result)
+	return result, nil
+}
+
+// fileUnderLocation sets the location of a place found online from the gazetteer
+func (fg *fallbackGeocoder) fileUnderLocation(ctx context.Context, result *GeocodeResult) {
+	if result.LocationID != nil {
+		return
+	}
+	if nearest, err := fg.gazetteer.ReverseGeocode(ctx, result.Latitude, result.Longitude); err == nil {
+		result.LocationID = nearest.LocationID
+	}
+}
+
+// Place is where a listing is
+type Place struct {
+	Address    string
+	Latitude   float64
+	Longitude  float64
+	LocationID *uuid.UUID
+
+	// Set by CompletePlace when it fills in the coordinates
+	Source    string
+	Precision string
+}
+
+// exactPinPrecisions are the precisions of geocoded pins on a listing's own building or street. Other
+// geocoded pins, such as every gazetteer match, are the centre of an area or town.
+var exactPinPrecisions = []string{"house", "building", "road", "amenity"}
+
+// IsApproximatePin reports whether coordinates geocoded from the given source with the given precision
+// only place a listing at the centre of its area or town. Pins placed by landlords have no source.
+func IsApproximatePin(source, precision string) bool {
+	if source == "" {
+		return false
+	}
+	for _, exact := range exactPinPrecisions {
+		if precision == exact {
+			return false
+		}
+	}
+	return true
+}
+
+// ApproximatePinSQL returns a condition that is true for houses whose pin is approximate, in the sense
+// of IsApproximatePin, along with its arguments
+func ApproximatePinSQL() (string, []interface{}) {
+	return "(houses.geocode_source <> '' AND houses.geocode_precision NOT IN ?)", []interface{}{exactPinPrecisions}
+}
+
+// HasCoordinates reports whether the place has been given coordinates; (0, 0) means none
+func (p *Place) HasCoordinates() bool {
+	return p.Latitude != 0 || p.Longitude != 0
+}
+
+// CompletePlace fills in what a listing's place is missing: its coordinates from its address, or
+// failing that from its location, its address from its coordinates, and its location from either.
+// It returns the result the coordinates or address were filled from, or nil when they were not
+// missing or could not be found. Geocoding never fails a listing, so errors are only logged.
+func CompletePlace(ctx context.Context, geocoder Geocoder, place *Place) *GeocodeResult {
+	var result *GeocodeResult
+	var err error
+	switch {
+	case !place.HasCoordinates() && place.Address != "":
+		result, err = geocoder.Geocode(ctx, place.Address)
+		if err != nil && place.LocationID != nil {
+			// Fall back to the centre of the location the landlord picked
+			var location *models.Location
+			if location, err = LoadLocation(*place.LocationID); err == nil {
+				result = &GeocodeResult{
+					Latitude:   location.Latitude,
+					Longitude:  location.Longitude,
+					Address:    place.Address,
+					LocationID: &location.ID,
+					Precision:  string(location.Level),
+					Source:     "location",
+				}
+			}
+		}
+		if err == nil {
+			place.Latitude, place.Longitude = result.Latitude, result.Longitude
+			place.Source, place.Precision = result.Source, result.Precision
+		}
+	case place.HasCoordinates() && place.Address == "":
+		result, err = geocoder.ReverseGeocode(ctx, place.Latitude, place.Longitude)
+		if err == nil {
+			place.Address = result.Address
+		}
+	case place.HasCoordinates() && place.LocationID == nil:
+		// Nothing visible is missing, but the listing can still be filed under a location
+		var nearest *GeocodeResult
+		if nearest, err = geocoder.ReverseGeocode(ctx, place.Latitude, place.Longitude); err == nil {
+			place.LocationID = nearest.LocationID
+		}
+	}
+
+	if err != nil {
+		if !errors.Is(err, ErrNoGeocodeMatch) && !errors.Is(err, ErrUnknownLocation) {
+			log.Printf("Failed to geocode listing: %v", err)
+		}
+		return nil
+	}
+	if result != nil && place.LocationID == nil {
+		place.LocationID = result.LocationID
+	}
+	return result
+}
+
+// geocodeRetryInterval is how long the geocoding job waits before trying an address it could not place
+// again, in case the geocoder has learnt it since
+const geocodeRetryInterval = 30 * 24 * time.Hour
+
+// GeocodingBackfill fills in the coordinates of standalone houses and properties saved without them, and
+// the location of those with coordinates but no location, a batch per run
+type GeocodingBackfill struct {
+	geocoder Geocoder
+}
+
+// NewGeocodingBackfill creates a geocoding backfill
+func NewGeocodingBackfill(geocoder Geocoder) *GeocodingBackfill {
+	return &GeocodingBackfill{geocoder: geocoder}
+}
+
+// backfillCandidatesSQL selects listings without coordinates but with an address to place, and listings
+// with coordinates that have not been filed under a location
+const backfillCandidatesSQL = "((latitude = 0 AND longitude = 0 AND address <> '') OR " +
+	"(location_id IS NULL AND (latitude <> 0 OR longitude <> 0)))"
+
+// ProcessMissingCoordinates geocodes the next batch of houses and properties without coordinates or a
+// location. Each attempt is recorded, and listings that cannot be placed are only tried again after
+// geocodeRetryInterval.
+func (gb *GeocodingBackfill) ProcessMissingCoordinates() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	retryBefore := now.Add(-geocodeRetryInterval)
+
+	// Units take their coordinates and location from their property
+	var houses []models.House
+	if err := config.DB.Where(backfillCandidatesSQL+" AND property_id IS NULL").
+		Where("geocode_attempted_at IS NULL OR geocode_attempted_at < ?", retryBefore).
+		Order("created_at ASC").Limit(50).Find(&houses).Error; err != nil {
+		return err
+	}
+	placed := 0
+	for _, house := range houses {
+		place := Place{Address: house.Address, Latitude: house.Latitude, Longitude: house.Longitude, LocationID: house.LocationID}
+		updates := gb.complete(ctx, &place, house.LocationID)
+		if len(updates) > 0 {
+			placed++
+			if place.Source != "" {
+				updates["geohash"] = models.EncodeGeohash(place.Latitude, place.Longitude, models.GeohashPrecision)
+			}
+		}
+		updates["geocode_attempted_at"] = now
+		if err := config.DB.Model(&house).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	var properties []models.Property
+	if err := config.DB.Where(backfillCandidatesSQL).
+		Where("geocode_attempted_at IS NULL OR geocode_attempted_at < ?", retryBefore).
+		Order("created_at ASC").Limit(50).Find(&properties).Error; err != nil {
+		return err
+	}
+	for i := range properties {
+		property := &properties[i]
+
+		place := Place{Address: property.Address, Latitude: property.Latitude, Longitude: property.Longitude, LocationID: property.LocationID}
+		updates := gb.complete(ctx, &place, property.LocationID)
+		changed := len(updates) > 0
+		updates["geocode_attempted_at"] = now
+		if err := config.DB.Model(property).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+		property.Latitude, property.Longitude, property.LocationID = place.Latitude, place.Longitude, place.LocationID
+		if place.Source != "" {
+			property.GeocodeSource, property.GeocodePrecision = place.Source, place.Precision
+		}
+		if err := SyncPropertyUnits(config.DB, property); err != nil {
+			return err
+		}
+		placed++
+	}
+
+	if placed > 0 {
+		log.Printf("Geocoded %d listings without coordinates or a location", placed)
+	}
+	return nil
+}
+
+// complete fills in a listing's place and returns the columns it changed
+func (gb *GeocodingBackfill) complete(ctx context.Context, place *Place, locationID *uuid.UUID) map[string]interface{} {
+	updates := map[string]interface{}{}
+	CompletePlace(ctx, gb.geocoder, place)
+	if place.Source != "" {
+		updates["latitude"] = place.Latitude
+		updates["longitude"] = place.Longitude
+		updates["geocode_source"] = place.Source
+		updates["geocode_precision"] = place.Precision
+	}
+	if locationID == nil && place.LocationID != nil {
+		updates["location_id"] = place.LocationID
+	}
+	return updates
+}
+
+// haversineKm returns the great-circle distance in km between two coordinates
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Pow(math.Sin(dLng/2), 2)
+	return EarthRadiusKm * 2 * math.Asin(math.Min(1, math.Sqrt(a)))
+}
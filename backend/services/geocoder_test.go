@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingGeocoder answers every lookup with a fixed result and counts the lookups
+type countingGeocoder struct {
+	result *GeocodeResult
+	err    error
+	calls  int
+}
+
+func (cg *countingGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	cg.calls++
+	return cg.result, cg.err
+}
+
+func (cg *countingGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error) {
+	cg.calls++
+	return cg.result, cg.err
+}
+
+func TestCachingGeocoder(t *testing.T) {
+	ctx := context.Background()
+
+	found := &countingGeocoder{result: &GeocodeResult{Latitude: -15.4, Longitude: 28.3, Source: "nominatim"}}
+	cached := NewCachingGeocoder(found)
+	for _, address := range []string{"12 Kabulonga Road, Lusaka", "12  kabulonga road, LUSAKA"} {
+		result, err := cached.Geocode(ctx, address)
+		if err != nil || result.Latitude != -15.4 {
+			t.Fatalf("Geocode(%q) = %v, %v", address, result, err)
+		}
+		result.Latitude = 0 // callers cannot change the cached result
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cached.ReverseGeocode(ctx, -15.400001, 28.3); err != nil {
+			t.Fatalf("ReverseGeocode: %v", err)
+		}
+	}
+	if found.calls != 2 {
+		t.Errorf("geocoder called %d times, want once per address and once per point", found.calls)
+	}
+
+	missing := &countingGeocoder{err: ErrNoGeocodeMatch}
+	cached = NewCachingGeocoder(missing)
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Geocode(ctx, "Nowhere"); !errors.Is(err, ErrNoGeocodeMatch) {
+			t.Fatalf("Geocode of an unknown address returned %v, want ErrNoGeocodeMatch", err)
+		}
+	}
+	if missing.calls != 1 {
+		t.Errorf("geocoder called %d times for an unknown address, want 1", missing.calls)
+	}
+
+	failing := &countingGeocoder{err: errors.New("service unavailable")}
+	cached = NewCachingGeocoder(failing)
+	cached.Geocode(ctx, "12 Kabulonga Road")
+	cached.Geocode(ctx, "12 Kabulonga Road")
+	if failing.calls != 2 {
+		t.Errorf("geocoder called %d times after failing, want failures retried", failing.calls)
+	}
+}
+
+func TestNominatimWaitHonoursContext(t *testing.T) {
+	ng := &NominatimGeocoder{turn: make(chan time.Time, 1)}
+	ng.turn <- time.Time{}
+
+	if err := ng.wait(context.Background()); err != nil {
+		t.Fatalf("first request waited: %v", err)
+	}
+
+	// The next request is spaced out, but gives up when its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := ng.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait returned %v, want the context deadline", err)
+	}
+	if waited := time.Since(start); waited > nominatimInterval/2 {
+		t.Errorf("waited %s for a request whose context was done", waited)
+	}
+
+	// A request queued behind one holding the turn also gives up with its context
+	held := <-ng.turn
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ng.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("queued wait returned %v, want the context deadline", err)
+	}
+	ng.turn <- held
+}
+
+func TestIsApproximatePin(t *testing.T) {
+	tests := []struct {
+		source, precision string
+		want              bool
+	}{
+		{"", "", false},
+		{"nominatim", "road", false},
+		{"nominatim", "building", false},
+		{"nominatim", "suburb", true},
+		{"gazetteer", "area", true},
+		{"location", "town", true},
+	}
+	for _, tt := range tests {
+		if got := IsApproximatePin(tt.source, tt.precision); got != tt.want {
+			t.Errorf("IsApproximatePin(%q, %q) = %v, want %v", tt.source, tt.precision, got, tt.want)
+		}
+	}
+}
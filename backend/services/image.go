@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxImageDeletionAttempts is how many times a stored image deletion is tried before it is left
+// in the queue for an administrator to look at
+const maxImageDeletionAttempts = 8
+
+// maxImageDeletionBackoff caps the wait between retries of a failed image deletion
+const maxImageDeletionBackoff = 24 * time.Hour
+
+// ImageCleanupService removes stored images once their records have been deleted
+type ImageCleanupService struct {
+	storage ImageStorage
+}
+
+// NewImageCleanupService creates an image cleanup service. With a nil storage, deletions stay
+// queued until a service with working storage processes them.
+func NewImageCleanupService(storage ImageStorage) *ImageCleanupService {
+	return &ImageCleanupService{storage: storage}
+}
+
+// QueueImageDeletions queues stored images for deletion. Call it in the transaction that deletes
+// their records, then pass the result to DeleteQueued once the transaction has committed.
+func QueueImageDeletions(tx *gorm.DB, publicIDs ...string) ([]models.ImageDeletion, error) {
+	var deletions []models.ImageDeletion
+	for _, publicID := range publicIDs {
+		if publicID == "" {
+			continue // images uploaded before public IDs were recorded
+		}
+		deletions = append(deletions, models.ImageDeletion{PublicID: publicID})
+	}
+	if len(deletions) == 0 {
+		return nil, nil
+	}
+	if err := tx.Create(&deletions).Error; err != nil {
+		return nil, err
+	}
+	return deletions, nil
+}
+
+// DeleteQueued tries to delete queued images straight away. Failures stay queued and are retried
+// by ProcessImageDeletions.
+func (ics *ImageCleanupService) DeleteQueued(ctx context.Context, deletions []models.ImageDeletion) {
+	if ics.storage == nil {
+		return
+	}
+	for i := range deletions {
+		if err := ics.attempt(ctx, &deletions[i]); err != nil {
+			log.Printf("Failed to delete stored image %s, will retry: %v", deletions[i].PublicID, err)
+		}
+	}
+}
+
+// ProcessImageDeletions retries queued image deletions that are due
+func (ics *ImageCleanupService) ProcessImageDeletions() error {
+	if ics.storage == nil {
+		return errors.New("image storage is not configured")
+	}
+
+	var deletions []models.ImageDeletion
+	if err := config.DB.Where("attempts < ? AND next_attempt_at <= ?", maxImageDeletionAttempts, time.Now()).
+		Order("next_attempt_at ASC").Limit(100).Find(&deletions).Error; err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	failed := 0
+	for i := range deletions {
+		if err := ics.attempt(ctx, &deletions[i]); err != nil {
+			failed++
+		}
+	}
+	if len(deletions) > 0 {
+		log.Printf("Deleted %d of %d queued images", len(deletions)-failed, len(deletions))
+	}
+	return nil
+}
+
+// attempt deletes a queued image from storage and removes it from the queue, or records the
+// failure and schedules the next attempt with exponential backoff
+func (ics *ImageCleanupService) attempt(ctx context.Context, deletion *models.ImageDeletion) error {
+	if err := ics.storage.Delete(ctx, deletion.PublicID); err != nil {
+		deletion.Attempts++
+		backoff := time.Minute << deletion.Attempts
+		if backoff > maxImageDeletionBackoff {
+			backoff = maxImageDeletionBackoff
+		}
+		if deletion.Attempts >= maxImageDeletionAttempts {
+			log.Printf("Giving up on deleting stored image %s after %d attempts, see GET /admin/image-deletions: %v",
+				deletion.PublicID, deletion.Attempts, err)
+		}
+		config.DB.Model(deletion).Updates(map[string]interface{}{
+			"attempts":        deletion.Attempts,
+			"last_error":      err.Error(),
+			"next_attempt_at": time.Now().Add(backoff),
+		})
+		return err
+	}
+	return config.DB.Delete(deletion).Error
+}
+
+// FailedImageDeletionsQuery selects the stored image deletions that were given up on
+func FailedImageDeletionsQuery() *gorm.DB {
+	return config.DB.Model(&models.ImageDeletion{}).Where("attempts >= ?", maxImageDeletionAttempts)
+}
+
+// FailedImageDeletions returns a page of the stored image deletions that were given up on, most
+// recent failure first, with their total
+func FailedImageDeletions(offset, limit int) ([]models.ImageDeletion, int64, error) {
+	query := FailedImageDeletionsQuery()
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var deletions []models.ImageDeletion
+	if err := query.Order("next_attempt_at DESC").Offset(offset).Limit(limit).Find(&deletions).Error; err != nil {
+		return nil, 0, err
+	}
+	return deletions, total, nil
+}
+
+// RetryImageDeletion puts a deletion that was given up on back in the queue, to be tried on the next
+// run of the deletion job. It returns gorm.ErrRecordNotFound when no such failed deletion exists.
+func RetryImageDeletion(id uuid.UUID) (*models.ImageDeletion, error) {
+	var deletion models.ImageDeletion
+	if err := config.DB.Where("attempts >= ?", maxImageDeletionAttempts).First(&deletion, id).Error; err != nil {
+		return nil, err
+	}
+	deletion.Attempts = 0
+	deletion.NextAttemptAt = time.Now()
+	if err := config.DB.Model(&deletion).Select("attempts", "next_attempt_at").Updates(&deletion).Error; err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// NextHouseImageSortOrder returns the gallery position for a new image, after the house's existing images
+func NextHouseImageSortOrder(tx *gorm.DB, houseID uuid.UUID) int {
+	var next int
+	tx.Model(&models.HouseImage{}).Where("house_id = ?", houseID).
+		Select("COALESCE(MAX(sort_order), -1) + 1").Scan(&next)
+	return next
+}
+
+// NextPropertyImageSortOrder returns the gallery position for a new image, after the property's existing images
+func NextPropertyImageSortOrder(tx *gorm.DB, propertyID uuid.UUID) int {
+	var next int
+	tx.Model(&models.PropertyImage{}).Where("property_id = ?", propertyID).
+		Select("COALESCE(MAX(sort_order), -1) + 1").Scan(&next)
+	return next
+}
+
+// SetPrimaryHouseImage makes an image the primary image of its house
+func SetPrimaryHouseImage(tx *gorm.DB, image *models.HouseImage) error {
+	if err := tx.Model(&models.HouseImage{}).
+		Where("house_id = ? AND id <> ? AND is_primary = ?", image.HouseID, image.ID, true).
+		Update("is_primary", false).Error; err != nil {
+		return err
+	}
+	image.IsPrimary = true
+	return tx.Model(image).Update("is_primary", true).Error
+}
+
+// SetPrimaryPropertyImage makes an image the primary image of its property
+func SetPrimaryPropertyImage(tx *gorm.DB, image *models.PropertyImage) error {
+	if err := tx.Model(&models.PropertyImage{}).
+		Where("property_id = ? AND id <> ? AND is_primary = ?", image.PropertyID, image.ID, true).
+		Update("is_primary", false).Error; err != nil {
+		return err
+	}
+	image.IsPrimary = true
+	return tx.Model(image).Update("is_primary", true).Error
+}
+
+// DeletePropertyImage deletes an image record and queues its stored image for deletion. When it was
+// the property's primary image, the first remaining image in gallery order becomes primary.
+func DeletePropertyImage(tx *gorm.DB, image *models.PropertyImage) ([]models.ImageDeletion, error) {
+	if err := tx.Delete(image).Error; err != nil {
+		return nil, err
+	}
+
+	if image.IsPrimary {
+		var next models.PropertyImage
+		err := tx.Where("property_id = ?", image.PropertyID).Order("sort_order ASC, created_at ASC").First(&next).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			if err := SetPrimaryPropertyImage(tx, &next); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return QueueImageDeletions(tx, image.PublicID)
+}
+
+// DeleteHouseImages deletes image records and queues their stored images for deletion. When the
+// house's primary image is deleted, the first remaining image in gallery order becomes primary.
+func DeleteHouseImages(tx *gorm.DB, images []models.HouseImage) ([]models.ImageDeletion, error) {
+	if len(images) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(images))
+	publicIDs := make([]string, 0, len(images))
+	lostPrimary := map[uuid.UUID]bool{}
+	for _, image := range images {
+		ids = append(ids, image.ID)
+		publicIDs = append(publicIDs, image.PublicID)
+		if image.IsPrimary {
+			lostPrimary[image.HouseID] = true
+		}
+	}
+
+	if err := tx.Where("id IN ?", ids).Delete(&models.HouseImage{}).Error; err != nil {
+		return nil, err
+	}
+
+	for houseID := range lostPrimary {
+		var next models.HouseImage
+		err := tx.Where("house_id = ?", houseID).Order("sort_order ASC, created_at ASC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := SetPrimaryHouseImage(tx, &next); err != nil {
+			return nil, err
+		}
+	}
+
+	return QueueImageDeletions(tx, publicIDs...)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// DifferenceHash computes the 64-bit difference hash (dHash) of an image. The image is shrunk to 9x8
+// grey pixels and each bit records whether a pixel is brighter than its right-hand neighbour, so
+// resized, recompressed or lightly edited copies of a photo hash to the same or nearly the same value.
+func DifferenceHash(img image.Image) int64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return int64(hash)
+}
+
+// HashDistance returns the number of bits that differ between two image hashes
+func HashDistance(a, b int64) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
+
+// FetchImageHash downloads an image and computes its difference hash. It is used for images that
+// did not pass through UploadImage, such as direct uploads to Cloudinary and images stored before
+// hashing was introduced.
+func FetchImageHash(ctx context.Context, client *http.Client, url string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("image download returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(config.AppConfig.MaxImageSizeMB)<<20))
+	if err != nil {
+		return 0, fmt.Errorf("failed to download image: %w", err)
+	}
+
+	// Check the dimensions from the header before decoding the whole image, as for uploads
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if header.Width > config.AppConfig.MaxImageDimension || header.Height > config.AppConfig.MaxImageDimension ||
+		header.Width*header.Height > maxImagePixels {
+		return 0, fmt.Errorf("image is too large to hash: %dx%d pixels", header.Width, header.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return DifferenceHash(img), nil
+}
+
+// ImageHashBackfill hashes house images that have no perceptual hash yet, a batch per run
+type ImageHashBackfill struct {
+	client *http.Client
+	cursor time.Time // creation time of the last image tried in the current pass
+}
+
+// NewImageHashBackfill creates an image hash backfill
+func NewImageHashBackfill() *ImageHashBackfill {
+	return &ImageHashBackfill{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// ProcessMissingImageHashes hashes the next batch of unhashed images. Images that cannot be
+// downloaded are skipped until the next pass over the table.
+func (ihb *ImageHashBackfill) ProcessMissingImageHashes() error {
+	var images []models.HouseImage
+	if err := config.DB.Where("image_hash IS NULL AND created_at > ?", ihb.cursor).
+		Order("created_at ASC").Limit(50).Find(&images).Error; err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		ihb.cursor = time.Time{}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	hashed := 0
+	for _, houseImage := range images {
+		ihb.cursor = houseImage.CreatedAt
+
+		url := houseImage.MediumURL
+		if url == "" {
+			url = houseImage.ImageURL
+		}
+		hash, err := FetchImageHash(ctx, ihb.client, url)
+		if err != nil {
+			log.Printf("Failed to hash house image %s: %v", houseImage.ID, err)
+			continue
+		}
+		if err := config.DB.Model(&houseImage).Update("image_hash", hash).Error; err != nil {
+			return err
+		}
+		hashed++
+	}
+	log.Printf("Hashed %d of %d house images", hashed, len(images))
+	return nil
+}
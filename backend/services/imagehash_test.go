@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bondihub/models"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// testPhoto draws a wave pattern standing in for a photo; other phases give other photos
+func testPhoto(width, height int, phase float64) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			shade := uint8(128 + 100*math.Sin(9*fx+5*fy*fy+phase)*math.Cos(4*fy-2*fx+phase))
+			img.Set(x, y, color.RGBA{R: shade, G: shade, B: shade, A: 255})
+		}
+	}
+	return img
+}
+
+func TestHashDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b int64
+		want int
+	}{
+		{"identical", 0x0f0f0f0f0f0f0f0f, 0x0f0f0f0f0f0f0f0f, 0},
+		{"one bit", 0, 1, 1},
+		{"sign bit", 0, -1 << 63, 1},
+		{"all bits", 0, -1, 64},
+		{"symmetric", 0x00ff, 0x0f0f, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("HashDistance(%#x, %#x) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := HashDistance(tt.b, tt.a); got != tt.want {
+				t.Errorf("HashDistance(%#x, %#x) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDifferenceHash(t *testing.T) {
+	photo := testPhoto(1200, 900, 0)
+	hash := DifferenceHash(photo)
+
+	resized := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	draw.ApproxBiLinear.Scale(resized, resized.Bounds(), photo, photo.Bounds(), draw.Src, nil)
+
+	tests := []struct {
+		name      string
+		img       image.Image
+		wantMatch bool
+	}{
+		{"same photo", photo, true},
+		{"resized copy", resized, true},
+		{"different photo", testPhoto(1200, 900, 2), false},
+		{"flat image", image.NewGray(image.Rect(0, 0, 1200, 900)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := HashDistance(hash, DifferenceHash(tt.img))
+			if match := distance <= imageMatchDistance; match != tt.wantMatch {
+				t.Errorf("distance %d, match = %v, want %v", distance, match, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestImageHashBandsCatchMatches(t *testing.T) {
+	bands := models.ImageHashBands
+	if len(bands) != imageMatchDistance+1 {
+		t.Fatalf("%d hash bands, need %d so matches always share one", len(bands), imageMatchDistance+1)
+	}
+	covered := uint64(0)
+	for _, band := range bands {
+		mask := (uint64(1)<<band.Bits - 1) << band.Shift
+		if covered&mask != 0 {
+			t.Fatalf("band at bit %d overlaps another band", band.Shift)
+		}
+		covered |= mask
+	}
+	if covered != ^uint64(0) {
+		t.Fatalf("bands cover %#x, not every bit", covered)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		a := int64(rng.Uint64())
+		b := a
+		for flips := rng.Intn(imageMatchDistance + 1); flips > 0; flips-- {
+			b ^= 1 << rng.Intn(64)
+		}
+		shared := false
+		for _, band := range bands {
+			if band.Value(a) == band.Value(b) {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			t.Fatalf("hashes %#x and %#x are %d bits apart but share no band", a, b, HashDistance(a, b))
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package services
+
+import (
+	"bondihub/config"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// minImageDimension is the smallest width and height accepted; smaller photos look broken on a listing
+const minImageDimension = 320
+
+// maxImagePixels guards against decompression bombs: small files that decode to huge images
+const maxImagePixels = 50_000_000
+
+// jpegQuality is the quality images are re-encoded at
+const jpegQuality = 88
+
+// uploadImageTypes are the image formats accepted for upload, by sniffed content type
+var uploadImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// imageVariant describes how a standard size is rendered from the original
+type imageVariant struct {
+	Width  int
+	Height int
+	Crop   bool // fill the box and crop the overflow, instead of fitting inside it
+}
+
+// imageVariants are the standard sizes rendered for storages that cannot resize on delivery.
+// They match the Cloudinary transformations used for the same sizes.
+var imageVariants = map[ImageSize]imageVariant{
+	ImageSizeMedium:    {Width: 800, Height: 600},
+	ImageSizeThumbnail: {Width: 300, Height: 200, Crop: true},
+}
+
+// VariantStorage is implemented by image storages that cannot resize images on delivery.
+// UploadImage renders the standard sizes and stores them next to the original.
+type VariantStorage interface {
+	UploadVariant(ctx context.Context, publicID string, size ImageSize, image io.Reader) error
+}
+
+// ImageValidationError is returned when an uploaded image is rejected. Its message is meant for the uploader.
+type ImageValidationError struct {
+	Message string
+}
+
+func (e *ImageValidationError) Error() string {
+	return e.Message
+}
+
+// UploadImage validates an uploaded image, strips its metadata and stores it with its standard sizes.
+// The source is streamed to a temporary file, so large uploads are never held in memory.
+// Images are rejected with an ImageValidationError unless they are JPEG, PNG or WebP, within the
+// configured file size, and between minImageDimension and the configured maximum dimension.
+func UploadImage(ctx context.Context, storage ImageStorage, source io.Reader, folder string) (*StoredImage, error) {
+	spool, err := SpoolImage(source)
+	if err != nil {
+		return nil, err
+	}
+	return UploadSpooledImage(ctx, storage, spool, folder)
+}
+
+// UploadSpooledImage is UploadImage for an upload already copied to a temporary file by SpoolImage.
+// The file is removed when the upload finishes.
+func UploadSpooledImage(ctx context.Context, storage ImageStorage, spool *os.File, folder string) (*StoredImage, error) {
+	defer removeTempFile(spool)
+
+	img, contentType, err := decodeUpload(spool)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-encoding drops EXIF, GPS, XMP and any other metadata from the original
+	original, err := os.CreateTemp("", "bondihub-image-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer removeTempFile(original)
+
+	if contentType == "image/png" {
+		err = png.Encode(original, img)
+	} else {
+		err = jpeg.Encode(original, flatten(img), &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	if _, err := original.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	stored, err := storage.Upload(ctx, original, folder)
+	if err != nil {
+		return nil, err
+	}
+	hash := DifferenceHash(img)
+	stored.Hash = &hash
+
+	if variantStorage, ok := storage.(VariantStorage); ok {
+		for size, variant := range imageVariants {
+			var encoded bytes.Buffer
+			if err := jpeg.Encode(&encoded, renderVariant(img, variant), &jpeg.Options{Quality: jpegQuality}); err != nil {
+				storage.Delete(ctx, stored.PublicID)
+				return nil, fmt.Errorf("failed to encode %s image: %w", size, err)
+			}
+			if err := variantStorage.UploadVariant(ctx, stored.PublicID, size, &encoded); err != nil {
+				storage.Delete(ctx, stored.PublicID)
+				return nil, fmt.Errorf("failed to upload %s image: %w", size, err)
+			}
+		}
+	}
+
+	return stored, nil
+}
+
+// SpoolImage copies an upload to a temporary file, rejecting it once it exceeds the size limit
+func SpoolImage(source io.Reader) (*os.File, error) {
+	maxBytes := int64(config.AppConfig.MaxImageSizeMB) << 20
+
+	spool, err := os.CreateTemp("", "bondihub-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	written, err := io.Copy(spool, io.LimitReader(source, maxBytes+1))
+	if err != nil {
+		removeTempFile(spool)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &ImageValidationError{Message: fmt.Sprintf("Image is larger than %d MB", config.AppConfig.MaxImageSizeMB)}
+		}
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if written > maxBytes {
+		removeTempFile(spool)
+		return nil, &ImageValidationError{Message: fmt.Sprintf("Image is larger than %d MB", config.AppConfig.MaxImageSizeMB)}
+	}
+	if written == 0 {
+		removeTempFile(spool)
+		return nil, &ImageValidationError{Message: "Image file is empty"}
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		removeTempFile(spool)
+		return nil, err
+	}
+	return spool, nil
+}
+
+// decodeUpload checks the type and dimensions of a spooled upload and decodes it upright
+func decodeUpload(spool *os.File) (image.Image, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(spool, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !uploadImageTypes[contentType] {
+		return nil, "", &ImageValidationError{Message: "Only JPEG, PNG and WebP images are accepted"}
+	}
+
+	// Check the dimensions from the header before decoding the whole image
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	header, _, err := image.DecodeConfig(spool)
+	if err != nil {
+		return nil, "", &ImageValidationError{Message: "Image file is corrupt or truncated"}
+	}
+	maxDimension := config.AppConfig.MaxImageDimension
+	if header.Width < minImageDimension || header.Height < minImageDimension {
+		return nil, "", &ImageValidationError{Message: fmt.Sprintf("Image must be at least %dx%d pixels", minImageDimension, minImageDimension)}
+	}
+	if header.Width > maxDimension || header.Height > maxDimension || header.Width*header.Height > maxImagePixels {
+		return nil, "", &ImageValidationError{Message: fmt.Sprintf("Image must be at most %dx%d pixels", maxDimension, maxDimension)}
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	img, _, err := image.Decode(spool)
+	if err != nil {
+		return nil, "", &ImageValidationError{Message: "Image file is corrupt or truncated"}
+	}
+
+	// Metadata is dropped on re-encoding, so apply the camera's orientation to the pixels first
+	if contentType == "image/jpeg" {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return nil, "", err
+		}
+		img = applyOrientation(img, jpegOrientation(spool))
+	}
+
+	return img, contentType, nil
+}
+
+// renderVariant scales an image to a standard size, never enlarging it
+func renderVariant(img image.Image, variant imageVariant) image.Image {
+	bounds := img.Bounds()
+	source := bounds
+	width, height := variant.Width, variant.Height
+
+	if variant.Crop {
+		// Crop the centre of the image to the variant's aspect ratio
+		if bounds.Dx()*height > bounds.Dy()*width {
+			cropWidth := bounds.Dy() * width / height
+			source.Min.X += (bounds.Dx() - cropWidth) / 2
+			source.Max.X = source.Min.X + cropWidth
+		} else {
+			cropHeight := bounds.Dx() * height / width
+			source.Min.Y += (bounds.Dy() - cropHeight) / 2
+			source.Max.Y = source.Min.Y + cropHeight
+		}
+		if source.Dx() < width {
+			width, height = source.Dx(), source.Dy()
+		}
+	} else {
+		// Fit inside the box, keeping the aspect ratio
+		width, height = bounds.Dx(), bounds.Dy()
+		if width > variant.Width {
+			width, height = variant.Width, height*variant.Width/width
+		}
+		if height > variant.Height {
+			width, height = width*variant.Height/height, variant.Height
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, source, draw.Over, nil)
+	return scaled
+}
+
+// flatten draws an image on a white background, since JPEG has no transparency
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, returning 1 (upright) when it has none
+func jpegOrientation(r io.Reader) int {
+	var marker [4]byte
+	if _, err := io.ReadFull(r, marker[:2]); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the segments before the image data looking for the EXIF APP1 segment
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+		kind := marker[1]
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if kind == 0xDA || length < 0 {
+			return 1 // start of scan: no EXIF segment
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return 1
+		}
+		if kind == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+	}
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates and flips an image so that it is upright for the given EXIF orientation
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	source := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(source, source.Bounds(), img, bounds.Min, draw.Src)
+	width, height := source.Rect.Dx(), source.Rect.Dy()
+
+	// Orientations 5 to 8 swap the width and height
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		outWidth, outHeight = height, width
+	}
+	out := image.NewRGBA(image.Rect(0, 0, outWidth, outHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // mirrored and rotated 90° counter-clockwise
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // mirrored and rotated 90° clockwise
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			si := source.PixOffset(x, y)
+			di := out.PixOffset(dx, dy)
+			copy(out.Pix[di:di+4], source.Pix[si:si+4])
+		}
+	}
+	return out
+}
+
+// removeTempFile closes and deletes a temporary file
+func removeTempFile(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+// ImageUploadResult is the outcome of one file of a batch upload
+type ImageUploadResult struct {
+	FileName string
+	Stored   *StoredImage
+	Err      error
+}
+
+// ImageBatch uploads spooled images concurrently with bounded parallelism. Results keep the order
+// the files were added in, and one file failing does not affect the others.
+type ImageBatch struct {
+	ctx     context.Context
+	storage ImageStorage
+	folder  string
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	results []ImageUploadResult
+}
+
+// NewImageBatch creates a batch that uploads at most concurrency images at a time
+func NewImageBatch(ctx context.Context, storage ImageStorage, folder string, concurrency int) *ImageBatch {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ImageBatch{
+		ctx:     ctx,
+		storage: storage,
+		folder:  folder,
+		slots:   make(chan struct{}, concurrency),
+	}
+}
+
+// Add starts uploading a spooled image. It blocks while the batch is at its concurrency limit, which
+// also stops the caller spooling more files to disk than are being processed.
+func (b *ImageBatch) Add(fileName string, spool *os.File) {
+	index := b.reserve(fileName)
+
+	b.slots <- struct{}{}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() { <-b.slots }()
+
+		stored, err := UploadSpooledImage(b.ctx, b.storage, spool, b.folder)
+		b.mu.Lock()
+		b.results[index].Stored, b.results[index].Err = stored, err
+		b.mu.Unlock()
+	}()
+}
+
+// Fail records a file that was rejected before it could be uploaded
+func (b *ImageBatch) Fail(fileName string, err error) {
+	index := b.reserve(fileName)
+	b.mu.Lock()
+	b.results[index].Err = err
+	b.mu.Unlock()
+}
+
+// Wait waits for all uploads to finish and returns their results in the order the files were added
+func (b *ImageBatch) Wait() []ImageUploadResult {
+	b.wg.Wait()
+	return b.results
+}
+
+// reserve adds a result slot for a file
+func (b *ImageBatch) reserve(fileName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.results = append(b.results, ImageUploadResult{FileName: fileName})
+	return len(b.results) - 1
+}
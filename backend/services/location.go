@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownLocation is returned when a location reference matches no location
+	ErrUnknownLocation = errors.New("location does not exist")
+	// ErrAmbiguousLocation is returned when a location name matches places in more than one town,
+	// e.g. "ndeke" in both Ndola and Kitwe
+	ErrAmbiguousLocation = errors.New("location name matches more than one place, use its path or ID")
+)
+
+// RentStats summarises the monthly rents of the published listings in a location
+type RentStats struct {
+	Listings    int64              `json:"listings"`
+	MinRent     float64            `json:"min_rent"`
+	MaxRent     float64            `json:"max_rent"`
+	AverageRent float64            `json:"average_rent"`
+	MedianRent  float64            `json:"median_rent"`
+	ByBedrooms  []BedroomRentStats `json:"by_bedrooms" gorm:"-"`
+}
+
+// BedroomRentStats summarises the rents of the listings in a location with a number of bedrooms
+type BedroomRentStats struct {
+	Bedrooms    int     `json:"bedrooms"`
+	Listings    int64   `json:"listings"`
+	AverageRent float64 `json:"average_rent"`
+	MedianRent  float64 `json:"median_rent"`
+}
+
+// ResolveLocation finds a location by ID, by path (e.g. "lusaka/lusaka/lusaka/kabulonga") or by name.
+// A name matching several places resolves to the one highest in the hierarchy, so "lusaka" is the
+// province; ErrAmbiguousLocation is returned when the highest matches are in different places.
+func ResolveLocation(ref string) (*models.Location, error) {
+	ref = strings.Trim(strings.ToLower(strings.TrimSpace(ref)), "/")
+	if ref == "" {
+		return nil, ErrUnknownLocation
+	}
+
+	var location models.Location
+	if id, err := uuid.Parse(ref); err == nil {
+		if err := config.DB.First(&location, id).Error; err != nil {
+			return nil, ErrUnknownLocation
+		}
+		return &location, nil
+	}
+
+	if strings.Contains(ref, "/") {
+		if err := config.DB.Where("path = ?", ref).First(&location).Error; err != nil {
+			return nil, ErrUnknownLocation
+		}
+		return &location, nil
+	}
+
+	// Match the name by slug, highest level first
+	var matches []models.Location
+	if err := config.DB.Where("slug = ?", models.LocationSlug(ref)).
+		Order("LENGTH(path) - LENGTH(REPLACE(path, '/', '')) ASC").Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, ErrUnknownLocation
+	}
+	// A town named after its district, and its district, are the same place
+	top := matches[0]
+	for _, match := range matches[1:] {
+		if strings.Count(match.Path, "/") == strings.Count(top.Path, "/") {
+			return nil, ErrAmbiguousLocation
+		}
+	}
+	return &top, nil
+}
+
+// LoadLocation returns the location with an ID, or ErrUnknownLocation
+func LoadLocation(id uuid.UUID) (*models.Location, error) {
+	var location models.Location
+	if err := config.DB.First(&location, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownLocation
+		}
+		return nil, err
+	}
+	return &location, nil
+}
+
+// LocationSubtree selects the IDs of a location and all of its descendants
+func LocationSubtree(location *models.Location) *gorm.DB {
+	// Slugs only hold letters, digits and hyphens, so the path needs no LIKE escaping
+	return config.DB.Model(&models.Location{}).Select("id").
+		Where("path = ? OR path LIKE ?", location.Path, location.Path+"/%")
+}
+
+// WithinLocation limits a house query to houses filed under a location or any place inside it
+func WithinLocation(query *gorm.DB, location *models.Location) *gorm.DB {
+	return query.Where("houses.location_id IN (?)", LocationSubtree(location))
+}
+
+// LocationAncestors returns the ancestors of a location, province first
+func LocationAncestors(location *models.Location) ([]models.Location, error) {
+	segments := strings.Split(location.Path, "/")
+	paths := make([]string, 0, len(segments)-1)
+	for i := 1; i < len(segments); i++ {
+		paths = append(paths, strings.Join(segments[:i], "/"))
+	}
+
+	ancestors := []models.Location{}
+	if len(paths) == 0 {
+		return ancestors, nil
+	}
+	err := config.DB.Where("path IN ?", paths).Order("LENGTH(path) ASC").Find(&ancestors).Error
+	return ancestors, err
+}
+
+// LocationRentStats summarises the rents of the published listings filed under a location or any
+// place inside it, overall and by number of bedrooms
+func LocationRentStats(location *models.Location) (*RentStats, error) {
+	listings := func() *gorm.DB {
+		return WithinLocation(config.DB.Model(&models.House{}), location).
+			Where("houses.listing_status = ? AND houses.monthly_rent > 0", models.ListingPublished)
+	}
+
+	stats := &RentStats{}
+	if err := listings().Select(`COUNT(*) AS listings,
+			COALESCE(MIN(monthly_rent), 0) AS min_rent,
+			COALESCE(MAX(monthly_rent), 0) AS max_rent,
+			COALESCE(ROUND(AVG(monthly_rent), 2), 0) AS average_rent,
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY monthly_rent), 0) AS median_rent`).
+		Scan(stats).Error; err != nil {
+		return nil, err
+	}
+
+	stats.ByBedrooms = []BedroomRentStats{}
+	if stats.Listings == 0 {
+		return stats, nil
+	}
+	if err := listings().Select(`bedrooms, COUNT(*) AS listings,
+			ROUND(AVG(monthly_rent), 2) AS average_rent,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY monthly_rent) AS median_rent`).
+		Group("bedrooms").Order("bedrooms ASC").Scan(&stats.ByBedrooms).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bondihub/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SetListingStatus moves a listing to a moderation state and records the change in its moderation
+// history. A rejection keeps its reason and note on the house until the listing is resubmitted.
+func SetListingStatus(tx *gorm.DB, house *models.House, status models.ListingStatus, actorID *uuid.UUID, reason, note string) error {
+	record := models.ListingModeration{
+		HouseID:    house.ID,
+		ActorID:    actorID,
+		FromStatus: house.ListingStatus,
+		ToStatus:   status,
+		Reason:     reason,
+		Note:       note,
+	}
+
+	columns := []interface{}{"submitted_at", "rejected_for", "moderator_note", "updated_at"}
+	house.ListingStatus = status
+	house.RejectedFor, house.ModeratorNote = "", ""
+	switch status {
+	case models.ListingPendingReview:
+		now := time.Now()
+		house.SubmittedAt = &now
+	case models.ListingRejected:
+		house.RejectedFor, house.ModeratorNote = reason, note
+	case models.ListingPublished:
+		// Matched against saved searches once the change is committed (see WakeSearchMatcher)
+		now := time.Now()
+		house.SearchMatchQueuedAt = &now
+		columns = append(columns, "search_match_queued_at")
+	}
+
+	if err := tx.Model(house).Select("listing_status", columns...).Updates(house).Error; err != nil {
+		return err
+	}
+	return tx.Create(&record).Error
+}
+
+// RequestListingReview sends a published listing back to the review queue, hiding it from the public,
+// after a change that moderators should check again, such as new rent, address or images. Listings in
+// any other state are left alone. It reports whether the listing went back for review.
+func RequestListingReview(tx *gorm.DB, houseID uuid.UUID, reason string) (bool, error) {
+	result := tx.Model(&models.House{}).
+		Where("id = ? AND listing_status = ?", houseID, models.ListingPublished).
+		Updates(map[string]interface{}{
+			"listing_status": models.ListingPendingReview,
+			"submitted_at":   time.Now(),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	record := models.ListingModeration{
+		HouseID:    houseID,
+		FromStatus: models.ListingPublished,
+		ToStatus:   models.ListingPendingReview,
+		Reason:     reason,
+	}
+	return true, tx.Create(&record).Error
+}
+
+// ReviewListingChange flags a landlord's change to a listing for moderators. Published listings go
+// back for review; listings already waiting for review or rejected keep their state, with the change
+// recorded in their moderation history so moderators see what changed since the listing was submitted
+// or rejected. It reports whether the listing went back for review.
+func ReviewListingChange(tx *gorm.DB, houseID uuid.UUID, reason string) (bool, error) {
+	sent, err := RequestListingReview(tx, houseID, reason)
+	if sent || err != nil {
+		return sent, err
+	}
+
+	var house models.House
+	if err := tx.Select("id", "listing_status").
+		Where("listing_status IN ?", []models.ListingStatus{models.ListingPendingReview, models.ListingRejected}).
+		Limit(1).Find(&house, houseID).Error; err != nil || house.ID == uuid.Nil {
+		return false, err
+	}
+
+	record := models.ListingModeration{
+		HouseID:    houseID,
+		FromStatus: house.ListingStatus,
+		ToStatus:   house.ListingStatus,
+		Reason:     reason,
+	}
+	return false, tx.Create(&record).Error
+}
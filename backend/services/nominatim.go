@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bondihub/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nominatimInterval spaces requests to respect the public Nominatim usage policy of one per second
+const nominatimInterval = time.Second
+
+// NominatimGeocoder geocodes with a Nominatim-compatible API, such as the OpenStreetMap service or a
+// self-hosted instance. GEOCODER_API_KEY is sent as the key parameter hosted providers expect.
+type NominatimGeocoder struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+
+	// turn holds the time of the last request; taking it from the channel is the right to send the
+	// next one, so waiting for it can be cancelled
+	turn chan time.Time
+}
+
+// nominatimPlace is a place in a Nominatim response
+type nominatimPlace struct {
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+	AddressType string `json:"addresstype"`
+	Error       string `json:"error"`
+}
+
+// NewNominatimGeocoder creates a Nominatim geocoder from the configuration
+func NewNominatimGeocoder() (*NominatimGeocoder, error) {
+	if config.AppConfig.GeocoderURL == "" {
+		return nil, errors.New("GEOCODER_URL must be set for the nominatim geocoder")
+	}
+	turn := make(chan time.Time, 1)
+	turn <- time.Time{}
+	return &NominatimGeocoder{
+		baseURL: strings.TrimRight(config.AppConfig.GeocoderURL, "/"),
+		apiKey:  config.AppConfig.GeocoderAPIKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		turn:    turn,
+	}, nil
+}
+
+// Geocode finds the coordinates of an address in Zambia
+func (ng *NominatimGeocoder) Geocode(ctx context.Context, address string) (*GeocodeResult, error) {
+	params := url.Values{}
+	params.Set("q", address)
+	params.Set("countrycodes", "zm")
+	params.Set("limit", "1")
+
+	var places []nominatimPlace
+	if err := ng.get(ctx, "/search", params, &places); err != nil {
+		return nil, err
+	}
+	if len(places) == 0 {
+		return nil, ErrNoGeocodeMatch
+	}
+	return places[0].result()
+}
+
+// ReverseGeocode suggests the street address at coordinates
+func (ng *NominatimGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(latitude, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(longitude, 'f', -1, 64))
+
+	var place nominatimPlace
+	if err := ng.get(ctx, "/reverse", params, &place); err != nil {
+		return nil, err
+	}
+	if place.Error != "" {
+		return nil, ErrNoGeocodeMatch
+	}
+	result, err := place.result()
+	if err != nil {
+		return nil, err
+	}
+	// Keep the pin where it was dropped rather than moving it to the matched feature
+	result.Latitude, result.Longitude = latitude, longitude
+	return result, nil
+}
+
+// get calls an endpoint of the API and decodes its JSON response
+func (ng *NominatimGeocoder) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	params.Set("format", "jsonv2")
+	if ng.apiKey != "" {
+		params.Set("key", ng.apiKey)
+	}
+
+	if err := ng.wait(ctx); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ng.baseURL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "BondiHub/1.0 (+"+config.AppConfig.APIBaseURL+")")
+	req.Header.Set("Accept-Language", "en")
+
+	resp, err := ng.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("geocoder returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// wait blocks until the next request may be sent, or the context is done. Requests queued behind
+// another stop waiting as soon as their own context is done.
+func (ng *NominatimGeocoder) wait(ctx context.Context) error {
+	var lastRequest time.Time
+	select {
+	case lastRequest = <-ng.turn:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if delay := time.Until(lastRequest.Add(nominatimInterval)); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			ng.turn <- lastRequest
+			return ctx.Err()
+		}
+	}
+	ng.turn <- time.Now()
+	return nil
+}
+
+// result converts a Nominatim place into a geocoding result
+func (np *nominatimPlace) result() (*GeocodeResult, error) {
+	latitude, err := strconv.ParseFloat(np.Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("geocoder returned an invalid latitude: %w", err)
+	}
+	longitude, err := strconv.ParseFloat(np.Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("geocoder returned an invalid longitude: %w", err)
+	}
+	return &GeocodeResult{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Address:   np.DisplayName,
+		Precision: np.AddressType,
+		Source:    "nominatim",
+	}, nil
+}
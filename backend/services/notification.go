@@ -0,0 +1,37 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+
+	"github.com/google/uuid"
+)
+
+// Notify creates a notification for a single user
+func Notify(userID uuid.UUID, title, message, notificationType string) error {
+	notification := models.Notification{
+		UserID:  userID,
+		Title:   title,
+		Message: message,
+		Type:    notificationType,
+	}
+	return config.DB.Create(&notification).Error
+}
+
+// NotifyAll creates the same notification for several users
+func NotifyAll(userIDs []uuid.UUID, title, message, notificationType string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	notifications := make([]models.Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		notifications = append(notifications, models.Notification{
+			UserID:  userID,
+			Title:   title,
+			Message: message,
+			Type:    notificationType,
+		})
+	}
+	return config.DB.Create(&notifications).Error
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UnitOccupancy describes one unit in a property's occupancy view
+type UnitOccupancy struct {
+	HouseID       uuid.UUID          `json:"house_id"`
+	UnitLabel     string             `json:"unit_label"`
+	Title         string             `json:"title"`
+	Status        models.HouseStatus `json:"status"`
+	MonthlyRent   float64            `json:"monthly_rent"`
+	CurrentRent   float64            `json:"current_rent"` // rent of the active agreement, 0 when vacant
+	AgreementID   *uuid.UUID         `json:"agreement_id"`
+	TenantName    string             `json:"tenant_name,omitempty"`
+	OccupiedUntil *time.Time         `json:"occupied_until"`
+	AvailableFrom *time.Time         `json:"available_from"`
+}
+
+// PropertyOccupancy summarises the occupancy and rent roll of a property's units
+type PropertyOccupancy struct {
+	TotalUnits       int             `json:"total_units"`
+	OccupiedUnits    int             `json:"occupied_units"`
+	AvailableUnits   int             `json:"available_units"`
+	MaintenanceUnits int             `json:"maintenance_units"`
+	OccupancyRate    float64         `json:"occupancy_rate"` // percentage of units occupied
+	RentRoll         float64         `json:"rent_roll"`      // monthly rent of the active agreements
+	PotentialRent    float64         `json:"potential_rent"` // monthly rent if every unit were let at its listed rent
+	VacatingSoon     int             `json:"vacating_soon"`  // occupied units handed back within 60 days
+	Units            []UnitOccupancy `json:"units"`
+}
+
+// SyncPropertyUnits copies a property's address, location and coordinates, and how they were geocoded, to
+// all of its units, so units are found by text and location searches like any other house
+func SyncPropertyUnits(tx *gorm.DB, property *models.Property) error {
+	geohash := ""
+	if property.Latitude != 0 || property.Longitude != 0 {
+		geohash = models.EncodeGeohash(property.Latitude, property.Longitude, models.GeohashPrecision)
+	}
+
+	return tx.Model(&models.House{}).
+		Where("property_id = ?", property.ID).
+		Updates(map[string]interface{}{
+			"address":           property.Address,
+			"location_id":       property.LocationID,
+			"latitude":          property.Latitude,
+			"longitude":         property.Longitude,
+			"geohash":           geohash,
+			"geocode_source":    property.GeocodeSource,
+			"geocode_precision": property.GeocodePrecision,
+		}).Error
+}
+
+// unitOccupancyRow is a unit of a property with its active agreement, if any
+type unitOccupancyRow struct {
+	ID              uuid.UUID
+	UnitLabel       string
+	Title           string
+	Status          models.HouseStatus
+	MonthlyRent     float64
+	AvailableFrom   *time.Time
+	AgreementID     *uuid.UUID
+	RentAmount      float64
+	EndDate         *time.Time
+	TerminationDate *time.Time
+	TenantName      string
+}
+
+// GetPropertyOccupancy builds the occupancy view of a property. The units and their active agreements
+// are loaded in one query, and the rent increases due on those agreements in another.
+func GetPropertyOccupancy(propertyID uuid.UUID) (*PropertyOccupancy, error) {
+	var units []unitOccupancyRow
+	if err := config.DB.Table("houses").
+		Select(`houses.id, houses.unit_label, houses.title, houses.status, houses.monthly_rent, houses.available_from,
+			agreements.id AS agreement_id, agreements.rent_amount, agreements.end_date, agreements.termination_date,
+			COALESCE(users.full_name, '') AS tenant_name`).
+		Joins(`LEFT JOIN LATERAL (SELECT * FROM rental_agreements WHERE rental_agreements.house_id = houses.id
+			AND rental_agreements.status = ? AND rental_agreements.deleted_at IS NULL
+			ORDER BY rental_agreements.start_date DESC LIMIT 1) agreements ON true`, models.AgreementStatusActive).
+		Joins("LEFT JOIN users ON users.id = agreements.tenant_id AND users.deleted_at IS NULL").
+		Where("houses.property_id = ? AND houses.deleted_at IS NULL", propertyID).
+		Order("houses.unit_label ASC, houses.title ASC").
+		Scan(&units).Error; err != nil {
+		return nil, err
+	}
+
+	// Increases that have taken effect but have not been applied by the background job yet
+	now := time.Now()
+	var agreementIDs []uuid.UUID
+	for _, unit := range units {
+		if unit.AgreementID != nil {
+			agreementIDs = append(agreementIDs, *unit.AgreementID)
+		}
+	}
+	dueEscalations := map[uuid.UUID][]models.RentEscalation{}
+	if len(agreementIDs) > 0 {
+		var escalations []models.RentEscalation
+		if err := config.DB.Where("agreement_id IN ? AND applied_at IS NULL AND effective_date <= ?", agreementIDs, now).
+			Order("effective_date ASC").Find(&escalations).Error; err != nil {
+			return nil, err
+		}
+		for _, escalation := range escalations {
+			dueEscalations[escalation.AgreementID] = append(dueEscalations[escalation.AgreementID], escalation)
+		}
+	}
+
+	occupancy := &PropertyOccupancy{Units: []UnitOccupancy{}}
+	vacatingBy := now.AddDate(0, 0, 60)
+	for _, unit := range units {
+		view := UnitOccupancy{
+			HouseID:       unit.ID,
+			UnitLabel:     unit.UnitLabel,
+			Title:         unit.Title,
+			Status:        unit.Status,
+			MonthlyRent:   unit.MonthlyRent,
+			AvailableFrom: unit.AvailableFrom,
+		}
+
+		if unit.AgreementID != nil {
+			agreement := models.RentalAgreement{EndDate: *unit.EndDate, TerminationDate: unit.TerminationDate}
+			occupiedUntil := agreement.OccupiedUntil()
+			view.AgreementID = unit.AgreementID
+			view.TenantName = unit.TenantName
+			view.CurrentRent = unit.RentAmount
+			for _, escalation := range dueEscalations[*unit.AgreementID] {
+				view.CurrentRent = escalation.Apply(view.CurrentRent)
+			}
+			view.OccupiedUntil = &occupiedUntil
+			occupancy.RentRoll += view.CurrentRent
+			if occupiedUntil.Before(vacatingBy) {
+				occupancy.VacatingSoon++
+			}
+		}
+
+		switch unit.Status {
+		case models.StatusOccupied:
+			occupancy.OccupiedUnits++
+		case models.StatusMaintenance:
+			occupancy.MaintenanceUnits++
+		default:
+			occupancy.AvailableUnits++
+		}
+		occupancy.PotentialRent += unit.MonthlyRent
+		occupancy.Units = append(occupancy.Units, view)
+	}
+
+	occupancy.TotalUnits = len(units)
+	if occupancy.TotalUnits > 0 {
+		occupancy.OccupancyRate = math.Round(float64(occupancy.OccupiedUnits)/float64(occupancy.TotalUnits)*10000) / 100
+	}
+	occupancy.RentRoll = math.Round(occupancy.RentRoll*100) / 100
+	occupancy.PotentialRent = math.Round(occupancy.PotentialRent*100) / 100
+	return occupancy, nil
+}
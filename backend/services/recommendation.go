@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Recommendations score listings from 0 to 1 by how closely they match a target: a house for similar
+// listings, or a tenant's preferences for their home feed. Each part scores from 0 to 1 and is
+// weighted as below; parts the target says nothing about score 0 for every listing.
+const (
+	proximityWeight = 0.30
+	rentWeight      = 0.25
+	typeWeight      = 0.20
+	bedroomWeight   = 0.15
+	amenityWeight   = 0.10
+)
+
+const (
+	// recommendationRadiusKm is the distance at which proximity stops counting
+	recommendationRadiusKm = 10.0
+	// similarHouseRadiusKm limits similar listings to those this close, for houses with coordinates
+	similarHouseRadiusKm = 25.0
+	// maxRecommendationPoints caps the places a tenant's feed is centred on
+	maxRecommendationPoints = 5
+	// recentViewWindow is how far back viewed listings shape a tenant's feed
+	recentViewWindow = 90 * 24 * time.Hour
+)
+
+// Seed weights: how much each listing a tenant showed interest in counts towards their preferences
+const (
+	favoriteSeedWeight    = 3.0
+	viewSeedWeight        = 1.0
+	savedSearchSeedWeight = 2.0
+)
+
+// recommendationTarget is what listings are scored against
+type recommendationTarget struct {
+	houseTypes map[models.HouseType]float64 // 0 to 1 for each preferred type
+	rent       float64                      // 0 when unknown
+	bedrooms   float64                      // -1 when unknown
+	points     [][2]float64                 // latitude and longitude of the places to be near
+	amenityIDs []uuid.UUID
+}
+
+// score adds the target's match score, and the distance to its nearest point, to a published house
+// query. Houses without coordinates score 0 for proximity.
+func (rt *recommendationTarget) score(query *gorm.DB) *gorm.DB {
+	var parts []string
+	var args []interface{}
+	selects := "houses.*"
+	var selectArgs []interface{}
+
+	if len(rt.points) > 0 {
+		distances := make([]string, 0, len(rt.points))
+		for _, point := range rt.points {
+			distanceSQL, distanceArgs := DistanceSQL(point[0], point[1])
+			distances = append(distances, distanceSQL)
+			selectArgs = append(selectArgs, distanceArgs...)
+		}
+		nearest := "LEAST(" + strings.Join(distances, ", ") + ")"
+		selects += ", CASE WHEN houses.geohash = '' THEN NULL ELSE " + nearest + " END AS distance_km"
+		parts = append(parts, fmt.Sprintf("%g * COALESCE(GREATEST(0, 1 - matches.distance_km / %g), 0)", proximityWeight, recommendationRadiusKm))
+	}
+	if rt.rent > 0 {
+		parts = append(parts, fmt.Sprintf("%g * GREATEST(0, 1 - ABS(matches.monthly_rent - ?) / ?)", rentWeight))
+		args = append(args, rt.rent, rt.rent)
+	}
+	if len(rt.houseTypes) > 0 {
+		houseTypes := make([]string, 0, len(rt.houseTypes))
+		for houseType := range rt.houseTypes {
+			houseTypes = append(houseTypes, string(houseType))
+		}
+		sort.Strings(houseTypes)
+
+		cases := make([]string, 0, len(houseTypes))
+		for _, houseType := range houseTypes {
+			cases = append(cases, fmt.Sprintf("WHEN ? THEN %g", rt.houseTypes[models.HouseType(houseType)]))
+			args = append(args, houseType)
+		}
+		parts = append(parts, fmt.Sprintf("%g * (CASE matches.house_type %s ELSE 0 END)", typeWeight, strings.Join(cases, " ")))
+	}
+	if rt.bedrooms >= 0 {
+		parts = append(parts, fmt.Sprintf("%g * GREATEST(0, 1 - ABS(matches.bedrooms - ?) / 3.0)", bedroomWeight))
+		args = append(args, rt.bedrooms)
+	}
+	if len(rt.amenityIDs) > 0 {
+		shared := config.DB.Table("(?) AS links", houseAmenityLinks()).
+			Select("links.house_id, COUNT(DISTINCT links.amenity_id) AS shared").
+			Where("links.amenity_id IN ?", rt.amenityIDs).
+			Group("links.house_id")
+		selects += ", COALESCE((SELECT shared_amenities.shared FROM (?) AS shared_amenities WHERE shared_amenities.house_id = houses.id), 0) AS shared_amenities"
+		selectArgs = append(selectArgs, shared)
+		parts = append(parts, fmt.Sprintf("%g * matches.shared_amenities / %d.0", amenityWeight, len(rt.amenityIDs)))
+	}
+
+	scoreSQL := "0"
+	if len(parts) > 0 {
+		scoreSQL = strings.Join(parts, " + ")
+	}
+
+	// Score in an outer query so the parts can use the distance and shared amenity columns
+	matches := query.Select(selects, selectArgs...)
+	return config.DB.Table("(?) AS matches", matches).
+		Select("matches.*, "+scoreSQL+" AS match_score", args...).
+		Order("match_score DESC, matches.created_at DESC")
+}
+
+// availableListings selects the published listings that are not occupied
+func availableListings() *gorm.DB {
+	return config.DB.Model(&models.House{}).
+		Where("houses.listing_status = ? AND houses.status <> ?", models.ListingPublished, models.StatusOccupied)
+}
+
+// houseAmenityIDs returns the active amenities a house offers, including its property's
+func houseAmenityIDs(houseIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	var links []struct {
+		HouseID   uuid.UUID
+		AmenityID uuid.UUID
+	}
+	if err := config.DB.Table("(?) AS links", houseAmenityLinks()).
+		Select("links.house_id, links.amenity_id").
+		Joins("JOIN amenities ON amenities.id = links.amenity_id").
+		Where("links.house_id IN ? AND amenities.is_active = ?", houseIDs, true).
+		Scan(&links).Error; err != nil {
+		return nil, err
+	}
+
+	byHouse := map[uuid.UUID][]uuid.UUID{}
+	for _, link := range links {
+		byHouse[link.HouseID] = append(byHouse[link.HouseID], link.AmenityID)
+	}
+	return byHouse, nil
+}
+
+// SimilarHousesQuery selects the available listings most like a house, best match first: nearby, in
+// the same rent band, of the same type, with about as many bedrooms and sharing its amenities. The
+// query is ordered and scored (match_score) but not limited; houses are selected as "matches".
+func SimilarHousesQuery(house *models.House) (*gorm.DB, error) {
+	amenities, err := houseAmenityIDs([]uuid.UUID{house.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	target := recommendationTarget{
+		houseTypes: map[models.HouseType]float64{house.HouseType: 1},
+		rent:       house.MonthlyRent,
+		bedrooms:   float64(house.Bedrooms),
+		amenityIDs: amenities[house.ID],
+	}
+
+	// Candidates: within half to one and a half times the rent, and nearby when the house has coordinates
+	query := availableListings().Where("houses.id <> ?", house.ID)
+	if house.MonthlyRent > 0 {
+		query = query.Where("houses.monthly_rent BETWEEN ? AND ?", house.MonthlyRent*0.5, house.MonthlyRent*1.5)
+	}
+	if house.Latitude != 0 || house.Longitude != 0 {
+		target.points = [][2]float64{{house.Latitude, house.Longitude}}
+		distanceSQL, distanceArgs := DistanceSQL(house.Latitude, house.Longitude)
+		query = WithinBoundingBox(query, RadiusBoundingBox(house.Latitude, house.Longitude, similarHouseRadiusKm))
+		query = query.Where(distanceSQL+" <= ?", append(distanceArgs, similarHouseRadiusKm)...)
+	}
+	return target.score(query), nil
+}
+
+// RecommendedHousesQuery selects available listings for a tenant's home feed, best match first,
+// from the listings they favorited and recently viewed and their saved searches. Listings they
+// already favorited or viewed, and their own, are left out. It reports false, and selects the
+// newest listings with featured ones first, when the tenant has shown no interest yet. Houses are
+// selected as "matches".
+func RecommendedHousesQuery(userID uuid.UUID) (*gorm.DB, bool, error) {
+	target, seenIDs, err := tenantPreferences(userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := availableListings().Where("houses.landlord_id <> ?", userID)
+	if len(seenIDs) > 0 {
+		query = query.Where("houses.id NOT IN ?", seenIDs)
+	}
+	if target == nil {
+		fallback := config.DB.Table("(?) AS matches", query.Select("houses.*")).
+			Select("matches.*").
+			Order("(matches.is_featured AND (matches.featured_until IS NULL OR matches.featured_until > NOW())) DESC, matches.created_at DESC")
+		return fallback, false, nil
+	}
+	return target.score(query), true, nil
+}
+
+// tenantPreferences builds a recommendation target from the listings a tenant favorited and
+// recently viewed and their saved searches, weighting each. It also returns the listings the tenant
+// has already seen. The target is nil when the tenant has shown no interest yet.
+func tenantPreferences(userID uuid.UUID) (*recommendationTarget, []uuid.UUID, error) {
+	weights := map[uuid.UUID]float64{}
+
+	var favoriteIDs []uuid.UUID
+	if err := config.DB.Model(&models.Favorite{}).Where("tenant_id = ?", userID).
+		Order("created_at DESC").Limit(20).Pluck("house_id", &favoriteIDs).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, id := range favoriteIDs {
+		weights[id] += favoriteSeedWeight
+	}
+
+	var viewedIDs []uuid.UUID
+	if err := config.DB.Model(&models.ListingEvent{}).
+		Where("viewer_id = ? AND kind = ? AND created_at > ?", userID, models.EventView, time.Now().Add(-recentViewWindow)).
+		Group("house_id").Order("MAX(created_at) DESC").Limit(30).Pluck("house_id", &viewedIDs).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, id := range viewedIDs {
+		weights[id] += viewSeedWeight
+	}
+
+	var searches []models.SavedSearch
+	if err := config.DB.Where("user_id = ?", userID).Find(&searches).Error; err != nil {
+		return nil, nil, err
+	}
+
+	seenIDs := make([]uuid.UUID, 0, len(weights))
+	for id := range weights {
+		seenIDs = append(seenIDs, id)
+	}
+	if len(weights) == 0 && len(searches) == 0 {
+		return nil, seenIDs, nil
+	}
+
+	var seeds []models.House
+	var err error
+	if len(seenIDs) > 0 {
+		if err := config.DB.Where("id IN ?", seenIDs).Find(&seeds).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	sort.Slice(seeds, func(i, j int) bool { return weights[seeds[i].ID] > weights[seeds[j].ID] })
+
+	target := &recommendationTarget{houseTypes: map[models.HouseType]float64{}, bedrooms: -1}
+	typeWeights := map[models.HouseType]float64{}
+	amenityWeights := map[uuid.UUID]float64{}
+	searchedAmenities := map[uuid.UUID]bool{}
+	var rentSum, rentWeight, bedroomSum, bedroomWeight float64
+
+	// Saved searches say most directly what the tenant wants, so their places come first
+	var amenitySlugs []string
+	for _, search := range searches {
+		if search.HouseType != "" {
+			typeWeights[search.HouseType] += savedSearchSeedWeight
+		}
+		if search.MinRent > 0 && search.MaxRent > 0 {
+			rentSum += (search.MinRent + search.MaxRent) / 2 * savedSearchSeedWeight
+			rentWeight += savedSearchSeedWeight
+		}
+		if search.Bedrooms > 0 {
+			bedroomSum += float64(search.Bedrooms) * savedSearchSeedWeight
+			bedroomWeight += savedSearchSeedWeight
+		}
+		if search.Latitude != nil && search.Longitude != nil && len(target.points) < maxRecommendationPoints {
+			target.points = append(target.points, [2]float64{*search.Latitude, *search.Longitude})
+		}
+		amenitySlugs = append(amenitySlugs, search.Amenities...)
+	}
+	if len(amenitySlugs) > 0 {
+		var searchAmenityIDs []uuid.UUID
+		if err := config.DB.Model(&models.Amenity{}).Where("slug IN ? AND is_active = ?", amenitySlugs, true).
+			Pluck("id", &searchAmenityIDs).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, id := range searchAmenityIDs {
+			searchedAmenities[id] = true
+		}
+	}
+
+	seedAmenities := map[uuid.UUID][]uuid.UUID{}
+	if len(seenIDs) > 0 {
+		if seedAmenities, err = houseAmenityIDs(seenIDs); err != nil {
+			return nil, nil, err
+		}
+	}
+	var seedWeight float64
+	for _, seed := range seeds {
+		weight := weights[seed.ID]
+		seedWeight += weight
+		typeWeights[seed.HouseType] += weight
+		rentSum += seed.MonthlyRent * weight
+		rentWeight += weight
+		bedroomSum += float64(seed.Bedrooms) * weight
+		bedroomWeight += weight
+		if (seed.Latitude != 0 || seed.Longitude != 0) && len(target.points) < maxRecommendationPoints {
+			target.points = append(target.points, [2]float64{seed.Latitude, seed.Longitude})
+		}
+		for _, amenityID := range seedAmenities[seed.ID] {
+			amenityWeights[amenityID] += weight
+		}
+	}
+
+	// Types score relative to the most preferred one
+	var topTypeWeight float64
+	for _, weight := range typeWeights {
+		topTypeWeight = max(topTypeWeight, weight)
+	}
+	for houseType, weight := range typeWeights {
+		target.houseTypes[houseType] = weight / topTypeWeight
+	}
+	if rentWeight > 0 {
+		target.rent = rentSum / rentWeight
+	}
+	if bedroomWeight > 0 {
+		target.bedrooms = bedroomSum / bedroomWeight
+	}
+
+	// Amenities asked for in a search, or offered by at least a third of what the tenant liked
+	for amenityID := range searchedAmenities {
+		target.amenityIDs = append(target.amenityIDs, amenityID)
+	}
+	for amenityID, weight := range amenityWeights {
+		if !searchedAmenities[amenityID] && weight >= seedWeight/3 {
+			target.amenityIDs = append(target.amenityIDs, amenityID)
+		}
+	}
+	return target, seenIDs, nil
+}
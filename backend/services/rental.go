@@ -0,0 +1,374 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAmendmentOverlap is returned when an amendment would make an agreement overlap another agreement
+// for the same house
+var ErrAmendmentOverlap = errors.New("the new dates overlap another agreement for the same house")
+
+// RentalService handles rental agreement lifecycle operations shared by handlers and jobs
+type RentalService struct{}
+
+// NewRentalService creates a new rental service instance
+func NewRentalService() *RentalService {
+	return &RentalService{}
+}
+
+// TenantAgreementIDs returns a subquery selecting the IDs of every agreement the user is a tenant on,
+// either as the primary tenant or as a co-tenant
+func TenantAgreementIDs(userID uuid.UUID) *gorm.DB {
+	coTenancies := config.DB.Model(&models.AgreementTenant{}).
+		Select("agreement_id").
+		Where("tenant_id = ?", userID)
+
+	return config.DB.Model(&models.RentalAgreement{}).
+		Select("id").
+		Where("tenant_id = ? OR id IN (?)", userID, coTenancies)
+}
+
+// IsAgreementTenant reports whether the user is the primary tenant or a co-tenant on the agreement
+func IsAgreementTenant(agreement *models.RentalAgreement, userID uuid.UUID) bool {
+	if agreement.TenantID == userID {
+		return true
+	}
+
+	var count int64
+	config.DB.Model(&models.AgreementTenant{}).
+		Where("agreement_id = ? AND tenant_id = ?", agreement.ID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// AgreementTenantIDs returns the IDs of every tenant on the agreement, primary tenant first
+func AgreementTenantIDs(agreement *models.RentalAgreement) []uuid.UUID {
+	var coTenantIDs []uuid.UUID
+	config.DB.Model(&models.AgreementTenant{}).
+		Where("agreement_id = ? AND tenant_id <> ?", agreement.ID, agreement.TenantID).
+		Pluck("tenant_id", &coTenantIDs)
+
+	return append([]uuid.UUID{agreement.TenantID}, coTenantIDs...)
+}
+
+// AgreementTenantShares returns the current tenants and rent shares of an agreement.
+// Agreements created before co-tenants were supported report the primary tenant paying the full rent.
+func AgreementTenantShares(tx *gorm.DB, agreement *models.RentalAgreement) []models.TenantShare {
+	var shares []models.TenantShare
+	tx.Model(&models.AgreementTenant{}).
+		Where("agreement_id = ?", agreement.ID).
+		Order("is_primary DESC, created_at ASC").
+		Select("tenant_id, rent_share").
+		Scan(&shares)
+
+	if len(shares) == 0 {
+		shares = []models.TenantShare{{TenantID: agreement.TenantID, RentShare: agreement.RentAmount}}
+	}
+	return shares
+}
+
+// RecordAgreementChange stores a change that has already been made to an agreement as a new applied version.
+// actorID is nil for changes made by background jobs.
+func RecordAgreementChange(tx *gorm.DB, agreementID uuid.UUID, actorID *uuid.UUID, changes models.FieldChanges, reason string) error {
+	now := time.Now()
+	amendment := models.AgreementAmendment{
+		AgreementID:   agreementID,
+		Status:        models.AmendmentStatusApplied,
+		Changes:       changes,
+		Reason:        reason,
+		EffectiveDate: now,
+		ProposedByID:  actorID,
+		AcceptedByID:  actorID,
+		AcceptedAt:    &now,
+		AppliedAt:     &now,
+	}
+	return CreateAgreementVersion(tx, &amendment)
+}
+
+// CreateAgreementVersion assigns the next version number for the agreement and stores the amendment.
+// The agreement is locked while the number is assigned, so versions created at the same time get
+// consecutive numbers instead of clashing.
+func CreateAgreementVersion(tx *gorm.DB, amendment *models.AgreementAmendment) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&models.RentalAgreement{}, amendment.AgreementID).Error; err != nil {
+			return err
+		}
+
+		var current int
+		if err := tx.Model(&models.AgreementAmendment{}).
+			Where("agreement_id = ?", amendment.AgreementID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&current).Error; err != nil {
+			return err
+		}
+
+		amendment.Version = current + 1
+		return tx.Create(amendment).Error
+	})
+}
+
+// ApplyAmendment applies the changes of an accepted amendment to its agreement
+func (rs *RentalService) ApplyAmendment(tx *gorm.DB, amendment *models.AgreementAmendment) error {
+	var agreement models.RentalAgreement
+	if err := tx.First(&agreement, amendment.AgreementID).Error; err != nil {
+		return err
+	}
+
+	resync := false
+	for _, change := range amendment.Changes {
+		var err error
+		switch change.Field {
+		case models.FieldRentAmount:
+			err = change.Decode(&agreement.RentAmount)
+		case models.FieldDeposit:
+			err = change.Decode(&agreement.Deposit)
+		case models.FieldStartDate:
+			agreement.StartDate, err = decodeDate(change)
+			resync = true
+		case models.FieldEndDate:
+			agreement.EndDate, err = decodeDate(change)
+			resync = true
+		case models.FieldEscalations:
+			agreement.Escalation = models.EscalationTerms{}
+			err = change.Decode(&agreement.Escalation)
+			resync = true
+		case models.FieldTenants:
+			var shares []models.TenantShare
+			if err = change.Decode(&shares); err == nil {
+				err = replaceAgreementTenants(tx, &agreement, shares)
+			}
+		default:
+			err = fmt.Errorf("field %s cannot be amended", change.Field)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply change to %s: %w", change.Field, err)
+		}
+	}
+
+	// Another agreement may have been signed for the house since the new dates were proposed
+	if err := LockHouse(tx, agreement.HouseID); err != nil {
+		return err
+	}
+	overlap, err := FindOverlappingAgreement(tx, agreement.HouseID, agreement.StartDate, agreement.OccupiedUntil(), &agreement.ID)
+	if err != nil {
+		return err
+	}
+	if overlap != nil {
+		return fmt.Errorf("%w: agreement %s", ErrAmendmentOverlap, overlap.ID)
+	}
+
+	agreement.UpdatedAt = time.Now()
+	if err := tx.Omit(clause.Associations).Save(&agreement).Error; err != nil {
+		return err
+	}
+
+	if err := RefreshHouseAvailability(tx, agreement.HouseID); err != nil {
+		return err
+	}
+
+	// Rebuild the rent increases still to come under the new dates or clause
+	if resync {
+		if err := SyncEscalationSchedule(tx, &agreement, amendment.EffectiveDate); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	amendment.Status = models.AmendmentStatusApplied
+	amendment.AppliedAt = &now
+	return tx.Omit(clause.Associations).Save(amendment).Error
+}
+
+// ProcessDueAmendments applies accepted amendments whose effective date has arrived
+func (rs *RentalService) ProcessDueAmendments() error {
+	var amendments []models.AgreementAmendment
+	if err := config.DB.
+		Where("status = ? AND effective_date <= ?", models.AmendmentStatusAccepted, time.Now()).
+		Order("version ASC").
+		Find(&amendments).Error; err != nil {
+		return fmt.Errorf("failed to load due amendments: %w", err)
+	}
+
+	for i := range amendments {
+		amendment := &amendments[i]
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return rs.ApplyAmendment(tx, amendment)
+		})
+		if err != nil {
+			log.Printf("Failed to apply amendment %s: %v", amendment.ID, err)
+			continue
+		}
+
+		var agreement models.RentalAgreement
+		if err := config.DB.Preload("House").First(&agreement, amendment.AgreementID).Error; err != nil {
+			continue
+		}
+		message := fmt.Sprintf("Version %d of the rental agreement for %s is now in effect", amendment.Version, agreement.House.Title)
+		NotifyAll(AgreementTenantIDs(&agreement), "Agreement Amendment in Effect", message, "agreement")
+		Notify(agreement.House.LandlordID, "Agreement Amendment in Effect", message, "agreement")
+	}
+
+	return nil
+}
+
+// replaceAgreementTenants replaces the tenants and rent shares of an agreement
+func replaceAgreementTenants(tx *gorm.DB, agreement *models.RentalAgreement, shares []models.TenantShare) error {
+	keep := make([]uuid.UUID, 0, len(shares))
+	for _, share := range shares {
+		keep = append(keep, share.TenantID)
+
+		tenant := models.AgreementTenant{
+			AgreementID: agreement.ID,
+			TenantID:    share.TenantID,
+		}
+		if err := tx.Where(models.AgreementTenant{AgreementID: agreement.ID, TenantID: share.TenantID}).
+			Assign(map[string]interface{}{
+				"rent_share": share.RentShare,
+				"is_primary": share.TenantID == agreement.TenantID,
+			}).
+			FirstOrCreate(&tenant).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Where("agreement_id = ? AND tenant_id NOT IN ?", agreement.ID, keep).
+		Delete(&models.AgreementTenant{}).Error
+}
+
+// decodeDate decodes a YYYY-MM-DD date from the new value of a change
+func decodeDate(change models.FieldChange) (time.Time, error) {
+	var value string
+	if err := change.Decode(&value); err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// TerminateAgreement ends an agreement now, frees up the house and completes any acknowledged notice.
+// Upcoming agreements can be terminated too, which cancels the pre-lease.
+// actorID is nil when the termination is applied by a background job.
+func (rs *RentalService) TerminateAgreement(agreement *models.RentalAgreement, actorID *uuid.UUID) error {
+	now := time.Now()
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		changes := models.FieldChanges{{
+			Field: models.FieldStatus,
+			From:  agreement.Status,
+			To:    models.AgreementStatusTerminated,
+		}}
+
+		agreement.Status = models.AgreementStatusTerminated
+		if agreement.TerminationDate == nil || agreement.TerminationDate.After(now) {
+			changes = append(changes, models.FieldChange{
+				Field: models.FieldTerminationDate,
+				From:  FormatOptionalDate(agreement.TerminationDate),
+				To:    now.Format("2006-01-02"),
+			})
+			agreement.TerminationDate = &now
+		}
+		agreement.UpdatedAt = now
+
+		if err := tx.Omit(clause.Associations).Save(agreement).Error; err != nil {
+			return err
+		}
+
+		if err := RecordAgreementChange(tx, agreement.ID, actorID, changes, "Agreement terminated"); err != nil {
+			return err
+		}
+
+		if err := RefreshHouseAvailability(tx, agreement.HouseID); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.MoveOutNotice{}).
+			Where("agreement_id = ? AND status = ?", agreement.ID, models.NoticeStatusAcknowledged).
+			Update("status", models.NoticeStatusCompleted).Error
+	})
+}
+
+// ProcessScheduledTerminations terminates active agreements whose scheduled termination date has passed
+func (rs *RentalService) ProcessScheduledTerminations() error {
+	var agreements []models.RentalAgreement
+	if err := config.DB.Preload("House").
+		Where("status = ? AND termination_date IS NOT NULL AND termination_date <= ?",
+			models.AgreementStatusActive, time.Now()).
+		Find(&agreements).Error; err != nil {
+		return fmt.Errorf("failed to load scheduled terminations: %w", err)
+	}
+
+	for i := range agreements {
+		agreement := &agreements[i]
+		if err := rs.TerminateAgreement(agreement, nil); err != nil {
+			log.Printf("Failed to terminate agreement %s: %v", agreement.ID, err)
+			continue
+		}
+
+		message := fmt.Sprintf("The rental agreement for %s ended on %s",
+			agreement.House.Title, agreement.TerminationDate.Format("2006-01-02"))
+		NotifyAll(AgreementTenantIDs(agreement), "Rental Agreement Terminated", message, "agreement")
+		Notify(agreement.House.LandlordID, "Rental Agreement Terminated", message, "agreement")
+	}
+
+	return nil
+}
+
+// ExpireEndedAgreements marks active agreements whose end date has passed as expired, freeing their
+// houses. Agreements terminated before their end date are handled by ProcessScheduledTerminations.
+func (rs *RentalService) ExpireEndedAgreements() error {
+	var agreements []models.RentalAgreement
+	if err := config.DB.Preload("House").
+		Where("status = ? AND end_date <= ? AND (termination_date IS NULL OR termination_date > end_date)",
+			models.AgreementStatusActive, time.Now()).
+		Find(&agreements).Error; err != nil {
+		return fmt.Errorf("failed to load ended agreements: %w", err)
+	}
+
+	for i := range agreements {
+		agreement := &agreements[i]
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(agreement).Update("status", models.AgreementStatusExpired).Error; err != nil {
+				return err
+			}
+			if err := RecordAgreementChange(tx, agreement.ID, nil, models.FieldChanges{
+				{Field: models.FieldStatus, From: models.AgreementStatusActive, To: models.AgreementStatusExpired},
+			}, "Agreement expired"); err != nil {
+				return err
+			}
+			if err := RefreshHouseAvailability(tx, agreement.HouseID); err != nil {
+				return err
+			}
+			return tx.Model(&models.MoveOutNotice{}).
+				Where("agreement_id = ? AND status = ?", agreement.ID, models.NoticeStatusAcknowledged).
+				Update("status", models.NoticeStatusCompleted).Error
+		})
+		if err != nil {
+			log.Printf("Failed to expire agreement %s: %v", agreement.ID, err)
+			continue
+		}
+
+		message := fmt.Sprintf("The rental agreement for %s ended on %s",
+			agreement.House.Title, agreement.EndDate.Format("2006-01-02"))
+		NotifyAll(AgreementTenantIDs(agreement), "Rental Agreement Ended", message, "agreement")
+		Notify(agreement.House.LandlordID, "Rental Agreement Ended", message, "agreement")
+	}
+
+	return nil
+}
+
+// FormatOptionalDate formats a date for an amendment diff, returning nil when the date is unset
+func FormatOptionalDate(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+	return date.Format("2006-01-02")
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReportHideReason is the moderation history reason recorded when reports hide a listing
+const ReportHideReason = "Hidden after reports from users"
+
+// HideReportedListing hides a published listing once enough different users have open reports on it,
+// sending it to the moderation queue. It reports whether the listing was hidden.
+func HideReportedListing(tx *gorm.DB, houseID uuid.UUID) (bool, error) {
+	threshold := config.AppConfig.ReportThreshold
+	if threshold <= 0 {
+		return false, nil
+	}
+
+	var reporters int64
+	if err := tx.Model(&models.ListingReport{}).
+		Where("house_id = ? AND status = ?", houseID, models.ReportStatusOpen).
+		Distinct("reporter_id").Count(&reporters).Error; err != nil {
+		return false, err
+	}
+	if reporters < int64(threshold) {
+		return false, nil
+	}
+	return RequestListingReview(tx, houseID, ReportHideReason)
+}
+
+// RestoreReportedListing publishes a listing again when reports hid it and nothing else has changed
+// since. It reports whether the listing was published.
+func RestoreReportedListing(tx *gorm.DB, house *models.House, actorID uuid.UUID) (bool, error) {
+	if house.ListingStatus != models.ListingPendingReview {
+		return false, nil
+	}
+
+	var last models.ListingModeration
+	err := tx.Where("house_id = ?", house.ID).Order("created_at DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if last.Reason != ReportHideReason || last.FromStatus != models.ListingPublished {
+		return false, nil
+	}
+	return true, SetListingStatus(tx, house, models.ListingPublished, &actorID, "Reports dismissed", "")
+}
+
+// ResolveListingReports resolves the open reports of the given listings and returns the users who
+// made them, so they can be told the outcome
+func ResolveListingReports(tx *gorm.DB, houseIDs []uuid.UUID, resolution models.ReportResolution, resolvedBy uuid.UUID, note string) ([]uuid.UUID, error) {
+	var reporterIDs []uuid.UUID
+	if err := tx.Model(&models.ListingReport{}).
+		Where("house_id IN ? AND status = ?", houseIDs, models.ReportStatusOpen).
+		Distinct("reporter_id").Pluck("reporter_id", &reporterIDs).Error; err != nil {
+		return nil, err
+	}
+
+	err := tx.Model(&models.ListingReport{}).
+		Where("house_id IN ? AND status = ?", houseIDs, models.ReportStatusOpen).
+		Updates(map[string]interface{}{
+			"status":          models.ReportStatusResolved,
+			"resolution":      resolution,
+			"resolution_note": note,
+			"resolved_by_id":  resolvedBy,
+			"resolved_at":     time.Now(),
+		}).Error
+	return reporterIDs, err
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// imageMatchDistance is the most hash bits, of 64, that may differ for two images to count as the same
+// photo. The hash bands are sized so that matches always share a band.
+const imageMatchDistance = 8
+
+// maxImageMatches caps the matches reported for one image
+const maxImageMatches = 20
+
+// ImageMatchReason is the moderation history reason recorded when a reused photo sends a listing for review
+const ImageMatchReason = "Photo matches a listing by another landlord"
+
+// Text similarity thresholds, as pg_trgm similarity from 0 to 1. Short descriptions are not compared,
+// as boilerplate such as "Spacious 2 bedroom flat" is shared by honest listings.
+const (
+	similarTitleThreshold       = 0.7
+	similarDescriptionThreshold = 0.6
+	similarAddressThreshold     = 0.8
+	minComparedDescription      = 60
+)
+
+// Risk score points for each kind of signal. Each other listing a photo is reused from adds points,
+// up to maxImageMatchPoints; text signals count once, for the most similar listing.
+var riskSignalPoints = map[models.RiskSignalKind]int{
+	models.SignalImageMatch:         35,
+	models.SignalSimilarDescription: 30,
+	models.SignalSimilarTitle:       15,
+	models.SignalSimilarAddress:     15,
+}
+
+const maxImageMatchPoints = 70
+
+// FindImageMatches finds images on other landlords' listings that match an image's perceptual hash,
+// closest first. Candidates are found through the indexed hash bands, as any image within
+// imageMatchDistance shares at least one band, and only they are compared bit by bit.
+func FindImageMatches(db *gorm.DB, houseImage *models.HouseImage, landlordID uuid.UUID) ([]models.RiskSignal, error) {
+	if houseImage.ImageHash == nil {
+		return nil, nil
+	}
+	hash := *houseImage.ImageHash
+
+	bands := models.ImageHashBands
+	sharesBand := db.Session(&gorm.Session{NewDB: true}).Where(bands[0].SQL()+" = ?", bands[0].Value(hash))
+	for _, band := range bands[1:] {
+		sharesBand = sharesBand.Or(band.SQL()+" = ?", band.Value(hash))
+	}
+
+	var candidates []models.HouseImage
+	if err := db.Model(&models.HouseImage{}).
+		Select("house_images.id", "house_images.house_id", "house_images.image_hash").
+		Joins("JOIN houses ON houses.id = house_images.house_id AND houses.deleted_at IS NULL").
+		Where("houses.landlord_id <> ? AND house_images.image_hash IS NOT NULL", landlordID).
+		Where(sharesBand).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var signals []models.RiskSignal
+	for _, candidate := range candidates {
+		distance := HashDistance(hash, *candidate.ImageHash)
+		if distance > imageMatchDistance {
+			continue
+		}
+		imageID, matchedImageID := houseImage.ID, candidate.ID
+		signals = append(signals, models.RiskSignal{
+			Kind:           models.SignalImageMatch,
+			HouseID:        candidate.HouseID,
+			ImageID:        &imageID,
+			MatchedImageID: &matchedImageID,
+			Similarity:     1 - float64(distance)/64,
+		})
+	}
+	sort.SliceStable(signals, func(i, j int) bool {
+		return signals[i].Similarity > signals[j].Similarity
+	})
+	if len(signals) > maxImageMatches {
+		signals = signals[:maxImageMatches]
+	}
+	return signals, nil
+}
+
+// ScreenHouseImage checks a newly uploaded image against the photos of other landlords' listings.
+// A match sends a published listing back for review; reassess the listing's risk afterwards to
+// record it. It reports whether the image matched.
+func ScreenHouseImage(houseImage *models.HouseImage) (bool, error) {
+	var house models.House
+	if err := config.DB.First(&house, houseImage.HouseID).Error; err != nil {
+		return false, err
+	}
+
+	matches, err := FindImageMatches(config.DB, houseImage, house.LandlordID)
+	if err != nil || len(matches) == 0 {
+		return false, err
+	}
+
+	_, err = RequestListingReview(config.DB, house.ID, ImageMatchReason)
+	return true, err
+}
+
+// AssessListingRisk scores how likely a listing is to be a duplicate or scam, from photos reused from
+// other landlords' listings and titles, descriptions and addresses nearly identical to theirs, and
+// saves the assessment for moderators
+func AssessListingRisk(houseID uuid.UUID) (*models.ListingRisk, error) {
+	var house models.House
+	if err := config.DB.Preload("Images").First(&house, houseID).Error; err != nil {
+		return nil, err
+	}
+
+	signals := models.RiskSignals{}
+
+	// Reused photos: keep the closest match with each other listing
+	imagePoints := 0
+	matchedHouses := map[uuid.UUID]bool{}
+	for i := range house.Images {
+		matches, err := FindImageMatches(config.DB, &house.Images[i], house.LandlordID)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if matchedHouses[match.HouseID] {
+				continue
+			}
+			matchedHouses[match.HouseID] = true
+			match.Points = min(riskSignalPoints[models.SignalImageMatch], maxImageMatchPoints-imagePoints)
+			imagePoints += match.Points
+			signals = append(signals, match)
+		}
+	}
+
+	// Near-duplicate text
+	textSignals, err := findSimilarText(&house)
+	if err != nil {
+		return nil, err
+	}
+	signals = append(signals, textSignals...)
+
+	score := 0
+	for _, signal := range signals {
+		score += signal.Points
+	}
+
+	risk := models.ListingRisk{
+		HouseID:    house.ID,
+		Score:      min(score, 100),
+		Signals:    signals,
+		AssessedAt: time.Now(),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&risk).Error; err != nil {
+		return nil, err
+	}
+	return &risk, nil
+}
+
+// findSimilarText finds the other landlords' listings whose title, description or address is nearly
+// identical to the listing's, keeping the most similar listing for each
+func findSimilarText(house *models.House) ([]models.RiskSignal, error) {
+	var candidates []struct {
+		ID                    uuid.UUID
+		TitleSimilarity       float64
+		DescriptionSimilarity float64
+		AddressSimilarity     float64
+	}
+	similar := config.DB.Model(&models.House{}).
+		Select("id, similarity(title, ?) AS title_similarity, similarity(description, ?) AS description_similarity, similarity(address, ?) AS address_similarity",
+			house.Title, house.Description, house.Address).
+		Where("landlord_id <> ? AND (title % ? OR address % ? OR description % ?)",
+			house.LandlordID, house.Title, house.Address, house.Description)
+	if err := config.DB.Table("(?) AS candidates", similar).
+		Order("GREATEST(title_similarity, description_similarity, address_similarity) DESC").
+		Limit(20).Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	best := map[models.RiskSignalKind]models.RiskSignal{}
+	consider := func(kind models.RiskSignalKind, houseID uuid.UUID, similarity, threshold float64) {
+		if similarity >= threshold && similarity > best[kind].Similarity {
+			best[kind] = models.RiskSignal{Kind: kind, HouseID: houseID, Similarity: similarity, Points: riskSignalPoints[kind]}
+		}
+	}
+	for _, candidate := range candidates {
+		consider(models.SignalSimilarTitle, candidate.ID, candidate.TitleSimilarity, similarTitleThreshold)
+		if len(house.Description) >= minComparedDescription {
+			consider(models.SignalSimilarDescription, candidate.ID, candidate.DescriptionSimilarity, similarDescriptionThreshold)
+		}
+		consider(models.SignalSimilarAddress, candidate.ID, candidate.AddressSimilarity, similarAddressThreshold)
+	}
+
+	var signals []models.RiskSignal
+	for _, kind := range []models.RiskSignalKind{models.SignalSimilarDescription, models.SignalSimilarTitle, models.SignalSimilarAddress} {
+		if signal, found := best[kind]; found {
+			signals = append(signals, signal)
+		}
+	}
+	return signals, nil
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"bondihub/config"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage stores images in an S3-compatible object store (AWS S3, MinIO, DigitalOcean Spaces,
+// Cloudflare R2) using path-style requests signed with AWS Signature Version 4
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	publicURL string
+	client    *http.Client
+}
+
+// NewS3Storage creates an S3-compatible image storage
+func NewS3Storage() (*S3Storage, error) {
+	cfg := config.AppConfig
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+		return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY must be set")
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.S3Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.S3Endpoint)
+	}
+
+	publicURL := strings.TrimRight(cfg.S3PublicURL, "/")
+	if publicURL == "" {
+		publicURL = endpoint.String() + "/" + cfg.S3Bucket
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    cfg.S3Region,
+		bucket:    cfg.S3Bucket,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		publicURL: publicURL,
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// Upload puts an image in the bucket. Files and seekable readers are streamed; other readers are buffered
+// because object stores need the content length up front.
+func (s3 *S3Storage) Upload(ctx context.Context, image io.Reader, folder string) (*StoredImage, error) {
+	size, known := readerSize(image)
+
+	key, contentType, reader, err := newImageKey(image, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s3.put(ctx, key, contentType, reader, size, known); err != nil {
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+
+	return &StoredImage{PublicID: key, URL: s3.URL(key, ImageSizeOriginal)}, nil
+}
+
+// UploadVariant puts a standard size of an image next to the original
+func (s3 *S3Storage) UploadVariant(ctx context.Context, publicID string, size ImageSize, image io.Reader) error {
+	length, known := readerSize(image)
+	if err := s3.put(ctx, variantKey(publicID, size), "image/jpeg", image, length, known); err != nil {
+		return fmt.Errorf("failed to upload %s image: %w", size, err)
+	}
+	return nil
+}
+
+// Delete removes an image and its standard sizes from the bucket
+func (s3 *S3Storage) Delete(ctx context.Context, publicID string) error {
+	for _, size := range []ImageSize{ImageSizeOriginal, ImageSizeMedium, ImageSizeThumbnail} {
+		req, err := s3.newRequest(ctx, http.MethodDelete, variantKey(publicID, size), http.NoBody, 0, sha256Hex(nil))
+		if err != nil {
+			return err
+		}
+		if err := s3.do(req); err != nil {
+			return fmt.Errorf("failed to delete image: %w", err)
+		}
+	}
+	return nil
+}
+
+// URL returns the public URL of an image at the given size
+func (s3 *S3Storage) URL(publicID string, size ImageSize) string {
+	return s3.publicURL + "/" + variantKey(publicID, size)
+}
+
+// put uploads an object. The payload is sent unsigned so it can be streamed without hashing it first.
+func (s3 *S3Storage) put(ctx context.Context, key, contentType string, body io.Reader, size int64, known bool) error {
+	if !known {
+		buffered, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("failed to read image: %w", err)
+		}
+		body, size = bytes.NewReader(buffered), int64(len(buffered))
+	}
+
+	req, err := s3.newRequest(ctx, http.MethodPut, key, body, size, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return s3.do(req)
+}
+
+// newRequest builds a signed request for an object in the bucket
+func (s3 *S3Storage) newRequest(ctx context.Context, method, key string, body io.Reader, size int64, payloadHash string) (*http.Request, error) {
+	objectURL := *s3.endpoint
+	objectURL.Path = "/" + s3.bucket + "/" + strings.TrimPrefix(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+
+	s3.sign(req, payloadHash, time.Now().UTC())
+	return req, nil
+}
+
+// readerSize returns the number of bytes left in a reader when it can be known without reading it
+func readerSize(r io.Reader) (int64, bool) {
+	switch reader := r.(type) {
+	case interface{ Len() int }:
+		return int64(reader.Len()), true
+	case io.Seeker:
+		current, err := reader.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := reader.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err := reader.Seek(current, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - current, true
+	}
+	return 0, false
+}
+
+// do sends a request and turns error responses into errors. A missing object is not an error.
+func (s3 *S3Storage) do(req *http.Request) error {
+	resp, err := s3.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("object store returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to a request
+func (s3 *S3Storage) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Canonical headers: lower-case names, sorted
+	signed := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s3.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(s3.signingKey(date), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3.accessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the Signature Version 4 key for a day
+func (s3 *S3Storage) signingKey(date string) []byte {
+	key := hmacSHA256([]byte("AWS4"+s3.secretKey), date)
+	key = hmacSHA256(key, s3.region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+// SignUpload returns a signed POST policy that lets a client upload one image to the staging folder.
+// The policy limits the file size and requires an image content type.
+func (s3 *S3Storage) SignUpload(folder string, expiresAt time.Time) (*DirectUpload, error) {
+	key := newStagingKey()
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	credential := s3.accessKey + "/" + date + "/" + s3.region + "/s3/aws4_request"
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": expiresAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"conditions": []interface{}{
+			map[string]string{"bucket": s3.bucket},
+			map[string]string{"key": key},
+			[]interface{}{"content-length-range", 1, int64(config.AppConfig.MaxImageSizeMB) << 20},
+			[]interface{}{"starts-with", "$Content-Type", "image/"},
+			map[string]string{"x-amz-algorithm": "AWS4-HMAC-SHA256"},
+			map[string]string{"x-amz-credential": credential},
+			map[string]string{"x-amz-date": amzDate},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policy)
+
+	return &DirectUpload{
+		URL:    s3.endpoint.String() + "/" + s3.bucket,
+		Method: "POST",
+		Fields: map[string]string{
+			"key":              key,
+			"Content-Type":     "image/jpeg",
+			"policy":           encodedPolicy,
+			"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+			"x-amz-credential": credential,
+			"x-amz-date":       amzDate,
+			"x-amz-signature":  hex.EncodeToString(hmacSHA256(s3.signingKey(date), encodedPolicy)),
+		},
+		FileField: "file",
+		PublicID:  key,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ConfirmUpload runs a direct upload through image validation into the folder and removes the staged object
+func (s3 *S3Storage) ConfirmUpload(ctx context.Context, publicID, folder string) (*StoredImage, error) {
+	if !isStagingKey(publicID) {
+		return nil, ErrUploadNotFound
+	}
+
+	req, err := s3.newRequest(ctx, http.MethodGet, publicID, http.NoBody, 0, sha256Hex(nil))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch uploaded image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrUploadNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch uploaded image: object store returned %s", resp.Status)
+	}
+
+	stored, err := UploadImage(ctx, s3, resp.Body, folder)
+	if err != nil {
+		var invalid *ImageValidationError
+		if errors.As(err, &invalid) {
+			s3.Delete(ctx, publicID)
+		}
+		return nil, err
+	}
+	s3.Delete(ctx, publicID)
+	return stored, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
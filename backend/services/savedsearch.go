@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// digestListingTitles caps the listings named in one daily digest
+const digestListingTitles = 5
+
+// savedSearchBatchSize is the number of queued listings, or of due digests, handled per query
+const savedSearchBatchSize = 100
+
+// Publishing a listing queues it to be matched against saved searches in the same transaction, then
+// wakes the background matcher, so matching every saved search never holds up the request. The
+// search_matches job picks up listings the matcher has not reached, e.g. after a restart.
+var (
+	searchMatcherWake  = make(chan struct{}, 1)
+	searchMatcherStart sync.Once
+)
+
+// WakeSearchMatcher has the background matcher match the queued listings. It never blocks.
+func WakeSearchMatcher() {
+	searchMatcherStart.Do(func() {
+		go func() {
+			for range searchMatcherWake {
+				if err := NewSavedSearchService().MatchQueuedListings(); err != nil {
+					log.Printf("Failed to match queued listings against saved searches: %v", err)
+				}
+			}
+		}()
+	})
+	select {
+	case searchMatcherWake <- struct{}{}:
+	default:
+	}
+}
+
+// savedSearchMatch is a saved search matched by a newly published listing
+type savedSearchMatch struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	Name           string
+	AlertFrequency models.AlertFrequency
+}
+
+// MatchSavedSearches records a newly published listing against every saved search it matches, and
+// alerts the users whose searches alert instantly. Matches for daily searches wait for the next
+// digest. Every saved search is matched by a single query, and a listing is only alerted once per
+// search, so listings published again after an edit are not repeated. It returns the number of
+// searches matched.
+func MatchSavedSearches(houseID uuid.UUID) (int, error) {
+	var house models.House
+	if err := config.DB.First(&house, houseID).Error; err != nil {
+		return 0, err
+	}
+	if house.ListingStatus != models.ListingPublished {
+		return 0, nil
+	}
+
+	// Amenities the house offers, including its property's
+	var slugs []string
+	if err := config.DB.Table("(?) AS links", houseAmenityLinks()).
+		Joins("JOIN amenities ON amenities.id = links.amenity_id").
+		Where("links.house_id = ? AND amenities.is_active = ?", house.ID, true).
+		Distinct().Pluck("amenities.slug", &slugs).Error; err != nil {
+		return 0, err
+	}
+	offered, err := json.Marshal(models.StringList(slugs))
+	if err != nil {
+		return 0, err
+	}
+
+	var matches []savedSearchMatch
+	if err := config.DB.Raw(`WITH matched AS (
+			INSERT INTO saved_search_matches (id, saved_search_id, house_id, created_at)
+			SELECT gen_random_uuid(), saved_searches.id, houses.id, NOW()
+			FROM saved_searches JOIN houses ON houses.id = ?
+			WHERE saved_searches.deleted_at IS NULL
+				AND saved_searches.alert_frequency <> ?
+				AND saved_searches.user_id <> houses.landlord_id
+				AND (saved_searches.house_type = '' OR saved_searches.house_type = houses.house_type)
+				AND (saved_searches.min_rent = 0 OR houses.monthly_rent >= saved_searches.min_rent)
+				AND (saved_searches.max_rent = 0 OR houses.monthly_rent <= saved_searches.max_rent)
+				AND houses.bedrooms >= saved_searches.bedrooms
+				AND saved_searches.amenities <@ ?::jsonb
+				AND (saved_searches.latitude IS NULL OR (houses.geohash <> '' AND `+
+		ColumnDistanceSQL("saved_searches.latitude", "saved_searches.longitude")+` <= saved_searches.radius_km))
+				AND (saved_searches.search = '' OR `+searchCondition("saved_searches.search")+`)
+			ON CONFLICT (saved_search_id, house_id) DO NOTHING
+			RETURNING id, saved_search_id
+		)
+		SELECT matched.id, saved_searches.user_id, saved_searches.name, saved_searches.alert_frequency
+		FROM matched JOIN saved_searches ON saved_searches.id = matched.saved_search_id`,
+		house.ID, models.AlertOff, string(offered)).Scan(&matches).Error; err != nil {
+		return 0, err
+	}
+
+	// Alert instant searches now, once per user
+	searchNames := map[uuid.UUID][]string{}
+	var instantIDs []uuid.UUID
+	for _, match := range matches {
+		if match.AlertFrequency == models.AlertInstant {
+			searchNames[match.UserID] = append(searchNames[match.UserID], fmt.Sprintf("\"%s\"", match.Name))
+			instantIDs = append(instantIDs, match.ID)
+		}
+	}
+	for userID, names := range searchNames {
+		message := fmt.Sprintf("A new listing matches your saved search %s: %s, %s, %.2f per month.",
+			strings.Join(names, ", "), house.Title, house.Address, house.MonthlyRent)
+		if err := Notify(userID, "New Listing Matches Your Search", message, "search"); err != nil {
+			log.Printf("Failed to alert user %s about house %s: %v", userID, house.ID, err)
+		}
+	}
+	if len(instantIDs) > 0 {
+		if err := config.DB.Model(&models.SavedSearchMatch{}).Where("id IN ?", instantIDs).
+			Update("notified_at", time.Now()).Error; err != nil {
+			return len(matches), err
+		}
+	}
+	return len(matches), nil
+}
+
+// SavedSearchService matches published listings against saved searches and sends the daily digests
+type SavedSearchService struct{}
+
+// NewSavedSearchService creates a new saved search service
+func NewSavedSearchService() *SavedSearchService {
+	return &SavedSearchService{}
+}
+
+// MatchQueuedListings matches the listings queued since they were published against saved searches,
+// oldest first, until none are left. A listing queued again while it is being matched stays queued.
+func (sss *SavedSearchService) MatchQueuedListings() error {
+	matched := 0
+	for {
+		var houses []models.House
+		if err := config.DB.Select("id", "search_match_queued_at").Where("search_match_queued_at IS NOT NULL").
+			Order("search_match_queued_at ASC").Limit(savedSearchBatchSize).Find(&houses).Error; err != nil {
+			return err
+		}
+
+		for _, house := range houses {
+			count, err := MatchSavedSearches(house.ID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			matched += count
+			if err := config.DB.Model(&models.House{}).
+				Where("id = ? AND search_match_queued_at = ?", house.ID, house.SearchMatchQueuedAt).
+				UpdateColumn("search_match_queued_at", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(houses) < savedSearchBatchSize {
+			break
+		}
+	}
+
+	if matched > 0 {
+		log.Printf("Matched published listings against %d saved searches", matched)
+	}
+	return nil
+}
+
+// SendSearchDigests sends a digest for each daily saved search that has new matches and has not
+// had a digest for a day, in batches until none are due. Listings no longer published by then are
+// left out.
+func (sss *SavedSearchService) SendSearchDigests() error {
+	sent := 0
+	for {
+		var searches []models.SavedSearch
+		if err := config.DB.Where("alert_frequency = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)",
+			models.AlertDaily, time.Now().Add(-24*time.Hour)).
+			Where("EXISTS (SELECT 1 FROM saved_search_matches WHERE saved_search_matches.saved_search_id = saved_searches.id AND saved_search_matches.notified_at IS NULL)").
+			Order("last_digest_at ASC NULLS FIRST").Limit(savedSearchBatchSize).Find(&searches).Error; err != nil {
+			return err
+		}
+
+		for _, search := range searches {
+			notified, err := sss.sendDigest(search, "Your Daily Listing Digest")
+			if err != nil {
+				return err
+			}
+			if notified {
+				sent++
+			}
+		}
+
+		if len(searches) < savedSearchBatchSize {
+			break
+		}
+	}
+
+	if sent > 0 {
+		log.Printf("Sent %d saved search digests", sent)
+	}
+	return nil
+}
+
+// FlushPendingMatches settles the matches a saved search was holding for its daily digest once it
+// no longer alerts daily: searches now alerting instantly are sent them at once, and searches with
+// alerts off drop them.
+func (sss *SavedSearchService) FlushPendingMatches(search models.SavedSearch) error {
+	switch search.AlertFrequency {
+	case models.AlertDaily:
+		return nil
+	case models.AlertInstant:
+		_, err := sss.sendDigest(search, "New Listings Match Your Search")
+		return err
+	default:
+		return config.DB.Model(&models.SavedSearchMatch{}).
+			Where("saved_search_id = ? AND notified_at IS NULL", search.ID).
+			Update("notified_at", time.Now()).Error
+	}
+}
+
+// sendDigest notifies the user of a saved search about its matches not yet alerted, and marks them
+// alerted. It reports whether a notification was sent, which it is not when none of the listings is
+// still published.
+func (sss *SavedSearchService) sendDigest(search models.SavedSearch, title string) (bool, error) {
+	var matches []models.SavedSearchMatch
+	if err := config.DB.Preload("House").Where("saved_search_id = ? AND notified_at IS NULL", search.ID).
+		Order("created_at ASC").Find(&matches).Error; err != nil {
+		return false, err
+	}
+
+	var titles []string
+	matchIDs := make([]uuid.UUID, 0, len(matches))
+	for _, match := range matches {
+		matchIDs = append(matchIDs, match.ID)
+		if match.House.ID != uuid.Nil && match.House.ListingStatus == models.ListingPublished {
+			titles = append(titles, fmt.Sprintf("%s (%.2f per month)", match.House.Title, match.House.MonthlyRent))
+		}
+	}
+
+	if len(titles) > 0 {
+		message := fmt.Sprintf("%d new listings match your saved search \"%s\": ", len(titles), search.Name)
+		if len(titles) == 1 {
+			message = fmt.Sprintf("1 new listing matches your saved search \"%s\": ", search.Name)
+		}
+		message += strings.Join(titles[:min(len(titles), digestListingTitles)], "; ")
+		if len(titles) > digestListingTitles {
+			message += fmt.Sprintf("; and %d more", len(titles)-digestListingTitles)
+		}
+		if err := Notify(search.UserID, title, message, "search"); err != nil {
+			return false, err
+		}
+	}
+
+	now := time.Now()
+	if len(matchIDs) > 0 {
+		if err := config.DB.Model(&models.SavedSearchMatch{}).Where("id IN ?", matchIDs).
+			Update("notified_at", now).Error; err != nil {
+			return false, err
+		}
+	}
+	if err := config.DB.Model(&search).Update("last_digest_at", now).Error; err != nil {
+		return false, err
+	}
+	return len(titles) > 0, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Job represents a background task run periodically by the scheduler
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// Scheduler runs registered jobs on their own intervals
+type Scheduler struct {
+	jobs []Job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler instance
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+// Register adds a job to the scheduler. Jobs must be registered before Start is called.
+func (s *Scheduler) Register(name string, interval time.Duration, run func() error) {
+	s.jobs = append(s.jobs, Job{
+		Name:     name,
+		Interval: interval,
+		Run:      run,
+	})
+}
+
+// Start runs every registered job once and then on its interval until Stop is called
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	log.Printf("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Stop signals all jobs to stop and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// loop runs a single job until the scheduler is stopped
+func (s *Scheduler) loop(job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	s.runJob(job)
+	for {
+		select {
+		case <-ticker.C:
+			s.runJob(job)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// runJob runs a job and logs failures and panics instead of crashing the server
+func (s *Scheduler) runJob(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Scheduled job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	if err := job.Run(); err != nil {
+		log.Printf("Scheduled job %s failed: %v", job.Name, err)
+	}
+}
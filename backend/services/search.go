@@ -0,0 +1,45 @@
+package services
+
+// House search ranks houses against a search term using the weighted search_vector column
+// (title, then address, then description). Title and address are also matched by trigram
+// similarity, so misspelt place names such as "Kabulona" still find Kabulonga. Trigram-only
+// matches always rank below full-text matches.
+
+const searchQuerySQL = "websearch_to_tsquery('english', ?)"
+
+// searchSnippetOptions highlights matched words in the description snippet
+const searchSnippetOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=12, MaxFragments=2, FragmentDelimiter=\" ... \""
+
+// SearchConditionSQL returns a condition matching houses to a search term, along with its arguments
+func SearchConditionSQL(term string) (string, []interface{}) {
+	return searchCondition("?"), []interface{}{term, term, term}
+}
+
+// searchCondition returns a condition matching houses to the search term given by an SQL expression
+func searchCondition(term string) string {
+	return "(houses.search_vector @@ websearch_to_tsquery('english', " + term + ") OR " +
+		term + " <% houses.title OR " + term + " <% houses.address)"
+}
+
+// SearchRankSQL returns an expression scoring how well a house matches a search term, along with its
+// arguments. Full-text matches score between 1 and 2; trigram-only matches score below 1.
+func SearchRankSQL(term string) (string, []interface{}) {
+	sql := "(CASE WHEN houses.search_vector @@ " + searchQuerySQL +
+		" THEN 1 + ts_rank_cd(houses.search_vector, " + searchQuerySQL + ", 32)" +
+		" ELSE GREATEST(word_similarity(?, houses.title), word_similarity(?, houses.address)) END)"
+	return sql, []interface{}{term, term, term, term}
+}
+
+// escapedDescriptionSQL is the house description with HTML special characters escaped. Snippets are
+// rendered as HTML for their <mark> highlights, so markup typed into a description must not survive.
+// ts_headline treats the entities as separate tokens, so the words around them still match.
+const escapedDescriptionSQL = "replace(replace(replace(replace(replace(COALESCE(houses.description, ''), " +
+	`'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+
+// SearchSnippetSQL returns an expression giving an excerpt of a house's description with the
+// matched words highlighted, along with its arguments. Everything but the <mark> highlights is
+// HTML-escaped.
+func SearchSnippetSQL(term string) (string, []interface{}) {
+	sql := "ts_headline('english', " + escapedDescriptionSQL + ", " + searchQuerySQL + ", '" + searchSnippetOptions + "')"
+	return sql, []interface{}{term}
+}
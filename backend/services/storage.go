@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bondihub/config"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ImageSize names a rendition of a stored image
+type ImageSize string
+
+const (
+	ImageSizeOriginal  ImageSize = "original"
+	ImageSizeMedium    ImageSize = "medium"    // listing pages, about 800px wide
+	ImageSizeThumbnail ImageSize = "thumbnail" // search results and galleries, about 300px wide
+)
+
+// LocalStorageRoute is the path the local storage backend's files are served from
+const LocalStorageRoute = "/uploads"
+
+// StoredImage is an image saved by an ImageStorage
+type StoredImage struct {
+	PublicID string // storage key, used to build URLs and delete the image
+	URL      string // URL of the original image
+	Hash     *int64 // perceptual hash from DifferenceHash, nil when it could not be computed
+}
+
+// ImageStorage stores uploaded images. Implementations exist for Cloudinary, the local disk
+// and S3-compatible object stores; NewImageStorage picks one from the configuration.
+type ImageStorage interface {
+	// Upload saves an image in the folder and returns its public ID and URL
+	Upload(ctx context.Context, image io.Reader, folder string) (*StoredImage, error)
+	// Delete removes an image. Deleting an image that does not exist is not an error.
+	Delete(ctx context.Context, publicID string) error
+	// URL returns the URL of an image at the given size
+	URL(publicID string, size ImageSize) string
+}
+
+// NewImageStorage creates the image storage selected by STORAGE_BACKEND
+func NewImageStorage() (ImageStorage, error) {
+	var storage ImageStorage
+	var err error
+	switch config.AppConfig.StorageBackend {
+	case "cloudinary":
+		storage, err = NewCloudinaryService()
+	case "s3":
+		storage, err = NewS3Storage()
+	case "local":
+		storage, err = NewLocalStorage()
+	default:
+		err = fmt.Errorf("unknown storage backend %q", config.AppConfig.StorageBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// imageExtensions maps the image types accepted for upload to file extensions
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// newImageKey returns a unique storage key in the folder, with an extension matching the image content.
+// The returned reader still yields the whole image.
+func newImageKey(image io.Reader, folder string) (string, string, io.Reader, error) {
+	buffered := bufio.NewReaderSize(image, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", "", nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	contentType := http.DetectContentType(head)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return "", "", nil, fmt.Errorf("unsupported image type %s", contentType)
+	}
+	return path.Join(folder, uuid.New().String()+ext), contentType, buffered, nil
+}
+
+// variantKey returns the storage key of an image at a standard size, stored next to the original
+func variantKey(publicID string, size ImageSize) string {
+	if size == ImageSizeOriginal || size == "" {
+		return publicID
+	}
+	return strings.TrimSuffix(publicID, path.Ext(publicID)) + "_" + string(size) + ".jpg"
+}
+
+// LocalStorage stores images on the local disk. Files are served by Gin from LocalStorageRoute,
+// so development and tests run without any external service.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates a local disk image storage
+func NewLocalStorage() (*LocalStorage, error) {
+	dir := config.AppConfig.LocalStorageDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local storage directory: %w", err)
+	}
+	return &LocalStorage{dir: dir, baseURL: strings.TrimRight(config.AppConfig.LocalStorageURL, "/")}, nil
+}
+
+// Upload saves an image under the storage directory
+func (ls *LocalStorage) Upload(ctx context.Context, image io.Reader, folder string) (*StoredImage, error) {
+	key, _, reader, err := newImageKey(image, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, err := ls.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image folder: %w", err)
+	}
+
+	if err := writeFile(filePath, reader); err != nil {
+		return nil, err
+	}
+
+	return &StoredImage{PublicID: key, URL: ls.URL(key, ImageSizeOriginal)}, nil
+}
+
+// UploadVariant saves a standard size of an image next to the original
+func (ls *LocalStorage) UploadVariant(ctx context.Context, publicID string, size ImageSize, image io.Reader) error {
+	filePath, err := ls.filePath(variantKey(publicID, size))
+	if err != nil {
+		return err
+	}
+	return writeFile(filePath, image)
+}
+
+// Delete removes an image and its standard sizes from the storage directory
+func (ls *LocalStorage) Delete(ctx context.Context, publicID string) error {
+	for _, size := range []ImageSize{ImageSizeOriginal, ImageSizeMedium, ImageSizeThumbnail} {
+		filePath, err := ls.filePath(variantKey(publicID, size))
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete image: %w", err)
+		}
+	}
+	return nil
+}
+
+// URL returns the URL the image is served from at the given size
+func (ls *LocalStorage) URL(publicID string, size ImageSize) string {
+	return ls.baseURL + "/" + variantKey(publicID, size)
+}
+
+// Dir returns the directory images are stored in
+func (ls *LocalStorage) Dir() string {
+	return ls.dir
+}
+
+// writeFile writes an image file, removing it again if the write fails
+func writeFile(filePath string, image io.Reader) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+	if _, err := io.Copy(file, image); err != nil {
+		file.Close()
+		os.Remove(filePath)
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	return nil
+}
+
+// filePath returns the path of a key inside the storage directory, rejecting keys that escape it
+func (ls *LocalStorage) filePath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" {
+		return "", errors.New("invalid image key")
+	}
+	return filepath.Join(ls.dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"bondihub/config"
+	"bondihub/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ViewingService handles viewing appointment operations run by background jobs
+type ViewingService struct{}
+
+// NewViewingService creates a new viewing service instance
+func NewViewingService() *ViewingService {
+	return &ViewingService{}
+}
+
+// ProcessViewingReminders reminds tenants and landlords of booked viewings starting soon.
+// Each viewing is reminded once; rescheduling clears the reminder so the new time is reminded too.
+func (vs *ViewingService) ProcessViewingReminders() error {
+	now := time.Now()
+
+	var viewings []models.Viewing
+	if err := config.DB.Preload("Slot").Preload("House").Preload("Tenant").
+		Joins("JOIN viewing_slots ON viewing_slots.id = viewings.slot_id").
+		Where("viewings.status = ? AND viewings.reminder_sent_at IS NULL", models.ViewingStatusBooked).
+		Where("viewing_slots.start_time > ? AND viewing_slots.start_time <= ?", now, now.Add(config.AppConfig.ViewingReminder)).
+		Find(&viewings).Error; err != nil {
+		return fmt.Errorf("failed to load upcoming viewings: %w", err)
+	}
+
+	for i := range viewings {
+		viewing := &viewings[i]
+		when := viewing.Slot.StartTime.Format("2006-01-02 15:04")
+
+		Notify(viewing.TenantID, "Upcoming Viewing",
+			fmt.Sprintf("Reminder: your viewing of %s is at %s", viewing.House.Title, when), "viewing")
+		Notify(viewing.House.LandlordID, "Upcoming Viewing",
+			fmt.Sprintf("Reminder: %s is viewing %s at %s", viewing.Tenant.FullName, viewing.House.Title, when), "viewing")
+
+		if err := config.DB.Model(viewing).Update("reminder_sent_at", now).Error; err != nil {
+			log.Printf("Failed to mark viewing %s as reminded: %v", viewing.ID, err)
+		}
+	}
+
+	return nil
+}